@@ -0,0 +1,31 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// apiKeyPrefix marks API keys, so they are recognisable when leaked.
+const apiKeyPrefix = "gqk_"
+
+// apiKeyPrefixLength is how much of a key is kept in clear to tell keys apart.
+const apiKeyPrefixLength = len(apiKeyPrefix) + 8
+
+// NewAPIKey returns a random API key and the prefix it is listed by.
+func NewAPIKey() (key, prefix string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	key = apiKeyPrefix + hex.EncodeToString(b)
+	return key, key[:apiKeyPrefixLength], nil
+}
+
+// HashAPIKey returns the hex SHA-256 of a key, which is what is stored. Keys
+// are long and random, so a fast hash is as safe as a password hash here and
+// keeps the lookup of every request cheap.
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
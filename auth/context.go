@@ -0,0 +1,42 @@
+package auth
+
+import (
+	"context"
+	"mas-diq/go-graphql/models"
+)
+
+type contextKey string
+
+const userKey contextKey = "currentUser"
+
+// WithUser stores the authenticated user in the context.
+func WithUser(ctx context.Context, user *models.User) context.Context {
+	return context.WithValue(ctx, userKey, user)
+}
+
+// UserFromContext returns the authenticated user, or nil for anonymous requests.
+func UserFromContext(ctx context.Context) *models.User {
+	user, _ := ctx.Value(userKey).(*models.User)
+	return user
+}
+
+const apiKeyKey contextKey = "apiKey"
+
+// WithAPIKey stores the API key the request was authenticated with.
+func WithAPIKey(ctx context.Context, key *models.APIKey) context.Context {
+	return context.WithValue(ctx, apiKeyKey, key)
+}
+
+// APIKeyFromContext returns the API key of the request, or nil when the
+// caller didn't authenticate with one.
+func APIKeyFromContext(ctx context.Context) *models.APIKey {
+	key, _ := ctx.Value(apiKeyKey).(*models.APIKey)
+	return key
+}
+
+// HasScope reports whether the caller may use scope: callers authenticated
+// with an API key need the key to have it, other callers have every scope.
+func HasScope(ctx context.Context, scope string) bool {
+	key := APIKeyFromContext(ctx)
+	return key == nil || key.HasScope(scope)
+}
@@ -0,0 +1,57 @@
+// Package cli implements the commands the server binary runs when it is
+// given arguments, e.g. "go run main.go persisted register ./client/queries".
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+)
+
+// command is a subcommand; it returns the exit code of the process.
+type command struct {
+	usage string
+	run   func(args []string) int
+}
+
+var commands = map[string]command{
+	"persisted": {
+		usage: "persisted extract|register ...   manage persisted GraphQL queries",
+		run:   persistedCommand,
+	},
+	"schema": {
+		usage: "schema print|diff ...            print the GraphQL schema as SDL or check it for breaking changes",
+		run:   schemaCommand,
+	},
+}
+
+// Run executes the command named by args[0] and returns the exit code.
+func Run(args []string) int {
+	if len(args) == 0 || args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		usage(os.Stdout)
+		return 0
+	}
+	cmd, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n", args[0])
+		usage(os.Stderr)
+		return 2
+	}
+	return cmd.run(args[1:])
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: go-graphql [command] [arguments]")
+	fmt.Fprintln(w, "Without a command the API server starts.")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintln(w, "  "+commands[name].usage)
+	}
+}
@@ -0,0 +1,184 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/fs"
+	"mas-diq/go-graphql/config"
+	"mas-diq/go-graphql/graphql"
+	"mas-diq/go-graphql/logging"
+	"mas-diq/go-graphql/models"
+	"mas-diq/go-graphql/persisted"
+	"os"
+	"path/filepath"
+	"strings"
+
+	gql "github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/source"
+)
+
+const persistedUsage = `Usage:
+  persisted extract [-o manifest.json] <files or directories>...
+      Validate the operations of .graphql/.gql files against the schema and
+      write them as a persisted query manifest (to stdout by default).
+  persisted register <manifest.json, files or directories>...
+      Validate operations and register them in the persisted query store
+      (GRAPHQL_PERSISTED_STORE), for use with GRAPHQL_PERSISTED_ONLY.`
+
+func persistedCommand(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, persistedUsage)
+		return 2
+	}
+	switch args[0] {
+	case "extract":
+		return persistedExtract(args[1:])
+	case "register":
+		return persistedRegister(args[1:])
+	default:
+		fmt.Fprintln(os.Stderr, persistedUsage)
+		return 2
+	}
+}
+
+func persistedExtract(args []string) int {
+	flags := flag.NewFlagSet("persisted extract", flag.ContinueOnError)
+	output := flags.String("o", "", "write the manifest to this file instead of stdout")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	manifest, err := loadOperations(flags.Args())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	data = append(data, '\n')
+	if *output == "" {
+		os.Stdout.Write(data)
+		return 0
+	}
+	if err := os.WriteFile(*output, data, 0o644); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	fmt.Fprintf(os.Stderr, "Wrote %d operations to %s\n", len(manifest.Operations), *output)
+	return 0
+}
+
+func persistedRegister(args []string) int {
+	manifest, err := loadOperations(args)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	if config.App.GraphQL.PersistedStore == "db" {
+		if err := config.ConnectDatabase(logging.Gorm(config.App.Log.SlowQuery)); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to connect to database: %v\n", err)
+			return 1
+		}
+		if err := models.Migrate(config.DB); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to migrate database: %v\n", err)
+			return 1
+		}
+	}
+	store, err := persisted.NewStore(config.App.GraphQL)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	for _, operation := range manifest.Operations {
+		query := persisted.Query{Hash: operation.ID, Body: operation.Body, OperationName: operation.Name, Registered: true}
+		if err := store.Save(context.Background(), query); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", operation.Name, err)
+			return 1
+		}
+		fmt.Printf("%s %s\n", operation.ID, operation.Name)
+	}
+	fmt.Fprintf(os.Stderr, "Registered %d operations\n", len(manifest.Operations))
+	return 0
+}
+
+// loadOperations reads manifests (.json) and GraphQL documents (.graphql,
+// .gql, or directories holding them) into one manifest, validated against the schema.
+func loadOperations(paths []string) (*persisted.Manifest, error) {
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no files given\n\n%s", persistedUsage)
+	}
+
+	manifest := &persisted.Manifest{}
+	documents := map[string]string{}
+	for _, path := range paths {
+		err := filepath.WalkDir(path, func(name string, entry fs.DirEntry, err error) error {
+			if err != nil || entry.IsDir() {
+				return err
+			}
+			switch filepath.Ext(name) {
+			case ".json":
+				data, err := os.ReadFile(name)
+				if err != nil {
+					return err
+				}
+				var m persisted.Manifest
+				if err := json.Unmarshal(data, &m); err != nil {
+					return fmt.Errorf("%s: %w", name, err)
+				}
+				manifest.Operations = append(manifest.Operations, m.Operations...)
+			case ".graphql", ".gql":
+				data, err := os.ReadFile(name)
+				if err != nil {
+					return err
+				}
+				documents[name] = string(data)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	extracted, err := persisted.Extract(documents)
+	if err != nil {
+		return nil, err
+	}
+	manifest.Format, manifest.Version = extracted.Format, extracted.Version
+	manifest.Operations = append(manifest.Operations, extracted.Operations...)
+
+	schema, err := graphql.NewSchema(nil)
+	if err != nil {
+		return nil, err
+	}
+	var problems []string
+	for _, operation := range manifest.Operations {
+		if persisted.Hash(operation.Body) != operation.ID {
+			problems = append(problems, fmt.Sprintf("%s: id does not match the body", operation.Name))
+			continue
+		}
+		doc, err := parser.Parse(parser.ParseParams{
+			Source: source.NewSource(&source.Source{Body: []byte(operation.Body), Name: operation.Name}),
+		})
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", operation.Name, err))
+			continue
+		}
+		for _, e := range gql.ValidateDocument(&schema, doc, nil).Errors {
+			problems = append(problems, fmt.Sprintf("%s: %s", operation.Name, e.Message))
+		}
+	}
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("invalid operations:\n  %s", strings.Join(problems, "\n  "))
+	}
+	return manifest, nil
+}
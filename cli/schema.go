@@ -0,0 +1,126 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"mas-diq/go-graphql/graphql"
+	"mas-diq/go-graphql/schemadiff"
+	"os"
+	"strings"
+)
+
+const schemaUsage = `Usage:
+  schema print [-o schema.graphql]
+      Print the GraphQL schema as SDL (to stdout by default), to commit it
+      or hand it to client teams.
+  schema diff [old.graphql [new.graphql]]
+      Compare the committed SDL (schema.graphql by default) with the current
+      schema, or with new.graphql, and list the changes as breaking,
+      dangerous or safe. Exits with 1 when a change is breaking.`
+
+func schemaCommand(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, schemaUsage)
+		return 2
+	}
+	switch args[0] {
+	case "print":
+		return schemaPrint(args[1:])
+	case "diff":
+		return schemaDiff(args[1:])
+	default:
+		fmt.Fprintln(os.Stderr, schemaUsage)
+		return 2
+	}
+}
+
+func schemaPrint(args []string) int {
+	flags := flag.NewFlagSet("schema print", flag.ContinueOnError)
+	output := flags.String("o", "", "write the SDL to this file instead of stdout")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	sdl, err := currentSDL()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if *output == "" {
+		fmt.Print(sdl)
+		return 0
+	}
+	if err := os.WriteFile(*output, []byte(sdl), 0o644); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	fmt.Fprintf(os.Stderr, "Wrote the schema to %s\n", *output)
+	return 0
+}
+
+func schemaDiff(args []string) int {
+	if len(args) > 2 {
+		fmt.Fprintln(os.Stderr, schemaUsage)
+		return 2
+	}
+	oldPath := "schema.graphql"
+	if len(args) > 0 {
+		oldPath = args[0]
+	}
+	oldSDL, err := os.ReadFile(oldPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	var newSDL string
+	if len(args) > 1 {
+		data, err := os.ReadFile(args[1])
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		newSDL = string(data)
+	} else if newSDL, err = currentSDL(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	changes, err := schemadiff.Compare(string(oldSDL), newSDL)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if len(changes) == 0 {
+		fmt.Println("No changes")
+		return 0
+	}
+	for _, level := range []schemadiff.Level{schemadiff.Breaking, schemadiff.Dangerous, schemadiff.Safe} {
+		count := 0
+		for _, change := range changes {
+			if change.Level != level {
+				continue
+			}
+			if count == 0 {
+				fmt.Printf("%s changes:\n", strings.ToUpper(level.String()[:1])+level.String()[1:])
+			}
+			fmt.Println("  " + change.String())
+			count++
+		}
+	}
+	if !schemadiff.HasBreaking(changes) {
+		return 0
+	}
+	if len(args) < 2 {
+		fmt.Fprintf(os.Stderr, "The schema breaks clients of %s; update it with \"schema print -o %s\" once they are ready\n", oldPath, oldPath)
+	}
+	return 1
+}
+
+// currentSDL prints the schema the server runs.
+func currentSDL() (string, error) {
+	schema, err := graphql.NewSchema(nil)
+	if err != nil {
+		return "", err
+	}
+	return graphql.PrintSchema(schema), nil
+}
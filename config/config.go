@@ -0,0 +1,450 @@
+package config
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AppConfig holds the application settings that can be tuned per environment.
+type AppConfig struct {
+	Server    ServerConfig
+	Database  DatabaseConfig
+	Log       LogConfig
+	Auth      AuthConfig
+	Users     UsersConfig
+	Posts     PostsConfig
+	Storage   StorageConfig
+	Uploads   UploadsConfig
+	GraphQL   GraphQLConfig
+	Webhooks  WebhooksConfig
+	Metrics   MetricsConfig
+	Tracing   TracingConfig
+	RateLimit RateLimitConfig
+	CORS      CORSConfig
+	Security  SecurityConfig
+}
+
+type ServerConfig struct {
+	// Addr is the address the HTTP server listens on.
+	Addr string
+	// TrustedProxies are the addresses or CIDR ranges of the proxies in front
+	// of the server, e.g. "10.0.0.0/8". The client IP is read from
+	// X-Forwarded-For only on requests coming from them; by default none is
+	// trusted, as anyone can send the header.
+	TrustedProxies []string
+	// ReadHeaderTimeout, ReadTimeout and WriteTimeout bound reading a request
+	// and writing its response. The change stream and WebSocket connections
+	// aren't subject to them.
+	ReadHeaderTimeout time.Duration
+	ReadTimeout       time.Duration
+	WriteTimeout      time.Duration
+	// IdleTimeout is how long keep-alive connections wait for a next request.
+	IdleTimeout time.Duration
+	// DrainDelay is how long the server keeps serving after SIGTERM while
+	// /readyz reports it as draining, so load balancers stop routing to it
+	// before it stops accepting connections. It counts toward ShutdownTimeout.
+	DrainDelay time.Duration
+	// ShutdownTimeout bounds the wait for running requests and operations
+	// after SIGTERM; whatever still runs then is cut off.
+	ShutdownTimeout time.Duration
+}
+
+type DatabaseConfig struct {
+	// DSN is the MySQL data source name of the primary, which gets the writes.
+	DSN string
+	// ReplicaDSNs are read replicas. GraphQL queries read from them (see
+	// WithReplicaReads); everything else uses the primary.
+	ReplicaDSNs []string
+
+	// Pool settings, applied to the primary and each replica.
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+
+	// ConnectTimeout is how long startup keeps retrying to connect, waiting
+	// RetryBase after the first failure and twice as long after each next one,
+	// up to RetryMax.
+	ConnectTimeout time.Duration
+	RetryBase      time.Duration
+	RetryMax       time.Duration
+	// ReadRetries is how many times a read that failed on a transient error
+	// (lost connection, deadlock...) is tried again outside of transactions,
+	// waiting a tenth of the startup delays.
+	ReadRetries int
+}
+
+type LogConfig struct {
+	// Level is the lowest level logged: "debug", "info", "warn" or "error".
+	Level string
+	// Format is "json", or "text" for reading logs in a terminal.
+	Format string
+	// Levels overrides Level per package, e.g. LOG_LEVELS=gorm=debug,graphql=warn.
+	Levels map[string]string
+	// SlowQuery is the duration above which queries are logged as warnings.
+	SlowQuery time.Duration
+}
+
+type AuthConfig struct {
+	// TrustUserHeader accepts the X-User-ID header as the identity of the caller.
+	// Anyone can send it, so it is off by default; only turn it on in
+	// development and for services behind a gateway that sets it.
+	TrustUserHeader bool
+}
+
+type UsersConfig struct {
+	// StripEmailPlusTag treats "john+news@example.com" as "john@example.com"
+	// when normalising emails, so the same mailbox can't register twice.
+	StripEmailPlusTag bool
+}
+
+type PostsConfig struct {
+	// ScheduleInterval is how often scheduled posts are checked for publication.
+	ScheduleInterval time.Duration
+	// StreamHeartbeat is how often idle change streams send a comment, so
+	// proxies don't close them.
+	StreamHeartbeat time.Duration
+	// StreamPollInterval is how often change streams check the change log for
+	// changes made by other instances.
+	StreamPollInterval time.Duration
+}
+
+type StorageConfig struct {
+	// Driver selects where uploaded files go: "local" or "s3".
+	Driver string
+	// PublicURL is the base URL files are served from. For the local driver it
+	// is also the path the API serves them on.
+	PublicURL string
+
+	// LocalDir is the directory of the local driver.
+	LocalDir string
+
+	// S3 settings, for AWS or any S3 compatible service such as MinIO.
+	S3Endpoint        string
+	S3Region          string
+	S3Bucket          string
+	S3AccessKeyID     string
+	S3SecretAccessKey string
+	// S3PathStyle addresses objects as endpoint/bucket/key instead of
+	// bucket.endpoint/key; MinIO needs it.
+	S3PathStyle bool
+}
+
+type UploadsConfig struct {
+	// MaxBytes is the largest file accepted.
+	MaxBytes int64
+	// MaxPixels bounds width x height, so small files can't decode to huge images.
+	MaxPixels int64
+	// ThumbnailWidth is the width thumbnails are scaled down to.
+	ThumbnailWidth int
+}
+
+type GraphQLConfig struct {
+	// MaxDepth is the deepest nesting of fields an operation may have.
+	MaxDepth int
+	// MaxComplexity is the cost budget of one operation (see graphql.Cost).
+	MaxComplexity int
+	// DefaultListSize is the assumed length of lists without a limit argument.
+	DefaultListSize int
+
+	// AutomaticPersistedQueries lets clients register queries by sending
+	// them along with their hash (Apollo APQ).
+	AutomaticPersistedQueries bool
+	// PersistedQueriesOnly rejects operations that weren't registered from a
+	// client manifest. Meant for production; it disables automatic registration.
+	PersistedQueriesOnly bool
+	// PersistedStore is where persisted queries are kept: "db" or "disk".
+	PersistedStore string
+	// PersistedDir is the directory of the disk store.
+	PersistedDir string
+
+	// CacheMaxAge is how long GET query responses may be cached when none
+	// of their fields asks for less.
+	CacheMaxAge time.Duration
+
+	// Playground serves GraphiQL to browsers opening GET /graphql. It is off
+	// by default, for development; the schema is available as SDL either way.
+	Playground bool
+}
+
+type WebhooksConfig struct {
+	// Enabled runs the dispatcher that delivers outbox events to webhooks.
+	// Turn it off on instances that shouldn't send requests out.
+	Enabled bool
+	// PollInterval is how often the outbox and due deliveries are checked.
+	PollInterval time.Duration
+	// Timeout bounds one delivery request.
+	Timeout time.Duration
+	// MaxAttempts is how many times a delivery is tried before it is dead.
+	MaxAttempts int
+	// BackoffBase is the wait after the first failed attempt; it doubles
+	// after each one, up to BackoffMax.
+	BackoffBase time.Duration
+	BackoffMax  time.Duration
+}
+
+type MetricsConfig struct {
+	// Enabled serves the Prometheus metrics on /metrics. Keep the path away
+	// from the public internet, e.g. by only routing it internally.
+	Enabled bool
+}
+
+type TracingConfig struct {
+	// Exporter is where spans go: "none", "otlp" or "file". The OTLP exporter
+	// is configured with the standard OTEL_EXPORTER_OTLP_* variables, e.g.
+	// OTEL_EXPORTER_OTLP_ENDPOINT=http://collector:4318.
+	Exporter string
+	// File is the file the "file" exporter appends spans to, as JSON.
+	File string
+	// SampleRatio is the share of new traces recorded, from 0 to 1. Requests
+	// that come with a sampled trace context are always recorded.
+	SampleRatio float64
+}
+
+type RateLimitConfig struct {
+	// Enabled meters clients: API keys and signed in users by their ID, others
+	// by their IP.
+	Enabled bool
+	// RESTRate and RESTBurst are the budget of a client on the REST routes:
+	// RESTBurst requests at once, refilled at RESTRate requests per second.
+	RESTRate  float64
+	RESTBurst float64
+	// GraphQLRate and GraphQLBurst are the budget of a client on /graphql, in
+	// operations, or in complexity points (see graphql.Cost) with GraphQLWeighted.
+	GraphQLRate     float64
+	GraphQLBurst    float64
+	GraphQLWeighted bool
+}
+
+type CORSConfig struct {
+	// AllowedOrigins are the origins browsers may call the API from, e.g.
+	// "https://app.example.com". A "*" in an origin stands for one or more
+	// subdomains ("https://*.example.com"), and "*" alone for any origin.
+	// Empty disables CORS, leaving browsers to the same origin.
+	AllowedOrigins []string
+	// AllowedMethods and AllowedHeaders are what preflighted requests may
+	// use; "*" in AllowedHeaders allows any header.
+	AllowedMethods []string
+	AllowedHeaders []string
+	// ExposedHeaders are the response headers scripts may read.
+	ExposedHeaders []string
+	// AllowCredentials lets browsers send cookies and Authorization headers.
+	AllowCredentials bool
+	// MaxAge is how long browsers may cache the answer to a preflight.
+	MaxAge time.Duration
+}
+
+type SecurityConfig struct {
+	// HSTSMaxAge is how long browsers should only use HTTPS for the host after
+	// an HTTPS response; zero sends no Strict-Transport-Security. Plain HTTP
+	// responses, unless a proxy sent X-Forwarded-Proto: https, never get it.
+	HSTSMaxAge            time.Duration
+	HSTSIncludeSubdomains bool
+	HSTSPreload           bool
+	// ContentSecurityPolicy is the policy of the API responses, which
+	// browsers have no reason to run scripts from or frame.
+	ContentSecurityPolicy string
+	// PlaygroundContentSecurityPolicy is the policy of the GraphQL playground
+	// page, which loads its scripts and styles from a CDN.
+	PlaygroundContentSecurityPolicy string
+	// ReferrerPolicy and FrameOptions set the headers of the same name; empty
+	// leaves them out.
+	ReferrerPolicy string
+	FrameOptions   string
+}
+
+// defaultPlaygroundCSP lets the playground page load GraphiQL from unpkg and
+// call the API, over HTTP and WebSocket.
+const defaultPlaygroundCSP = "default-src 'self'; script-src 'self' 'unsafe-inline' https://unpkg.com; " +
+	"style-src 'self' 'unsafe-inline' https://unpkg.com; font-src 'self' data: https://unpkg.com; " +
+	"img-src 'self' data: https:; connect-src 'self' ws: wss:; frame-ancestors 'none'"
+
+// App is the configuration loaded from the environment at startup.
+var App = Load()
+
+// Load reads the configuration from environment variables, falling back to defaults.
+func Load() AppConfig {
+	return AppConfig{
+		Server: ServerConfig{
+			Addr:              getEnv("SERVER_ADDR", ":8000"),
+			TrustedProxies:    getEnvList("SERVER_TRUSTED_PROXIES", nil),
+			ReadHeaderTimeout: getEnvDuration("SERVER_READ_HEADER_TIMEOUT", 5*time.Second),
+			ReadTimeout:       getEnvDuration("SERVER_READ_TIMEOUT", 30*time.Second),
+			WriteTimeout:      getEnvDuration("SERVER_WRITE_TIMEOUT", 60*time.Second),
+			IdleTimeout:       getEnvDuration("SERVER_IDLE_TIMEOUT", 2*time.Minute),
+			DrainDelay:        getEnvDuration("SERVER_DRAIN_DELAY", 5*time.Second),
+			ShutdownTimeout:   getEnvDuration("SERVER_SHUTDOWN_TIMEOUT", 30*time.Second),
+		},
+		Database: DatabaseConfig{
+			DSN:         getEnv("DB_DSN", "root:adminMariadb@tcp(localhost:3306)/go_test?charset=utf8mb4&parseTime=True&loc=Local"),
+			ReplicaDSNs: getEnvList("DB_REPLICA_DSNS", nil),
+
+			MaxOpenConns:    int(getEnvInt64("DB_MAX_OPEN_CONNS", 25)),
+			MaxIdleConns:    int(getEnvInt64("DB_MAX_IDLE_CONNS", 10)),
+			ConnMaxLifetime: getEnvDuration("DB_CONN_MAX_LIFETIME", 30*time.Minute),
+			ConnMaxIdleTime: getEnvDuration("DB_CONN_MAX_IDLE_TIME", 5*time.Minute),
+
+			ConnectTimeout: getEnvDuration("DB_CONNECT_TIMEOUT", time.Minute),
+			RetryBase:      getEnvDuration("DB_RETRY_BASE", 500*time.Millisecond),
+			RetryMax:       getEnvDuration("DB_RETRY_MAX", 10*time.Second),
+			ReadRetries:    int(getEnvInt64("DB_READ_RETRIES", 2)),
+		},
+		Log: LogConfig{
+			Level:     getEnv("LOG_LEVEL", "info"),
+			Format:    getEnv("LOG_FORMAT", "json"),
+			Levels:    getEnvMap("LOG_LEVELS"),
+			SlowQuery: getEnvDuration("LOG_SLOW_QUERY", 200*time.Millisecond),
+		},
+		Auth: AuthConfig{
+			TrustUserHeader: getEnvBool("AUTH_TRUST_USER_HEADER", false),
+		},
+		Users: UsersConfig{
+			StripEmailPlusTag: getEnvBool("USERS_STRIP_EMAIL_PLUS_TAG", false),
+		},
+		Posts: PostsConfig{
+			ScheduleInterval:   getEnvDuration("POSTS_SCHEDULE_INTERVAL", 30*time.Second),
+			StreamHeartbeat:    getEnvDuration("POSTS_STREAM_HEARTBEAT", 15*time.Second),
+			StreamPollInterval: getEnvDuration("POSTS_STREAM_POLL_INTERVAL", 2*time.Second),
+		},
+		Storage: StorageConfig{
+			Driver:            getEnv("STORAGE_DRIVER", "local"),
+			PublicURL:         getEnv("STORAGE_PUBLIC_URL", "/uploads"),
+			LocalDir:          getEnv("STORAGE_LOCAL_DIR", "data/uploads"),
+			S3Endpoint:        getEnv("STORAGE_S3_ENDPOINT", "https://s3.amazonaws.com"),
+			S3Region:          getEnv("STORAGE_S3_REGION", "us-east-1"),
+			S3Bucket:          getEnv("STORAGE_S3_BUCKET", ""),
+			S3AccessKeyID:     getEnv("STORAGE_S3_ACCESS_KEY_ID", ""),
+			S3SecretAccessKey: getEnv("STORAGE_S3_SECRET_ACCESS_KEY", ""),
+			S3PathStyle:       getEnvBool("STORAGE_S3_PATH_STYLE", false),
+		},
+		Uploads: UploadsConfig{
+			MaxBytes:       getEnvInt64("UPLOADS_MAX_BYTES", 10<<20),
+			MaxPixels:      getEnvInt64("UPLOADS_MAX_PIXELS", 40_000_000),
+			ThumbnailWidth: int(getEnvInt64("UPLOADS_THUMBNAIL_WIDTH", 320)),
+		},
+		GraphQL: GraphQLConfig{
+			MaxDepth:        int(getEnvInt64("GRAPHQL_MAX_DEPTH", 10)),
+			MaxComplexity:   int(getEnvInt64("GRAPHQL_MAX_COMPLEXITY", 5000)),
+			DefaultListSize: int(getEnvInt64("GRAPHQL_DEFAULT_LIST_SIZE", 20)),
+
+			AutomaticPersistedQueries: getEnvBool("GRAPHQL_APQ", true),
+			PersistedQueriesOnly:      getEnvBool("GRAPHQL_PERSISTED_ONLY", false),
+			PersistedStore:            getEnv("GRAPHQL_PERSISTED_STORE", "db"),
+			PersistedDir:              getEnv("GRAPHQL_PERSISTED_DIR", "persisted_queries"),
+
+			CacheMaxAge: getEnvDuration("GRAPHQL_CACHE_MAX_AGE", time.Minute),
+
+			Playground: getEnvBool("GRAPHQL_PLAYGROUND", false),
+		},
+		Webhooks: WebhooksConfig{
+			Enabled:      getEnvBool("WEBHOOKS_ENABLED", true),
+			PollInterval: getEnvDuration("WEBHOOKS_POLL_INTERVAL", 5*time.Second),
+			Timeout:      getEnvDuration("WEBHOOKS_TIMEOUT", 10*time.Second),
+			MaxAttempts:  int(getEnvInt64("WEBHOOKS_MAX_ATTEMPTS", 8)),
+			BackoffBase:  getEnvDuration("WEBHOOKS_BACKOFF_BASE", 30*time.Second),
+			BackoffMax:   getEnvDuration("WEBHOOKS_BACKOFF_MAX", 6*time.Hour),
+		},
+		Metrics: MetricsConfig{
+			Enabled: getEnvBool("METRICS_ENABLED", true),
+		},
+		Tracing: TracingConfig{
+			Exporter:    getEnv("TRACING_EXPORTER", "none"),
+			File:        getEnv("TRACING_FILE", "traces.json"),
+			SampleRatio: getEnvFloat64("TRACING_SAMPLE_RATIO", 1),
+		},
+		RateLimit: RateLimitConfig{
+			Enabled:         getEnvBool("RATE_LIMIT_ENABLED", true),
+			RESTRate:        getEnvFloat64("RATE_LIMIT_REST_RATE", 10),
+			RESTBurst:       getEnvFloat64("RATE_LIMIT_REST_BURST", 60),
+			GraphQLRate:     getEnvFloat64("RATE_LIMIT_GRAPHQL_RATE", 250),
+			GraphQLBurst:    getEnvFloat64("RATE_LIMIT_GRAPHQL_BURST", 10000),
+			GraphQLWeighted: getEnvBool("RATE_LIMIT_GRAPHQL_WEIGHTED", true),
+		},
+		CORS: CORSConfig{
+			AllowedOrigins:   getEnvList("CORS_ALLOWED_ORIGINS", nil),
+			AllowedMethods:   getEnvList("CORS_ALLOWED_METHODS", []string{"GET", "HEAD", "POST", "PUT", "DELETE"}),
+			AllowedHeaders:   getEnvList("CORS_ALLOWED_HEADERS", []string{"Authorization", "Content-Type", "Accept-Language", "X-Request-ID", "If-None-Match"}),
+			ExposedHeaders:   getEnvList("CORS_EXPOSED_HEADERS", []string{"X-Request-ID", "ETag", "Retry-After", "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "RateLimit-Policy"}),
+			AllowCredentials: getEnvBool("CORS_ALLOW_CREDENTIALS", false),
+			MaxAge:           getEnvDuration("CORS_MAX_AGE", 10*time.Minute),
+		},
+		Security: SecurityConfig{
+			HSTSMaxAge:                      getEnvDuration("SECURITY_HSTS_MAX_AGE", 180*24*time.Hour),
+			HSTSIncludeSubdomains:           getEnvBool("SECURITY_HSTS_INCLUDE_SUBDOMAINS", false),
+			HSTSPreload:                     getEnvBool("SECURITY_HSTS_PRELOAD", false),
+			ContentSecurityPolicy:           getEnv("SECURITY_CSP", "default-src 'none'; frame-ancestors 'none'"),
+			PlaygroundContentSecurityPolicy: getEnv("SECURITY_PLAYGROUND_CSP", defaultPlaygroundCSP),
+			ReferrerPolicy:                  getEnv("SECURITY_REFERRER_POLICY", "no-referrer"),
+			FrameOptions:                    getEnv("SECURITY_FRAME_OPTIONS", "DENY"),
+		},
+	}
+}
+
+func getEnv(key, fallback string) string {
+	if value, ok := os.LookupEnv(key); ok {
+		return strings.TrimSpace(value)
+	}
+	return fallback
+}
+
+func getEnvBool(key string, fallback bool) bool {
+	value, err := strconv.ParseBool(getEnv(key, ""))
+	if err != nil {
+		return fallback
+	}
+	return value
+}
+
+func getEnvInt64(key string, fallback int64) int64 {
+	value, err := strconv.ParseInt(getEnv(key, ""), 10, 64)
+	if err != nil {
+		return fallback
+	}
+	return value
+}
+
+func getEnvFloat64(key string, fallback float64) float64 {
+	value, err := strconv.ParseFloat(getEnv(key, ""), 64)
+	if err != nil {
+		return fallback
+	}
+	return value
+}
+
+func getEnvDuration(key string, fallback time.Duration) time.Duration {
+	value, err := time.ParseDuration(getEnv(key, ""))
+	if err != nil {
+		return fallback
+	}
+	return value
+}
+
+// getEnvList reads a comma separated list, leaving out empty items. Setting
+// the variable to an empty value gives an empty list rather than fallback.
+func getEnvList(key string, fallback []string) []string {
+	raw, ok := os.LookupEnv(key)
+	if !ok {
+		return fallback
+	}
+	var values []string
+	for _, value := range strings.Split(raw, ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// getEnvMap reads a comma separated list of key=value pairs; malformed pairs are ignored.
+func getEnvMap(key string) map[string]string {
+	values := make(map[string]string)
+	for _, pair := range strings.Split(getEnv(key, ""), ",") {
+		name, value, ok := strings.Cut(pair, "=")
+		if name, value = strings.TrimSpace(name), strings.TrimSpace(value); ok && name != "" {
+			values[name] = value
+		}
+	}
+	return values
+}
@@ -1,37 +1,162 @@
 package config
 
 import (
+	"context"
+	"database/sql"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"math/rand/v2"
+	"time"
 
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"gorm.io/plugin/dbresolver"
 )
 
 var DB *gorm.DB
 
-func ConnectDatabase() {
-	// Update these values according to your MariaDB configuration
-	const (
-		username = "root"
-		password = "adminMariadb"
-		host     = "localhost"
-		port     = "3306"
-		dbName   = "go_test"
-	)
+// replicaResolver routes reads to the read replicas, nil without replicas.
+var replicaResolver *dbresolver.DBResolver
 
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=True&loc=Local",
-		username,
-		password,
-		host,
-		port,
-		dbName)
+// ConnectDatabase opens the database configured in App.Database, logging
+// queries to queryLogger. The primary may still be starting, as in a fresh
+// docker compose up, so failed attempts are retried until ConnectTimeout.
+func ConnectDatabase(queryLogger logger.Interface) error {
+	cfg := App.Database
+	gormConfig := &gorm.Config{
+		// Map driver specific errors (e.g. MySQL 1062) to gorm.ErrDuplicatedKey and friends
+		TranslateError: true,
+		Logger:         queryLogger,
+	}
 
-	database, err := gorm.Open(mysql.Open(dsn), &gorm.Config{})
-	if err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
+	var database *gorm.DB
+	deadline := time.Now().Add(cfg.ConnectTimeout)
+	for attempt := 1; ; attempt++ {
+		var err error
+		if database, err = gorm.Open(mysql.Open(cfg.DSN), gormConfig); err == nil {
+			break
+		}
+		wait := retryDelay(cfg.RetryBase, cfg.RetryMax, attempt)
+		if time.Now().Add(wait).After(deadline) {
+			return fmt.Errorf("connect to database (%d attempts): %w", attempt, err)
+		}
+		slog.Warn("Failed to connect to database, retrying", "attempt", attempt, "retry_in", wait.String(), "error", err)
+		time.Sleep(wait)
+	}
+
+	if err := configureDatabase(database, cfg); err != nil {
+		return err
 	}
 
 	DB = database
-	log.Println("Database connected successfully")
+	slog.Info("Database connected successfully", "replicas", len(cfg.ReplicaDSNs))
+	return nil
+}
+
+// configureDatabase sizes the connection pools, routes the reads allowed by
+// WithReplicaReads to the replicas and retries the reads that hit transient
+// errors.
+func configureDatabase(db *gorm.DB, cfg DatabaseConfig) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
+	sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
+	sqlDB.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	sqlDB.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+
+	if len(cfg.ReplicaDSNs) > 0 {
+		replicas := make([]gorm.Dialector, 0, len(cfg.ReplicaDSNs))
+		for _, dsn := range cfg.ReplicaDSNs {
+			replicas = append(replicas, mysql.Open(dsn))
+		}
+		resolver := dbresolver.Register(dbresolver.Config{Replicas: replicas}).
+			SetMaxOpenConns(cfg.MaxOpenConns).
+			SetMaxIdleConns(cfg.MaxIdleConns).
+			SetConnMaxLifetime(cfg.ConnMaxLifetime).
+			SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+		if err := db.Use(resolver); err != nil {
+			return fmt.Errorf("register read replicas: %w", err)
+		}
+		replicaResolver = resolver
+		if err := registerPrimaryReads(db); err != nil {
+			return err
+		}
+	}
+
+	return registerReadRetry(db, cfg)
+}
+
+// CloseDatabase closes the connection pools of the primary and of the read
+// replicas.
+func CloseDatabase() error {
+	primary, err := DB.DB()
+	if err != nil {
+		return err
+	}
+	var errs []error
+	if replicaResolver != nil {
+		// The resolver also visits the primary, as the source of its replicas.
+		errs = append(errs, replicaResolver.Call(func(pool gorm.ConnPool) error {
+			if replica, ok := pool.(*sql.DB); ok && replica != primary {
+				return replica.Close()
+			}
+			return nil
+		}))
+	}
+	return errors.Join(append(errs, primary.Close())...)
+}
+
+type replicaReadsKey struct{}
+
+// WithReplicaReads returns a copy of ctx whose reads may be served by a read
+// replica, for operations that don't need to see their own writes, such as
+// GraphQL queries. Replicas lag behind the primary, so reads made without it
+// (db.WithContext) go to the primary.
+func WithReplicaReads(ctx context.Context) context.Context {
+	return context.WithValue(ctx, replicaReadsKey{}, true)
+}
+
+// ReplicaReads reports whether ctx allows reading from replicas.
+func ReplicaReads(ctx context.Context) bool {
+	allowed, _ := ctx.Value(replicaReadsKey{}).(bool)
+	return allowed
+}
+
+// registerPrimaryReads keeps the reads of contexts without WithReplicaReads
+// on the primary; dbresolver would send every read to the replicas. Like the
+// dbresolver callback, it runs before all others, and ahead of it as it is
+// registered later.
+func registerPrimaryReads(db *gorm.DB) error {
+	primary := func(db *gorm.DB) {
+		if ctx := db.Statement.Context; ctx == nil || !ReplicaReads(ctx) {
+			dbresolver.Write.ModifyStatement(db.Statement)
+		}
+	}
+	callbacks := db.Callback()
+	return errors.Join(
+		callbacks.Query().Before("*").Register("replicas:primary_reads", primary),
+		callbacks.Row().Before("*").Register("replicas:primary_reads", primary),
+		callbacks.Raw().Before("*").Register("replicas:primary_reads", primary),
+	)
+}
+
+// retryDelay is how long to wait after a failed attempt: base, doubled for
+// each earlier failure up to max, less a random part of up to half of it so
+// that instances restarted together don't retry in step.
+func retryDelay(base, max time.Duration, attempt int) time.Duration {
+	delay := base
+	for i := 1; i < attempt && delay < max; i++ {
+		delay *= 2
+	}
+	if delay > max {
+		delay = max
+	}
+	if delay <= 1 {
+		return delay
+	}
+	return delay - rand.N(delay/2)
 }
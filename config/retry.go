@@ -0,0 +1,83 @@
+package config
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"log/slog"
+	"syscall"
+	"time"
+
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"gorm.io/gorm"
+)
+
+// MySQL errors worth retrying a read for.
+const (
+	erTooManyConnections = 1040
+	erLockWaitTimeout    = 1205
+	erLockDeadlock       = 1213
+)
+
+// registerReadRetry wraps the gorm:query callback, which runs the SELECTs of
+// First, Find and friends, to try again reads that failed on a transient
+// error. Reads in a transaction aren't retried: the transaction is gone with
+// the connection, or was rolled back by a deadlock.
+func registerReadRetry(db *gorm.DB, cfg DatabaseConfig) error {
+	if cfg.ReadRetries <= 0 {
+		return nil
+	}
+	query := db.Callback().Query().Get("gorm:query")
+	return db.Callback().Query().Replace("gorm:query", func(db *gorm.DB) {
+		query(db)
+		for attempt := 1; attempt <= cfg.ReadRetries && retryableRead(db); attempt++ {
+			wait := retryDelay(cfg.RetryBase/10, cfg.RetryMax/10, attempt)
+			ctx := db.Statement.Context
+			slog.WarnContext(ctx, "Retrying read after a transient error", "attempt", attempt, "retry_in", wait.String(), "error", db.Error)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(wait):
+			}
+			db.Error = nil
+			db.RowsAffected = 0
+			db.Statement.RowsAffected = 0
+			query(db)
+		}
+	})
+}
+
+// retryableRead reports whether the statement failed on a transient error,
+// outside of a transaction.
+func retryableRead(db *gorm.DB) bool {
+	if db.Error == nil {
+		return false
+	}
+	if _, inTransaction := db.Statement.ConnPool.(gorm.TxCommitter); inTransaction {
+		return false
+	}
+	return transient(db.Error)
+}
+
+// transient reports whether err is a lost or refused connection, or a lock
+// conflict that a new attempt may not run into.
+func transient(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var mysqlErr *mysqldriver.MySQLError
+	if errors.As(err, &mysqlErr) {
+		switch mysqlErr.Number {
+		case erTooManyConnections, erLockWaitTimeout, erLockDeadlock:
+			return true
+		}
+		return false
+	}
+	return errors.Is(err, driver.ErrBadConn) ||
+		errors.Is(err, mysqldriver.ErrInvalidConn) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.EPIPE)
+}
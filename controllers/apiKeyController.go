@@ -0,0 +1,100 @@
+package controllers
+
+import (
+	"mas-diq/go-graphql/auth"
+	"mas-diq/go-graphql/dto"
+	"mas-diq/go-graphql/models"
+	"mas-diq/go-graphql/schemas"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetListAPIKey lists the API keys of the current user.
+func GetListAPIKey(c *gin.Context) {
+	res := schemas.Response{}
+	user := auth.UserFromContext(c.Request.Context())
+
+	var list []models.APIKey
+	if err := models.GetListAPIKey(&list, user.ID); err != nil {
+		abortWithError(c, err)
+		return
+	}
+
+	data := make([]dto.APIKeyResponse, 0, len(list))
+	for _, key := range list {
+		data = append(data, dto.NewAPIKeyResponse(key))
+	}
+
+	res.Code = http.StatusOK
+	res.Info = "API keys retrieved successfully"
+	res.Data = gin.H{
+		"apiKeys": data,
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+// CreateAPIKey issues a key owned by the current user. The response carries
+// the key, which can't be retrieved afterwards.
+func CreateAPIKey(c *gin.Context) {
+	res := schemas.Response{}
+
+	var input dto.CreateAPIKeyRequest
+	if !bindJSON(c, &input) {
+		return
+	}
+	if input.ExpiresAt != nil && !input.ExpiresAt.After(time.Now()) {
+		abortWithFieldError(c, "expiresAt", "gt", "now")
+		return
+	}
+
+	secret, prefix, err := auth.NewAPIKey()
+	if err != nil {
+		abortWithError(c, err)
+		return
+	}
+
+	key := models.APIKey{
+		Name:       input.Name,
+		Prefix:     prefix,
+		SecretHash: auth.HashAPIKey(secret),
+		UserID:     auth.UserFromContext(c.Request.Context()).ID,
+		Scopes:     dto.JoinScopes(input.Scopes),
+		ExpiresAt:  input.ExpiresAt,
+	}
+	if err := models.CreateAPIKeyData(&key); err != nil {
+		abortWithError(c, err)
+		return
+	}
+
+	data := dto.NewAPIKeyResponse(key)
+	data.Key = secret
+
+	res.Code = http.StatusOK
+	res.Info = "API key created successfully"
+	res.Data = data
+	c.JSON(http.StatusOK, res)
+}
+
+// RevokeAPIKey disables a key of the current user; admins may revoke any key.
+func RevokeAPIKey(c *gin.Context) {
+	res := schemas.Response{}
+	id := c.MustGet("id").(uint64)
+
+	var key models.APIKey
+	if err := models.GetOneAPIKey(&key, id); err != nil {
+		abortWithError(c, err)
+		return
+	}
+
+	if err := models.RevokeAPIKey(&key, auth.UserFromContext(c.Request.Context())); err != nil {
+		abortWithError(c, err)
+		return
+	}
+
+	res.Code = http.StatusOK
+	res.Info = "API key revoked successfully"
+	res.Data = dto.NewAPIKeyResponse(key)
+	c.JSON(http.StatusOK, res)
+}
@@ -0,0 +1,170 @@
+package controllers
+
+import (
+	"mas-diq/go-graphql/auth"
+	"mas-diq/go-graphql/dto"
+	"mas-diq/go-graphql/models"
+	"mas-diq/go-graphql/schemas"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+func GetPostComments(c *gin.Context) {
+	id := c.MustGet("id").(uint64)
+
+	post, ok := loadPost(c, id, nil)
+	if !ok {
+		return
+	}
+
+	respondCommentPage(c, post.ID, nil)
+}
+
+func GetCommentReplies(c *gin.Context) {
+	id := c.MustGet("id").(uint64)
+
+	var comment models.Comment
+	if err := models.GetOneComment(&comment, id); err != nil {
+		abortWithError(c, err)
+		return
+	}
+	// Comments of posts the caller can't see are hidden with the post.
+	if _, ok := loadPost(c, uint64(comment.PostID), nil); !ok {
+		return
+	}
+
+	respondCommentPage(c, comment.PostID, &comment.ID)
+}
+
+func respondCommentPage(c *gin.Context, postID uint, parentID *uint) {
+	res := schemas.Response{}
+
+	var input dto.CommentPageQuery
+	if !bindQuery(c, &input) {
+		return
+	}
+	after, err := dto.DecodeCursor(input.After)
+	if err != nil {
+		abortWithFieldError(c, "after", "cursor", "")
+		return
+	}
+
+	var page models.CommentPage
+	viewer := auth.UserFromContext(c.Request.Context())
+	if err := models.GetCommentPage(&page, postID, parentID, viewer, after, input.Limit()); err != nil {
+		abortWithError(c, err)
+		return
+	}
+
+	res.Code = http.StatusOK
+	res.Info = "Comments retrieved successfully"
+	res.Data = dto.NewCommentPageResponse(page)
+	c.JSON(http.StatusOK, res)
+}
+
+func CreateComment(c *gin.Context) {
+	res := schemas.Response{}
+	id := c.MustGet("id").(uint64)
+
+	var input dto.CreateCommentRequest
+	if !bindJSON(c, &input) {
+		return
+	}
+
+	post, ok := loadPost(c, id, nil)
+	if !ok {
+		return
+	}
+
+	comment := models.Comment{
+		PostID:   post.ID,
+		AuthorID: auth.UserFromContext(c.Request.Context()).ID,
+		ParentID: input.ParentID,
+		Body:     input.Body,
+	}
+	if err := models.CreateCommentData(&comment); err != nil {
+		abortWithError(c, err)
+		return
+	}
+
+	res.Code = http.StatusOK
+	res.Info = "Comment created successfully"
+	res.Data = dto.NewCommentResponse(comment)
+	c.JSON(http.StatusOK, res)
+}
+
+func UpdateComment(c *gin.Context) {
+	res := schemas.Response{}
+	id := c.MustGet("id").(uint64)
+
+	var input dto.UpdateCommentRequest
+	if !bindJSON(c, &input) {
+		return
+	}
+
+	var comment models.Comment
+	if err := models.GetOneComment(&comment, id); err != nil {
+		abortWithError(c, err)
+		return
+	}
+
+	comment.Body = input.Body
+	if err := models.UpdateCommentData(&comment, auth.UserFromContext(c.Request.Context())); err != nil {
+		abortWithError(c, err)
+		return
+	}
+
+	res.Code = http.StatusOK
+	res.Info = "Comment updated successfully"
+	res.Data = dto.NewCommentResponse(comment)
+	c.JSON(http.StatusOK, res)
+}
+
+func ModerateComment(c *gin.Context) {
+	res := schemas.Response{}
+	id := c.MustGet("id").(uint64)
+
+	var input dto.ModerateCommentRequest
+	if !bindJSON(c, &input) {
+		return
+	}
+
+	var comment models.Comment
+	if err := models.GetOneComment(&comment, id); err != nil {
+		abortWithError(c, err)
+		return
+	}
+
+	actor := auth.UserFromContext(c.Request.Context())
+	if err := models.ModerateComment(&comment, actor, models.CommentStatus(input.Status)); err != nil {
+		abortWithError(c, err)
+		return
+	}
+
+	res.Code = http.StatusOK
+	res.Info = "Comment moderated successfully"
+	res.Data = dto.NewCommentResponse(comment)
+	c.JSON(http.StatusOK, res)
+}
+
+func DeleteComment(c *gin.Context) {
+	res := schemas.Response{}
+	id := c.MustGet("id").(uint64)
+
+	var comment models.Comment
+	if err := models.GetOneComment(&comment, id); err != nil {
+		abortWithError(c, err)
+		return
+	}
+
+	if err := models.DeleteComment(&comment, auth.UserFromContext(c.Request.Context())); err != nil {
+		abortWithError(c, err)
+		return
+	}
+
+	res.Code = http.StatusOK
+	res.Info = "Comment deleted successfully"
+	res.Data = nil
+	c.JSON(http.StatusOK, res)
+}
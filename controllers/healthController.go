@@ -0,0 +1,41 @@
+package controllers
+
+import (
+	"mas-diq/go-graphql/config"
+	"mas-diq/go-graphql/health"
+	"mas-diq/go-graphql/schemas"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Healthz reports that the process is up and serving. It doesn't check the
+// database, so an outage doesn't get every instance restarted.
+func Healthz(c *gin.Context) {
+	res := schemas.Response{}
+	res.Code = http.StatusOK
+	res.Info = "Server is alive"
+	c.JSON(http.StatusOK, res)
+}
+
+// Readyz reports whether the instance can take traffic: the database answers,
+// its schema is migrated and the server isn't shutting down.
+func Readyz(c *gin.Context) {
+	res := schemas.Response{}
+
+	ready, checks := health.Ready(c.Request.Context(), config.DB)
+	res.Data = gin.H{
+		"checks": checks,
+	}
+	if !ready {
+		res.Code = http.StatusServiceUnavailable
+		res.Info = "Server is not ready"
+		res.ErrorCode = "NOT_READY"
+		c.JSON(http.StatusServiceUnavailable, res)
+		return
+	}
+
+	res.Code = http.StatusOK
+	res.Info = "Server is ready"
+	c.JSON(http.StatusOK, res)
+}
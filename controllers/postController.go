@@ -1,48 +1,98 @@
 package controllers
 
 import (
+	"errors"
+	"mas-diq/go-graphql/auth"
 	"mas-diq/go-graphql/dto"
+	"mas-diq/go-graphql/events"
 	"mas-diq/go-graphql/models"
 	"mas-diq/go-graphql/schemas"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
+// loadPost loads the post a request acts on, or aborts the request: posts the
+// caller can't see are not found, like on the post reads, and those allowed
+// doesn't let them act on are forbidden. A nil allowed only checks that the
+// post can be seen.
+func loadPost(c *gin.Context, id uint64, allowed func(*models.User, *models.Post) bool) (models.Post, bool) {
+	var post models.Post
+	err := models.GetOnePost(&post, id)
+	viewer := auth.UserFromContext(c.Request.Context())
+	if err == nil && !models.CanViewPost(viewer, &post) {
+		err = gorm.ErrRecordNotFound
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "post not found"})
+		return post, false
+	}
+	if err == nil && allowed != nil && !allowed(viewer, &post) {
+		err = models.ErrForbidden
+	}
+	if err != nil {
+		abortWithError(c, err)
+		return post, false
+	}
+	return post, true
+}
+
 func CreatePost(c *gin.Context) {
 	res := schemas.Response{}
 
 	var input dto.CreatePostRequest
-	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindJSON(c, &input) {
 		return
 	}
 
-	post := models.Post{
-		Title:     input.Title,
-		Subtitle:  input.Subtitle,
-		Image:     input.Image,
-		Content:   input.Content,
-		Status:    models.PostStatus(input.Status),
-		CreatedBy: input.CreatedBy,
+	actor := auth.UserFromContext(c.Request.Context())
+	post := input.ToModel(actor.ID)
+	if input.Status != "" {
+		if err := models.TransitionPost(&post, actor, models.PostStatus(input.Status), nil); err != nil {
+			abortWithError(c, err)
+			return
+		}
 	}
-
-	if err := models.CreatePostData(&post); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if err := models.CreatePostData(&post, input.Tags); err != nil {
+		abortWithError(c, err)
 		return
 	}
+	events.Publish(events.Event{Type: events.PostCreated, Post: post})
 
 	res.Code = http.StatusOK
 	res.Info = "Post created successfully"
-	res.Data = dto.PostResponse{
-		ID:        post.ID,
-		Title:     post.Title,
-		Subtitle:  post.Subtitle,
-		Image:     post.Image,
-		Content:   post.Content,
-		Status:    string(post.Status),
-		CreatedBy: post.CreatedBy,
+	res.Data = dto.NewPostResponse(post)
+	c.JSON(http.StatusOK, res)
+}
+
+func GetPostBySlug(c *gin.Context) {
+	res := schemas.Response{}
+
+	var post models.Post
+	moved, err := models.GetPostBySlug(&post, c.Param("slug"))
+	if err == nil && !models.CanViewPost(auth.UserFromContext(c.Request.Context()), &post) {
+		// Unpublished posts of others don't exist as far as the caller knows.
+		err = gorm.ErrRecordNotFound
 	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "post not found"})
+		return
+	}
+	if err != nil {
+		abortWithError(c, err)
+		return
+	}
+
+	// Old slugs permanently redirect to the current one.
+	if moved {
+		c.Redirect(http.StatusMovedPermanently, "/posts/by-slug/"+post.Slug)
+		return
+	}
+
+	res.Code = http.StatusOK
+	res.Info = "Post retrieved successfully"
+	res.Data = dto.NewPostResponse(post)
 	c.JSON(http.StatusOK, res)
 }
 
@@ -51,55 +101,104 @@ func UpdatePost(c *gin.Context) {
 	id := c.MustGet("id").(uint64)
 
 	var input dto.UpdatePostRequest
-	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindJSON(c, &input) {
+		return
+	}
+
+	post, ok := loadPost(c, id, models.CanEditPost)
+	if !ok {
+		return
+	}
+
+	actor := auth.UserFromContext(c.Request.Context())
+	previous := post.Status
+	input.ApplyTo(&post)
+	if input.Status != "" {
+		if err := models.TransitionPost(&post, actor, models.PostStatus(input.Status), nil); err != nil {
+			abortWithError(c, err)
+			return
+		}
+	}
+	if err := models.UpdatePostData(&post, actor, input.Tags); err != nil {
+		abortWithError(c, err)
+		return
+	}
+	events.Publish(events.Updated(post, previous))
+
+	res.Code = http.StatusOK
+	res.Info = "Post updated successfully"
+	res.Data = dto.NewPostResponse(post)
+	c.JSON(http.StatusOK, res)
+}
+
+func PublishPost(c *gin.Context) {
+	res := schemas.Response{}
+	id := c.MustGet("id").(uint64)
+
+	var input dto.PublishPostRequest
+	if c.Request.ContentLength != 0 && !bindJSON(c, &input) {
 		return
 	}
 
 	var post models.Post
 	if err := models.GetOnePost(&post, id); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		abortWithError(c, err)
 		return
 	}
 
-	post.Title = input.Title
-	post.Subtitle = input.Subtitle
-	post.Image = input.Image
-	post.Content = input.Content
-	post.Status = models.PostStatus(input.Status)
-	if err := models.UpdatePostData(&post); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	previous := post.Status
+	if err := models.PublishPost(&post, auth.UserFromContext(c.Request.Context()), input.ScheduledFor); err != nil {
+		abortWithError(c, err)
 		return
 	}
+	events.Publish(events.Updated(post, previous))
 
 	res.Code = http.StatusOK
-	res.Info = "Post updated successfully"
-	res.Data = dto.PostResponse{
-		ID:        post.ID,
-		Title:     post.Title,
-		Subtitle:  post.Subtitle,
-		Image:     post.Image,
-		Content:   post.Content,
-		Status:    string(post.Status),
-		CreatedBy: post.CreatedBy,
+	res.Info = "Post published successfully"
+	if post.Status == models.Scheduled {
+		res.Info = "Post scheduled successfully"
 	}
+	res.Data = dto.NewPostResponse(post)
 	c.JSON(http.StatusOK, res)
 }
 
-func DeletePost(c *gin.Context) {
+func ArchivePost(c *gin.Context) {
 	res := schemas.Response{}
 	id := c.MustGet("id").(uint64)
 
 	var post models.Post
 	if err := models.GetOnePost(&post, id); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		abortWithError(c, err)
+		return
+	}
+
+	previous := post.Status
+	if err := models.ArchivePost(&post, auth.UserFromContext(c.Request.Context())); err != nil {
+		abortWithError(c, err)
+		return
+	}
+	events.Publish(events.Updated(post, previous))
+
+	res.Code = http.StatusOK
+	res.Info = "Post archived successfully"
+	res.Data = dto.NewPostResponse(post)
+	c.JSON(http.StatusOK, res)
+}
+
+func DeletePost(c *gin.Context) {
+	res := schemas.Response{}
+	id := c.MustGet("id").(uint64)
+
+	post, ok := loadPost(c, id, models.CanEditPost)
+	if !ok {
 		return
 	}
 
 	if err := models.DeletePost(&post); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		abortWithError(c, err)
 		return
 	}
+	events.Publish(events.Event{Type: events.PostDeleted, Post: post})
 
 	res.Code = http.StatusOK
 	res.Info = "Post deleted successfully"
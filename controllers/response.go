@@ -0,0 +1,130 @@
+package controllers
+
+import (
+	"errors"
+	"mas-diq/go-graphql/logging"
+	"mas-diq/go-graphql/models"
+	"mas-diq/go-graphql/schemas"
+	"mas-diq/go-graphql/uploads"
+	"mas-diq/go-graphql/validation"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+var logger = logging.For("controllers")
+
+// bindJSON binds the request body into input and writes a 400 response with
+// field level details when binding fails. It returns false if the handler should stop.
+func bindJSON(c *gin.Context, input interface{}) bool {
+	if err := c.ShouldBindJSON(input); err != nil {
+		abortWithBindingError(c, err)
+		return false
+	}
+	return true
+}
+
+// bindQuery is the query string counterpart of bindJSON.
+func bindQuery(c *gin.Context, input interface{}) bool {
+	if err := c.ShouldBindQuery(input); err != nil {
+		abortWithBindingError(c, err)
+		return false
+	}
+	return true
+}
+
+// abortWithFieldError reports a single invalid input field, in the same shape as binding errors.
+func abortWithFieldError(c *gin.Context, field, rule, param string) {
+	locale := validation.ParseLocale(c.GetHeader("Accept-Language"))
+	c.JSON(http.StatusBadRequest, schemas.Response{
+		Code:      http.StatusBadRequest,
+		Info:      "Validation failed",
+		ErrorCode: "VALIDATION_FAILED",
+		Errors:    []schemas.FieldError{validation.NewFieldError(locale, field, rule, param)},
+	})
+}
+
+func abortWithBindingError(c *gin.Context, err error) {
+	fields := validation.Translate(err, validation.ParseLocale(c.GetHeader("Accept-Language")))
+	if fields == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusBadRequest, schemas.Response{
+		Code:      http.StatusBadRequest,
+		Info:      "Validation failed",
+		ErrorCode: "VALIDATION_FAILED",
+		Errors:    fields,
+	})
+}
+
+// abortWithError writes the response for an error returned by the models package,
+// mapping known domain errors to their status code and error code.
+func abortWithError(c *gin.Context, err error) {
+	locale := validation.ParseLocale(c.GetHeader("Accept-Language"))
+
+	switch {
+	case errors.Is(err, models.ErrEmailTaken):
+		c.JSON(http.StatusConflict, schemas.Response{
+			Code:      http.StatusConflict,
+			Info:      "Email is already taken",
+			ErrorCode: "EMAIL_TAKEN",
+			Errors:    []schemas.FieldError{validation.NewFieldError(locale, "email", "unique", "")},
+		})
+	case errors.Is(err, models.ErrSlugTaken):
+		c.JSON(http.StatusConflict, schemas.Response{
+			Code:      http.StatusConflict,
+			Info:      "Slug is already taken",
+			ErrorCode: "SLUG_TAKEN",
+			Errors:    []schemas.FieldError{validation.NewFieldError(locale, "slug", "unique", "")},
+		})
+	case errors.Is(err, models.ErrTagTaken):
+		c.JSON(http.StatusConflict, schemas.Response{
+			Code:      http.StatusConflict,
+			Info:      "Tag already exists",
+			ErrorCode: "TAG_TAKEN",
+			Errors:    []schemas.FieldError{validation.NewFieldError(locale, "name", "unique", "")},
+		})
+	case errors.Is(err, models.ErrInvalidTagName):
+		c.JSON(http.StatusBadRequest, schemas.Response{
+			Code:      http.StatusBadRequest,
+			Info:      err.Error(),
+			ErrorCode: "VALIDATION_FAILED",
+			Errors:    []schemas.FieldError{validation.NewFieldError(locale, "name", "tag", "")},
+		})
+	case errors.Is(err, models.ErrInvalidParent):
+		c.JSON(http.StatusBadRequest, schemas.Response{
+			Code:      http.StatusBadRequest,
+			Info:      err.Error(),
+			ErrorCode: "VALIDATION_FAILED",
+			Errors:    []schemas.FieldError{validation.NewFieldError(locale, "parentId", "parent", "")},
+		})
+	case errors.Is(err, models.ErrInvalidTransition):
+		c.JSON(http.StatusConflict, schemas.Response{
+			Code:      http.StatusConflict,
+			Info:      err.Error(),
+			ErrorCode: "INVALID_TRANSITION",
+		})
+	case errors.Is(err, models.ErrForbidden):
+		c.JSON(http.StatusForbidden, schemas.Response{
+			Code:      http.StatusForbidden,
+			Info:      err.Error(),
+			ErrorCode: "FORBIDDEN",
+		})
+	case errors.Is(err, uploads.ErrUnsupportedType):
+		c.JSON(http.StatusUnsupportedMediaType, schemas.Response{
+			Code:      http.StatusUnsupportedMediaType,
+			Info:      err.Error(),
+			ErrorCode: "UNSUPPORTED_MEDIA_TYPE",
+		})
+	case errors.Is(err, uploads.ErrTooLarge):
+		c.JSON(http.StatusRequestEntityTooLarge, schemas.Response{
+			Code:      http.StatusRequestEntityTooLarge,
+			Info:      err.Error(),
+			ErrorCode: "FILE_TOO_LARGE",
+		})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,89 @@
+package controllers
+
+import (
+	"mas-diq/go-graphql/auth"
+	"mas-diq/go-graphql/dto"
+	"mas-diq/go-graphql/events"
+	"mas-diq/go-graphql/models"
+	"mas-diq/go-graphql/schemas"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+func GetPostRevisions(c *gin.Context) {
+	res := schemas.Response{}
+	id := c.MustGet("id").(uint64)
+	if _, ok := loadPost(c, id, models.CanViewRevisions); !ok {
+		return
+	}
+
+	var revisions []models.PostRevision
+	if err := models.GetPostRevisions(&revisions, id); err != nil {
+		abortWithError(c, err)
+		return
+	}
+
+	data := make([]dto.RevisionResponse, 0, len(revisions))
+	for _, revision := range revisions {
+		data = append(data, dto.NewRevisionResponse(revision))
+	}
+
+	res.Code = http.StatusOK
+	res.Info = "Revisions retrieved successfully"
+	res.Data = gin.H{
+		"revisions": data,
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+func DiffPostRevisions(c *gin.Context) {
+	res := schemas.Response{}
+	id := c.MustGet("id").(uint64)
+
+	var input dto.RevisionDiffQuery
+	if !bindQuery(c, &input) {
+		return
+	}
+	if _, ok := loadPost(c, id, models.CanViewRevisions); !ok {
+		return
+	}
+
+	diff, err := models.DiffRevisions(id, input.From, input.To)
+	if err != nil {
+		abortWithError(c, err)
+		return
+	}
+
+	res.Code = http.StatusOK
+	res.Info = "Revision diff computed successfully"
+	res.Data = dto.RevisionDiffResponse{
+		From: input.From,
+		To:   input.To,
+		Diff: diff,
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+func RestorePostRevision(c *gin.Context) {
+	res := schemas.Response{}
+	id := c.MustGet("id").(uint64)
+	revision := c.MustGet("revision").(uint64)
+
+	var post models.Post
+	if err := models.GetOnePost(&post, id); err != nil {
+		abortWithError(c, err)
+		return
+	}
+
+	if err := models.RestoreRevision(&post, int(revision), auth.UserFromContext(c.Request.Context())); err != nil {
+		abortWithError(c, err)
+		return
+	}
+	events.Publish(events.Event{Type: events.PostUpdated, Post: post})
+
+	res.Code = http.StatusOK
+	res.Info = "Revision restored successfully"
+	res.Data = dto.NewPostResponse(post)
+	c.JSON(http.StatusOK, res)
+}
@@ -0,0 +1,37 @@
+package controllers
+
+import (
+	"mas-diq/go-graphql/dto"
+	"mas-diq/go-graphql/schemas"
+	"mas-diq/go-graphql/search"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+func SearchPosts(c *gin.Context) {
+	res := schemas.Response{}
+
+	var input dto.SearchQuery
+	if !bindQuery(c, &input) {
+		return
+	}
+
+	results, err := search.Posts(c.Request.Context(), input.Q, input.Limit, input.Offset)
+	if err != nil {
+		abortWithError(c, err)
+		return
+	}
+
+	data := make([]dto.SearchResultResponse, 0, len(results))
+	for _, result := range results {
+		data = append(data, dto.NewSearchResultResponse(result))
+	}
+
+	res.Code = http.StatusOK
+	res.Info = "Search completed successfully"
+	res.Data = gin.H{
+		"results": data,
+	}
+	c.JSON(http.StatusOK, res)
+}
@@ -0,0 +1,154 @@
+package controllers
+
+import (
+	"fmt"
+	"mas-diq/go-graphql/auth"
+	"mas-diq/go-graphql/config"
+	"mas-diq/go-graphql/dto"
+	"mas-diq/go-graphql/events"
+	"mas-diq/go-graphql/models"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+)
+
+// streamBatchSize is how many changes are read from the log at a time.
+const streamBatchSize = 100
+
+// StreamPostChanges sends post changes as Server-Sent Events. Each event is
+// named after the change ("created", "updated", "deleted" or "published")
+// and carries the change log Seq as its id, so clients reconnecting with
+// Last-Event-ID get the changes they missed. Changes of posts the caller
+// may not read are left out, like the posts themselves.
+func StreamPostChanges(c *gin.Context) {
+	var input dto.PostStreamQuery
+	if !bindQuery(c, &input) {
+		return
+	}
+	filter := models.PostChangeFilter{AuthorID: input.AuthorID, Status: models.PostStatus(input.Status)}
+	viewer := auth.UserFromContext(c.Request.Context())
+
+	lastID, ok := streamStart(c, input)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	// The bus says when this instance changed a post; polling picks up changes made by others.
+	changed := events.Subscribe(ctx, nil)
+	poll := time.NewTicker(config.App.Posts.StreamPollInterval)
+	defer poll.Stop()
+	heartbeat := time.NewTicker(config.App.Posts.StreamHeartbeat)
+	defer heartbeat.Stop()
+
+	// The stream outlives the read and write timeouts of the server.
+	controller := http.NewResponseController(c.Writer)
+	controller.SetReadDeadline(time.Time{})
+	controller.SetWriteDeadline(time.Time{})
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no") // Keeps nginx from buffering the stream
+	c.Status(http.StatusOK)
+	// Clients wait this long (in milliseconds) before reconnecting.
+	fmt.Fprint(c.Writer, "retry: 3000\n\n")
+	c.Writer.Flush()
+
+	for {
+		var err error
+		if lastID, err = sendPostChanges(c, lastID, filter, viewer); err != nil {
+			logger.ErrorContext(ctx, "Failed to stream post changes", "error", err)
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case _, open := <-changed:
+			if !open {
+				return
+			}
+		case <-poll.C:
+		case <-heartbeat.C:
+			// A comment line, which EventSource ignores.
+			fmt.Fprint(c.Writer, ": heartbeat\n\n")
+			c.Writer.Flush()
+		}
+	}
+}
+
+// streamStart returns the ID of the last change the client has seen: the
+// Last-Event-ID header or the lastEventId parameter when it resumes, the
+// latest change otherwise so that only new changes are sent.
+func streamStart(c *gin.Context, input dto.PostStreamQuery) (uint64, bool) {
+	if header := c.GetHeader("Last-Event-ID"); header != "" {
+		id, err := strconv.ParseUint(header, 10, 64)
+		if err != nil {
+			abortWithFieldError(c, "Last-Event-ID", "numeric", "")
+			return 0, false
+		}
+		return id, true
+	}
+	if input.LastEventID != nil {
+		return *input.LastEventID, true
+	}
+
+	id, err := models.LastPostChangeSeq()
+	if err != nil {
+		abortWithError(c, err)
+		return 0, false
+	}
+	return id, true
+}
+
+// sendPostChanges sends the changes logged after lastID that viewer may see
+// and returns the Seq of the last one read.
+func sendPostChanges(c *gin.Context, lastID uint64, filter models.PostChangeFilter, viewer *models.User) (uint64, error) {
+	for {
+		var changes []models.PostChange
+		if err := models.GetPostChanges(&changes, lastID, filter, streamBatchSize); err != nil {
+			return lastID, err
+		}
+		if len(changes) == 0 {
+			return lastID, nil
+		}
+
+		ids := make([]uint, 0, len(changes))
+		for _, change := range changes {
+			ids = append(ids, change.PostID)
+		}
+		var posts []models.Post
+		if err := models.GetPostsByIDs(&posts, ids); err != nil {
+			return lastID, err
+		}
+		byID := make(map[uint]*models.Post, len(posts))
+		for i := range posts {
+			byID[posts[i].ID] = &posts[i]
+		}
+
+		for _, change := range changes {
+			lastID = change.Seq
+			// The post as it was after the change decides whether the change is shown.
+			if !models.CanViewPost(viewer, &models.Post{Status: change.Status, CreatedBy: change.AuthorID}) {
+				continue
+			}
+			post := byID[change.PostID]
+			if post != nil && !models.CanViewPost(viewer, post) {
+				post = nil
+			}
+			c.Render(-1, sse.Event{
+				Id:    strconv.FormatUint(change.Seq, 10),
+				Event: string(change.Type),
+				Data:  dto.NewPostChangeResponse(change, post),
+			})
+		}
+		c.Writer.Flush()
+
+		if len(changes) < streamBatchSize {
+			return lastID, nil
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package controllers
+
+import (
+	"mas-diq/go-graphql/dto"
+	"mas-diq/go-graphql/events"
+	"mas-diq/go-graphql/models"
+	"mas-diq/go-graphql/schemas"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+func GetListTag(c *gin.Context) {
+	res := schemas.Response{}
+
+	var input dto.TagListQuery
+	if !bindQuery(c, &input) {
+		return
+	}
+
+	var tags []models.TagCount
+	if err := models.GetTagCounts(&tags, input.Limit); err != nil {
+		abortWithError(c, err)
+		return
+	}
+
+	data := make([]dto.TagResponse, 0, len(tags))
+	for _, tag := range tags {
+		item := dto.NewTagResponse(tag.Tag)
+		item.PostCount = &tag.PostCount
+		data = append(data, item)
+	}
+
+	res.Code = http.StatusOK
+	res.Info = "Tags retrieved successfully"
+	res.Data = gin.H{
+		"tags": data,
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+func CreateTag(c *gin.Context) {
+	res := schemas.Response{}
+
+	var input dto.TagRequest
+	if !bindJSON(c, &input) {
+		return
+	}
+
+	tag := models.Tag{Name: input.Name}
+	if err := models.CreateTagData(&tag); err != nil {
+		abortWithError(c, err)
+		return
+	}
+
+	res.Code = http.StatusOK
+	res.Info = "Tag created successfully"
+	res.Data = dto.NewTagResponse(tag)
+	c.JSON(http.StatusOK, res)
+}
+
+func UpdateTag(c *gin.Context) {
+	res := schemas.Response{}
+	id := c.MustGet("id").(uint64)
+
+	var input dto.TagRequest
+	if !bindJSON(c, &input) {
+		return
+	}
+
+	var tag models.Tag
+	if err := models.GetOneTag(&tag, id); err != nil {
+		abortWithError(c, err)
+		return
+	}
+
+	tag.Name = input.Name
+	if err := models.UpdateTagData(&tag); err != nil {
+		abortWithError(c, err)
+		return
+	}
+
+	res.Code = http.StatusOK
+	res.Info = "Tag updated successfully"
+	res.Data = dto.NewTagResponse(tag)
+	c.JSON(http.StatusOK, res)
+}
+
+func DeleteTag(c *gin.Context) {
+	res := schemas.Response{}
+	id := c.MustGet("id").(uint64)
+
+	var tag models.Tag
+	if err := models.GetOneTag(&tag, id); err != nil {
+		abortWithError(c, err)
+		return
+	}
+
+	if err := models.DeleteTag(&tag); err != nil {
+		abortWithError(c, err)
+		return
+	}
+
+	res.Code = http.StatusOK
+	res.Info = "Tag deleted successfully"
+	res.Data = nil
+	c.JSON(http.StatusOK, res)
+}
+
+func SetPostTags(c *gin.Context) {
+	res := schemas.Response{}
+	id := c.MustGet("id").(uint64)
+
+	var input dto.SetPostTagsRequest
+	if !bindJSON(c, &input) {
+		return
+	}
+
+	post, ok := loadPost(c, id, models.CanEditPost)
+	if !ok {
+		return
+	}
+
+	if err := models.SetPostTags(&post, input.Tags); err != nil {
+		abortWithError(c, err)
+		return
+	}
+	events.Publish(events.Event{Type: events.PostUpdated, Post: post})
+
+	res.Code = http.StatusOK
+	res.Info = "Post tags updated successfully"
+	res.Data = dto.NewPostResponse(post)
+	c.JSON(http.StatusOK, res)
+}
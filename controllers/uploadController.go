@@ -0,0 +1,52 @@
+package controllers
+
+import (
+	"errors"
+	"mas-diq/go-graphql/auth"
+	"mas-diq/go-graphql/config"
+	"mas-diq/go-graphql/dto"
+	"mas-diq/go-graphql/schemas"
+	"mas-diq/go-graphql/storage"
+	"mas-diq/go-graphql/uploads"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MultipartOverhead allows for the multipart framing and other fields around an uploaded file.
+const MultipartOverhead = 1 << 20
+
+func CreateUpload(c *gin.Context) {
+	res := schemas.Response{}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, config.App.Uploads.MaxBytes+MultipartOverhead)
+	header, err := c.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			abortWithError(c, uploads.ErrTooLarge)
+			return
+		}
+		abortWithFieldError(c, "file", "required", "")
+		return
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		abortWithError(c, err)
+		return
+	}
+	defer file.Close()
+
+	actor := auth.UserFromContext(c.Request.Context())
+	upload, err := uploads.SaveImage(c.Request.Context(), storage.Default, file, actor.ID)
+	if err != nil {
+		abortWithError(c, err)
+		return
+	}
+
+	res.Code = http.StatusOK
+	res.Info = "File uploaded successfully"
+	res.Data = dto.NewUploadResponse(*upload, storage.Default)
+	c.JSON(http.StatusOK, res)
+}
@@ -13,14 +13,13 @@ func CreateUser(c *gin.Context) {
 	res := schemas.Response{}
 
 	var input dto.CreateUserRequest
-	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindJSON(c, &input) {
 		return
 	}
 
 	user := models.User{Name: input.Name, Email: input.Email}
 	if err := models.CreateUserData(&user); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		abortWithError(c, err)
 		return
 	}
 
@@ -40,7 +39,7 @@ func GetUser(c *gin.Context) {
 
 	var user models.User
 	if err := models.GetOneUser(&user, id); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		abortWithError(c, err)
 		return
 	}
 
@@ -59,7 +58,7 @@ func GetListUser(c *gin.Context) {
 
 	var user []models.User
 	if err := models.GetListUser(&user, models.User{}); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		abortWithError(c, err)
 		return
 	}
 
@@ -71,26 +70,47 @@ func GetListUser(c *gin.Context) {
 	c.JSON(http.StatusOK, res)
 }
 
+func CheckEmailAvailability(c *gin.Context) {
+	res := schemas.Response{}
+
+	var input dto.EmailAvailabilityQuery
+	if !bindQuery(c, &input) {
+		return
+	}
+
+	available, err := models.IsEmailAvailable(input.Email, 0)
+	if err != nil {
+		abortWithError(c, err)
+		return
+	}
+
+	res.Code = http.StatusOK
+	res.Info = "Email availability checked successfully"
+	res.Data = dto.EmailAvailabilityResponse{
+		Email:     models.NormalizeEmail(input.Email),
+		Available: available,
+	}
+	c.JSON(http.StatusOK, res)
+}
+
 func UpdateUser(c *gin.Context) {
 	res := schemas.Response{}
 	id := c.MustGet("id").(uint64)
 
 	var input dto.UpdateUserRequest
-	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindJSON(c, &input) {
 		return
 	}
 
 	var user models.User
 	if err := models.GetOneUser(&user, id); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		abortWithError(c, err)
 		return
 	}
 
-	user.Name = input.Name
-	user.Email = input.Email
+	input.ApplyTo(&user)
 	if err := models.UpdateUserData(&user); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		abortWithError(c, err)
 		return
 	}
 
@@ -110,12 +130,12 @@ func DeleteUser(c *gin.Context) {
 
 	var user models.User
 	if err := models.GetOneUser(&user, id); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		abortWithError(c, err)
 		return
 	}
 
 	if err := models.DeleteUser(&user); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		abortWithError(c, err)
 		return
 	}
 
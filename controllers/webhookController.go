@@ -0,0 +1,235 @@
+package controllers
+
+import (
+	"mas-diq/go-graphql/auth"
+	"mas-diq/go-graphql/dto"
+	"mas-diq/go-graphql/models"
+	"mas-diq/go-graphql/schemas"
+	"mas-diq/go-graphql/webhooks"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func GetListWebhook(c *gin.Context) {
+	res := schemas.Response{}
+
+	var list []models.Webhook
+	if err := models.GetListWebhook(&list); err != nil {
+		abortWithError(c, err)
+		return
+	}
+
+	data := make([]dto.WebhookResponse, 0, len(list))
+	for _, webhook := range list {
+		data = append(data, dto.NewWebhookResponse(webhook))
+	}
+
+	res.Code = http.StatusOK
+	res.Info = "Webhooks retrieved successfully"
+	res.Data = gin.H{
+		"webhooks": data,
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+// CreateWebhook registers an endpoint. The response carries the signing
+// secret, which can't be retrieved afterwards.
+func CreateWebhook(c *gin.Context) {
+	res := schemas.Response{}
+
+	var input dto.CreateWebhookRequest
+	if !bindJSON(c, &input) {
+		return
+	}
+
+	secret, err := webhooks.NewSecret()
+	if err != nil {
+		abortWithError(c, err)
+		return
+	}
+
+	webhook := models.Webhook{
+		URL:         input.URL,
+		Description: input.Description,
+		Secret:      secret,
+		Events:      dto.JoinEvents(input.Events),
+		Active:      input.Active == nil || *input.Active,
+		CreatedBy:   auth.UserFromContext(c.Request.Context()).ID,
+	}
+	if err := models.CreateWebhookData(&webhook); err != nil {
+		abortWithError(c, err)
+		return
+	}
+
+	data := dto.NewWebhookResponse(webhook)
+	data.Secret = webhook.Secret
+
+	res.Code = http.StatusOK
+	res.Info = "Webhook created successfully"
+	res.Data = data
+	c.JSON(http.StatusOK, res)
+}
+
+func GetWebhook(c *gin.Context) {
+	res := schemas.Response{}
+	id := c.MustGet("id").(uint64)
+
+	var webhook models.Webhook
+	if err := models.GetOneWebhook(&webhook, id); err != nil {
+		abortWithError(c, err)
+		return
+	}
+
+	res.Code = http.StatusOK
+	res.Info = "Webhook retrieved successfully"
+	res.Data = dto.NewWebhookResponse(webhook)
+	c.JSON(http.StatusOK, res)
+}
+
+func UpdateWebhook(c *gin.Context) {
+	res := schemas.Response{}
+	id := c.MustGet("id").(uint64)
+
+	var input dto.UpdateWebhookRequest
+	if !bindJSON(c, &input) {
+		return
+	}
+
+	var webhook models.Webhook
+	if err := models.GetOneWebhook(&webhook, id); err != nil {
+		abortWithError(c, err)
+		return
+	}
+
+	if input.URL != nil {
+		webhook.URL = *input.URL
+	}
+	if input.Description != nil {
+		webhook.Description = *input.Description
+	}
+	if input.Events != nil {
+		webhook.Events = dto.JoinEvents(input.Events)
+	}
+	if input.Active != nil {
+		webhook.Active = *input.Active
+	}
+
+	if err := models.UpdateWebhookData(&webhook); err != nil {
+		abortWithError(c, err)
+		return
+	}
+
+	res.Code = http.StatusOK
+	res.Info = "Webhook updated successfully"
+	res.Data = dto.NewWebhookResponse(webhook)
+	c.JSON(http.StatusOK, res)
+}
+
+func DeleteWebhook(c *gin.Context) {
+	res := schemas.Response{}
+	id := c.MustGet("id").(uint64)
+
+	var webhook models.Webhook
+	if err := models.GetOneWebhook(&webhook, id); err != nil {
+		abortWithError(c, err)
+		return
+	}
+
+	if err := models.DeleteWebhook(&webhook); err != nil {
+		abortWithError(c, err)
+		return
+	}
+
+	res.Code = http.StatusOK
+	res.Info = "Webhook deleted successfully"
+	c.JSON(http.StatusOK, res)
+}
+
+func GetWebhookDeliveries(c *gin.Context) {
+	res := schemas.Response{}
+	id := c.MustGet("id").(uint64)
+
+	var input dto.WebhookDeliveryListQuery
+	if !bindQuery(c, &input) {
+		return
+	}
+	if input.Limit == 0 {
+		input.Limit = 50
+	}
+
+	var webhook models.Webhook
+	if err := models.GetOneWebhook(&webhook, id); err != nil {
+		abortWithError(c, err)
+		return
+	}
+
+	var deliveries []models.WebhookDelivery
+	if err := models.GetWebhookDeliveries(&deliveries, id, models.DeliveryStatus(input.Status), input.Limit); err != nil {
+		abortWithError(c, err)
+		return
+	}
+
+	data := make([]dto.WebhookDeliveryResponse, 0, len(deliveries))
+	for _, delivery := range deliveries {
+		data = append(data, dto.NewWebhookDeliveryResponse(delivery))
+	}
+
+	res.Code = http.StatusOK
+	res.Info = "Webhook deliveries retrieved successfully"
+	res.Data = gin.H{
+		"deliveries": data,
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+// ReplayWebhookDelivery queues a delivery to be sent again on the next
+// dispatcher run, with a fresh set of attempts.
+func ReplayWebhookDelivery(c *gin.Context) {
+	res := schemas.Response{}
+	id := c.MustGet("id").(uint64)
+	deliveryID := c.MustGet("delivery").(uint64)
+
+	var delivery models.WebhookDelivery
+	if err := models.GetOneWebhookDelivery(&delivery, id, deliveryID); err != nil {
+		abortWithError(c, err)
+		return
+	}
+
+	if err := models.ReplayDelivery(&delivery, time.Now()); err != nil {
+		abortWithError(c, err)
+		return
+	}
+
+	res.Code = http.StatusOK
+	res.Info = "Webhook delivery queued for replay"
+	res.Data = dto.NewWebhookDeliveryResponse(delivery)
+	c.JSON(http.StatusOK, res)
+}
+
+// ReplayDeadWebhookDeliveries queues every dead delivery of a webhook again,
+// typically once the endpoint is fixed.
+func ReplayDeadWebhookDeliveries(c *gin.Context) {
+	res := schemas.Response{}
+	id := c.MustGet("id").(uint64)
+
+	var webhook models.Webhook
+	if err := models.GetOneWebhook(&webhook, id); err != nil {
+		abortWithError(c, err)
+		return
+	}
+
+	count, err := models.ReplayDeadDeliveries(id, time.Now())
+	if err != nil {
+		abortWithError(c, err)
+		return
+	}
+
+	res.Code = http.StatusOK
+	res.Info = "Dead webhook deliveries queued for replay"
+	res.Data = gin.H{
+		"replayed": count,
+	}
+	c.JSON(http.StatusOK, res)
+}
@@ -0,0 +1,48 @@
+package dto
+
+import (
+	"mas-diq/go-graphql/models"
+	"time"
+)
+
+type CreateAPIKeyRequest struct {
+	Name   string   `json:"name" binding:"required,max=100"`
+	Scopes []string `json:"scopes" binding:"required,min=1,max=4,dive,oneof=users:read users:write posts:read posts:write"`
+	// ExpiresAt is when the key stops working; empty for a key that doesn't expire.
+	ExpiresAt *time.Time `json:"expiresAt"`
+}
+
+type APIKeyResponse struct {
+	ID         uint       `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	UserID     uint       `json:"userId"`
+	ExpiresAt  *time.Time `json:"expiresAt"`
+	LastUsedAt *time.Time `json:"lastUsedAt"`
+	RevokedAt  *time.Time `json:"revokedAt"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	// Key authenticates the client; it is only returned when the key is created.
+	Key string `json:"key,omitempty"`
+}
+
+// JoinScopes stores a list of scopes the way models.APIKey keeps them,
+// without duplicates.
+func JoinScopes(scopes []string) string {
+	return joinUnique(scopes)
+}
+
+// NewAPIKeyResponse maps an API key model to its REST representation, without its secret.
+func NewAPIKeyResponse(key models.APIKey) APIKeyResponse {
+	return APIKeyResponse{
+		ID:         key.ID,
+		Name:       key.Name,
+		Prefix:     key.Prefix,
+		Scopes:     key.ScopeList(),
+		UserID:     key.UserID,
+		ExpiresAt:  key.ExpiresAt,
+		LastUsedAt: key.LastUsedAt,
+		RevokedAt:  key.RevokedAt,
+		CreatedAt:  key.CreatedAt,
+	}
+}
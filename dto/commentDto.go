@@ -0,0 +1,110 @@
+package dto
+
+import (
+	"encoding/base64"
+	"errors"
+	"mas-diq/go-graphql/models"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type CreateCommentRequest struct {
+	PostID   uint   `json:"postId"`
+	ParentID *uint  `json:"parentId" binding:"omitempty,gt=0"`
+	Body     string `json:"body" binding:"required,min=1,max=5000"`
+}
+
+type UpdateCommentRequest struct {
+	Body string `json:"body" binding:"required,min=1,max=5000"`
+}
+
+type ModerateCommentRequest struct {
+	Status string `json:"status" binding:"required,oneof=approved pending rejected"`
+}
+
+type CommentPageQuery struct {
+	First int    `form:"first" binding:"omitempty,gte=1,lte=100"`
+	After string `form:"after"`
+}
+
+// Limit returns the requested page size, defaulting to 20.
+func (q CommentPageQuery) Limit() int {
+	if q.First == 0 {
+		return 20
+	}
+	return q.First
+}
+
+type CommentResponse struct {
+	ID        uint      `json:"id"`
+	PostID    uint      `json:"postId"`
+	AuthorID  uint      `json:"authorId"`
+	ParentID  *uint     `json:"parentId"`
+	Body      string    `json:"body"`
+	Status    string    `json:"status"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// NewCommentResponse maps a comment model to its REST representation.
+func NewCommentResponse(comment models.Comment) CommentResponse {
+	return CommentResponse{
+		ID:        comment.ID,
+		PostID:    comment.PostID,
+		AuthorID:  comment.AuthorID,
+		ParentID:  comment.ParentID,
+		Body:      comment.Body,
+		Status:    string(comment.Status),
+		CreatedAt: comment.CreatedAt,
+	}
+}
+
+type PageInfo struct {
+	HasNextPage bool   `json:"hasNextPage"`
+	EndCursor   string `json:"endCursor,omitempty"`
+}
+
+type CommentPageResponse struct {
+	Comments   []CommentResponse `json:"comments"`
+	PageInfo   PageInfo          `json:"pageInfo"`
+	TotalCount int64             `json:"totalCount"`
+}
+
+// NewCommentPageResponse maps a page of comments to its REST representation.
+func NewCommentPageResponse(page models.CommentPage) CommentPageResponse {
+	res := CommentPageResponse{
+		Comments:   make([]CommentResponse, 0, len(page.Comments)),
+		PageInfo:   PageInfo{HasNextPage: page.HasNextPage},
+		TotalCount: page.TotalCount,
+	}
+	for _, comment := range page.Comments {
+		res.Comments = append(res.Comments, NewCommentResponse(comment))
+	}
+	if n := len(page.Comments); n > 0 {
+		res.PageInfo.EndCursor = EncodeCursor(page.Comments[n-1].ID)
+	}
+	return res
+}
+
+const cursorPrefix = "cursor:"
+
+// EncodeCursor turns an ID into the opaque cursor used by paginated lists.
+func EncodeCursor(id uint) string {
+	return base64.StdEncoding.EncodeToString([]byte(cursorPrefix + strconv.FormatUint(uint64(id), 10)))
+}
+
+// DecodeCursor reverses EncodeCursor; an empty cursor means "from the start".
+func DecodeCursor(cursor string) (uint, error) {
+	if cursor == "" {
+		return 0, nil
+	}
+	raw, err := base64.StdEncoding.DecodeString(cursor)
+	if err != nil || !strings.HasPrefix(string(raw), cursorPrefix) {
+		return 0, errors.New("invalid cursor")
+	}
+	id, err := strconv.ParseUint(strings.TrimPrefix(string(raw), cursorPrefix), 10, 64)
+	if err != nil {
+		return 0, errors.New("invalid cursor")
+	}
+	return uint(id), nil
+}
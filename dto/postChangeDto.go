@@ -0,0 +1,44 @@
+package dto
+
+import (
+	"mas-diq/go-graphql/models"
+	"time"
+)
+
+type PostStreamQuery struct {
+	AuthorID uint   `form:"authorId"`
+	Status   string `form:"status" binding:"omitempty,oneof=draft scheduled published archived"`
+	// LastEventID resumes the stream like the Last-Event-ID header, for
+	// clients that can't set headers.
+	LastEventID *uint64 `form:"lastEventId"`
+}
+
+type PostChangeResponse struct {
+	// ID is the position of the change in the log, the id of its event.
+	ID        uint64    `json:"id"`
+	Type      string    `json:"type"`
+	PostID    uint      `json:"postId"`
+	AuthorID  uint      `json:"authorId"`
+	Status    string    `json:"status"`
+	ChangedAt time.Time `json:"changedAt"`
+	// Post is the post as it is now, missing once it is deleted or hidden
+	// from the caller.
+	Post *PostResponse `json:"post,omitempty"`
+}
+
+// NewPostChangeResponse maps a logged change, and the post when it still exists, to its REST representation.
+func NewPostChangeResponse(change models.PostChange, post *models.Post) PostChangeResponse {
+	res := PostChangeResponse{
+		ID:        change.Seq,
+		Type:      string(change.Type),
+		PostID:    change.PostID,
+		AuthorID:  change.AuthorID,
+		Status:    string(change.Status),
+		ChangedAt: change.CreatedAt,
+	}
+	if post != nil {
+		current := NewPostResponse(*post)
+		res.Post = &current
+	}
+	return res
+}
@@ -1,28 +1,109 @@
 package dto
 
+import (
+	"mas-diq/go-graphql/models"
+	"time"
+)
+
 type CreatePostRequest struct {
-	Title     string `json:"title" binding:"required,min=3,max=255"`
-	Subtitle  string `json:"subtitle" binding:"max=255"`
-	Image     string `json:"image" binding:"max=255"`
-	Content   string `json:"content" binding:"required"`
-	Status    string `json:"status" binding:"omitempty,oneof=draft published archived"`
-	CreatedBy uint   `json:"createdBy" binding:"required"`
+	Title    string `json:"title" binding:"required,min=3,max=255"`
+	Slug     string `json:"slug" binding:"omitempty,slug"`
+	Subtitle string `json:"subtitle" binding:"max=255"`
+	Image    string `json:"image" binding:"max=255"`
+	Content  string `json:"content" binding:"required"`
+	// Status is where the new draft moves to, through the post workflow.
+	Status string `json:"status" binding:"omitempty,oneof=draft published archived"`
+
+	Tags []string `json:"tags" binding:"omitempty,max=20,dive,min=1,max=50"`
+}
+
+// ToModel builds the post to be inserted from the request, as a draft of
+// author. Status is left to models.TransitionPost.
+func (r CreatePostRequest) ToModel(author uint) models.Post {
+	return models.Post{
+		Title:     r.Title,
+		Slug:      r.Slug,
+		Subtitle:  r.Subtitle,
+		Image:     r.Image,
+		Content:   r.Content,
+		Status:    models.Draft,
+		CreatedBy: author,
+	}
 }
 
 type UpdatePostRequest struct {
 	Title    string `json:"title" binding:"omitempty,min=3,max=255"`
+	Slug     string `json:"slug" binding:"omitempty,slug"`
 	Subtitle string `json:"subtitle" binding:"omitempty,max=255"`
 	Image    string `json:"image" binding:"omitempty,max=255"`
 	Content  string `json:"content" binding:"omitempty"`
 	Status   string `json:"status" binding:"omitempty,oneof=draft published archived"`
+
+	// Tags replaces the post's tags when sent; leave it out to keep them.
+	Tags []string `json:"tags" binding:"omitempty,max=20,dive,min=1,max=50"`
+}
+
+// ApplyTo copies the fields that were sent onto an existing post.
+// Status changes go through models.TransitionPost instead.
+func (r UpdatePostRequest) ApplyTo(post *models.Post) {
+	if r.Title != "" {
+		post.Title = r.Title
+	}
+	if r.Slug != "" {
+		post.Slug = r.Slug
+	}
+	if r.Subtitle != "" {
+		post.Subtitle = r.Subtitle
+	}
+	if r.Image != "" {
+		post.Image = r.Image
+	}
+	if r.Content != "" {
+		post.Content = r.Content
+	}
+}
+
+type PublishPostRequest struct {
+	// ScheduledFor publishes the post at a later time; empty publishes it now.
+	ScheduledFor *time.Time `json:"scheduledFor"`
 }
 
 type PostResponse struct {
 	ID        uint   `json:"id"`
 	Title     string `json:"title"`
+	Slug      string `json:"slug"`
 	Subtitle  string `json:"subtitle"`
 	Image     string `json:"image"`
 	Content   string `json:"content"`
 	Status    string `json:"status"`
 	CreatedBy uint   `json:"createdBy"`
+
+	PublishedAt  *time.Time `json:"publishedAt"`
+	ScheduledFor *time.Time `json:"scheduledFor"`
+
+	Tags []string `json:"tags"`
+}
+
+// NewPostResponse maps a post model to its REST representation.
+func NewPostResponse(post models.Post) PostResponse {
+	tags := make([]string, 0, len(post.Tags))
+	for _, tag := range post.Tags {
+		tags = append(tags, tag.Name)
+	}
+
+	return PostResponse{
+		ID:        post.ID,
+		Title:     post.Title,
+		Slug:      post.Slug,
+		Subtitle:  post.Subtitle,
+		Image:     post.Image,
+		Content:   post.Content,
+		Status:    string(post.Status),
+		CreatedBy: post.CreatedBy,
+
+		PublishedAt:  post.PublishedAt,
+		ScheduledFor: post.ScheduledFor,
+
+		Tags: tags,
+	}
 }
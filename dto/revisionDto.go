@@ -0,0 +1,44 @@
+package dto
+
+import (
+	"mas-diq/go-graphql/models"
+	"time"
+)
+
+type RevisionDiffQuery struct {
+	From int `form:"from" binding:"required,gt=0"`
+	To   int `form:"to" binding:"required,gt=0"`
+}
+
+type RevisionResponse struct {
+	Revision  int       `json:"revision"`
+	EditorID  *uint     `json:"editorId"`
+	Title     string    `json:"title"`
+	Slug      string    `json:"slug"`
+	Subtitle  string    `json:"subtitle"`
+	Image     string    `json:"image"`
+	Content   string    `json:"content"`
+	Status    string    `json:"status"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// NewRevisionResponse maps a post revision to its REST representation.
+func NewRevisionResponse(revision models.PostRevision) RevisionResponse {
+	return RevisionResponse{
+		Revision:  revision.Revision,
+		EditorID:  revision.EditorID,
+		Title:     revision.Title,
+		Slug:      revision.Slug,
+		Subtitle:  revision.Subtitle,
+		Image:     revision.Image,
+		Content:   revision.Content,
+		Status:    string(revision.Status),
+		CreatedAt: revision.CreatedAt,
+	}
+}
+
+type RevisionDiffResponse struct {
+	From int    `json:"from"`
+	To   int    `json:"to"`
+	Diff string `json:"diff"`
+}
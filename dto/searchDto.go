@@ -0,0 +1,24 @@
+package dto
+
+import "mas-diq/go-graphql/search"
+
+type SearchQuery struct {
+	Q      string `form:"q" binding:"required,min=2,max=200"`
+	Limit  int    `form:"limit,default=20" binding:"gte=1,lte=100"`
+	Offset int    `form:"offset" binding:"omitempty,gte=0"`
+}
+
+type SearchResultResponse struct {
+	Post       PostResponse      `json:"post"`
+	Score      float64           `json:"score"`
+	Highlights search.Highlights `json:"highlights"`
+}
+
+// NewSearchResultResponse maps a search result to its REST representation.
+func NewSearchResultResponse(result search.Result) SearchResultResponse {
+	return SearchResultResponse{
+		Post:       NewPostResponse(result.Post),
+		Score:      result.Score,
+		Highlights: result.Highlights,
+	}
+}
@@ -0,0 +1,31 @@
+package dto
+
+import "mas-diq/go-graphql/models"
+
+type TagRequest struct {
+	Name string `json:"name" binding:"required,min=1,max=50"`
+}
+
+type TagListQuery struct {
+	Limit int `form:"limit" binding:"omitempty,gte=0,lte=500"`
+}
+
+type SetPostTagsRequest struct {
+	Tags []string `json:"tags" binding:"max=20,dive,min=1,max=50"`
+}
+
+type TagResponse struct {
+	ID        uint   `json:"id"`
+	Name      string `json:"name"`
+	Slug      string `json:"slug"`
+	PostCount *int64 `json:"postCount,omitempty"`
+}
+
+// NewTagResponse maps a tag model to its REST representation.
+func NewTagResponse(tag models.Tag) TagResponse {
+	return TagResponse{
+		ID:   tag.ID,
+		Name: tag.Name,
+		Slug: tag.Slug,
+	}
+}
@@ -0,0 +1,41 @@
+package dto
+
+import (
+	"mas-diq/go-graphql/models"
+	"mas-diq/go-graphql/storage"
+)
+
+type ImageResponse struct {
+	URL    string `json:"url"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+}
+
+type UploadResponse struct {
+	ID          uint          `json:"id"`
+	Key         string        `json:"key"` // Set a post's image to this key to use the upload
+	URL         string        `json:"url"`
+	ContentType string        `json:"contentType"`
+	Size        int64         `json:"size"`
+	Width       int           `json:"width"`
+	Height      int           `json:"height"`
+	Thumbnail   ImageResponse `json:"thumbnail"`
+}
+
+// NewUploadResponse maps an upload to its REST representation, with URLs from the backend.
+func NewUploadResponse(upload models.Upload, backend storage.Backend) UploadResponse {
+	return UploadResponse{
+		ID:          upload.ID,
+		Key:         upload.Key,
+		URL:         backend.URL(upload.Key),
+		ContentType: upload.ContentType,
+		Size:        upload.Size,
+		Width:       upload.Width,
+		Height:      upload.Height,
+		Thumbnail: ImageResponse{
+			URL:    backend.URL(upload.ThumbnailKey),
+			Width:  upload.ThumbnailWidth,
+			Height: upload.ThumbnailHeight,
+		},
+	}
+}
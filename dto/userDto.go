@@ -1,5 +1,7 @@
 package dto
 
+import "mas-diq/go-graphql/models"
+
 // Request
 type CreateUserRequest struct {
 	Name  string `json:"name" binding:"required,min=3,max=100"`
@@ -11,9 +13,28 @@ type UpdateUserRequest struct {
 	Email string `json:"email" binding:"omitempty,email,max=255"`
 }
 
+// ApplyTo copies the fields that were sent onto an existing user.
+func (r UpdateUserRequest) ApplyTo(user *models.User) {
+	if r.Name != "" {
+		user.Name = r.Name
+	}
+	if r.Email != "" {
+		user.Email = r.Email
+	}
+}
+
+type EmailAvailabilityQuery struct {
+	Email string `form:"email" binding:"required,email,max=255"`
+}
+
 // Response
 type UserResponse struct {
 	ID    uint   `json:"id"`
 	Name  string `json:"name"`
 	Email string `json:"email"`
 }
+
+type EmailAvailabilityResponse struct {
+	Email     string `json:"email"`
+	Available bool   `json:"available"`
+}
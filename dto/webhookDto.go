@@ -0,0 +1,100 @@
+package dto
+
+import (
+	"mas-diq/go-graphql/models"
+	"strings"
+	"time"
+)
+
+type CreateWebhookRequest struct {
+	URL         string `json:"url" binding:"required,url,max=2048"`
+	Description string `json:"description" binding:"max=255"`
+	// Events are the event types to receive, "*" for all of them.
+	Events []string `json:"events" binding:"required,min=1,max=10,dive,oneof=* user.created user.deleted post.created post.updated post.published post.deleted"`
+	Active *bool    `json:"active"`
+}
+
+type UpdateWebhookRequest struct {
+	URL         *string  `json:"url" binding:"omitempty,url,max=2048"`
+	Description *string  `json:"description" binding:"omitempty,max=255"`
+	Events      []string `json:"events" binding:"omitempty,min=1,max=10,dive,oneof=* user.created user.deleted post.created post.updated post.published post.deleted"`
+	Active      *bool    `json:"active"`
+}
+
+type WebhookDeliveryListQuery struct {
+	Status string `form:"status" binding:"omitempty,oneof=pending delivered dead"`
+	Limit  int    `form:"limit" binding:"omitempty,gte=0,lte=500"`
+}
+
+type WebhookResponse struct {
+	ID          uint      `json:"id"`
+	URL         string    `json:"url"`
+	Description string    `json:"description"`
+	Events      []string  `json:"events"`
+	Active      bool      `json:"active"`
+	CreatedBy   uint      `json:"createdBy"`
+	CreatedAt   time.Time `json:"createdAt"`
+	// Secret signs the deliveries; it is only returned when the webhook is created.
+	Secret string `json:"secret,omitempty"`
+}
+
+type WebhookDeliveryResponse struct {
+	ID            uint64     `json:"id"`
+	EventID       uint64     `json:"eventId"`
+	EventType     string     `json:"eventType"`
+	Status        string     `json:"status"`
+	Attempts      int        `json:"attempts"`
+	NextAttemptAt *time.Time `json:"nextAttemptAt"`
+	LastStatus    int        `json:"lastStatus"`
+	LastError     string     `json:"lastError"`
+	DeliveredAt   *time.Time `json:"deliveredAt"`
+	CreatedAt     time.Time  `json:"createdAt"`
+}
+
+// JoinEvents stores a list of event types the way models.Webhook keeps them,
+// without duplicates.
+func JoinEvents(events []string) string {
+	return joinUnique(events)
+}
+
+// joinUnique joins values with commas, leaving out duplicates.
+func joinUnique(values []string) string {
+	seen := make(map[string]bool, len(values))
+	unique := make([]string, 0, len(values))
+	for _, value := range values {
+		if !seen[value] {
+			seen[value] = true
+			unique = append(unique, value)
+		}
+	}
+	return strings.Join(unique, ",")
+}
+
+// NewWebhookResponse maps a webhook model to its REST representation, without its secret.
+func NewWebhookResponse(webhook models.Webhook) WebhookResponse {
+	return WebhookResponse{
+		ID:          webhook.ID,
+		URL:         webhook.URL,
+		Description: webhook.Description,
+		Events:      webhook.EventList(),
+		Active:      webhook.Active,
+		CreatedBy:   webhook.CreatedBy,
+		CreatedAt:   webhook.CreatedAt,
+	}
+}
+
+// NewWebhookDeliveryResponse maps a delivery, with its event loaded, to its REST representation.
+func NewWebhookDeliveryResponse(delivery models.WebhookDelivery) WebhookDeliveryResponse {
+	return WebhookDeliveryResponse{
+		ID:            delivery.ID,
+		EventID:       delivery.EventID,
+		EventType:     string(delivery.Event.Type),
+		Status:        string(delivery.Status),
+		Attempts:      delivery.Attempts,
+		NextAttemptAt: delivery.NextAttemptAt,
+		LastStatus:    delivery.LastStatus,
+		LastError:     delivery.LastError,
+		DeliveredAt:   delivery.DeliveredAt,
+		CreatedAt:     delivery.CreatedAt,
+	}
+}
@@ -0,0 +1,124 @@
+package events
+
+import (
+	"context"
+	"mas-diq/go-graphql/logging"
+	"mas-diq/go-graphql/models"
+	"sync"
+)
+
+var logger = logging.For("events")
+
+// Type names what happened to a post.
+type Type string
+
+const (
+	PostCreated   Type = "post.created"
+	PostUpdated   Type = "post.updated"
+	PostPublished Type = "post.published"
+	PostDeleted   Type = "post.deleted"
+)
+
+// Event is a change to a post, published once it is saved.
+type Event struct {
+	Type Type
+	Post models.Post
+}
+
+// Updated returns the event of a saved change to a post: PostPublished when
+// the change published it, PostUpdated otherwise.
+func Updated(post models.Post, previous models.PostStatus) Event {
+	if post.Status == models.Published && previous != models.Published {
+		return Event{Type: PostPublished, Post: post}
+	}
+	return Event{Type: PostUpdated, Post: post}
+}
+
+// bufferSize is how many events a subscriber may fall behind before it misses some.
+const bufferSize = 64
+
+// Bus delivers events to the subscribers of this process. Publishing never
+// blocks: a subscriber that doesn't keep up misses events.
+type Bus struct {
+	mu          sync.RWMutex
+	subscribers map[*subscriber]struct{}
+	closed      bool
+}
+
+type subscriber struct {
+	events chan Event
+	filter func(Event) bool
+}
+
+func NewBus() *Bus {
+	return &Bus{subscribers: make(map[*subscriber]struct{})}
+}
+
+// Default is the bus controllers and mutations publish to.
+var Default = NewBus()
+
+// Publish sends an event to the default bus.
+func Publish(event Event) {
+	Default.Publish(event)
+}
+
+// Subscribe listens on the default bus.
+func Subscribe(ctx context.Context, filter func(Event) bool) <-chan Event {
+	return Default.Subscribe(ctx, filter)
+}
+
+// Publish sends an event to every subscriber whose filter accepts it.
+func (b *Bus) Publish(event Event) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for s := range b.subscribers {
+		if s.filter != nil && !s.filter(event) {
+			continue
+		}
+		select {
+		case s.events <- event:
+		default:
+			logger.Warn("Dropped event for a slow subscriber", "type", event.Type, "post_id", event.Post.ID)
+		}
+	}
+}
+
+// Subscribe returns the events accepted by filter (all events when it is
+// nil). The channel is closed once ctx is done, or when the bus is closed.
+func (b *Bus) Subscribe(ctx context.Context, filter func(Event) bool) <-chan Event {
+	s := &subscriber{events: make(chan Event, bufferSize), filter: filter}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		close(s.events)
+		return s.events
+	}
+	b.subscribers[s] = struct{}{}
+
+	go func() {
+		<-ctx.Done()
+		b.unsubscribe(s)
+	}()
+	return s.events
+}
+
+func (b *Bus) unsubscribe(s *subscriber) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.subscribers[s]; ok {
+		delete(b.subscribers, s)
+		close(s.events)
+	}
+}
+
+// Close closes the channel of every subscriber, and those of later ones
+// right away. It ends the streams fed by the bus when the server shuts down.
+func (b *Bus) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	for s := range b.subscribers {
+		delete(b.subscribers, s)
+		close(s.events)
+	}
+}
@@ -0,0 +1,225 @@
+package graphql
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"mas-diq/go-graphql/models"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+)
+
+// CacheScope tells whether a response may be stored by shared caches (CDNs,
+// proxies) or only by the client that asked for it.
+type CacheScope int
+
+const (
+	CachePublic CacheScope = iota
+	CachePrivate
+)
+
+// CacheHint is how long the value of a field may be cached, and by whom.
+type CacheHint struct {
+	MaxAge time.Duration
+	Scope  CacheScope
+}
+
+// CacheControl computes the Cache-Control header of query responses from
+// the hints of the fields they contain, like Apollo's cache control: the
+// response may be cached as long as its shortest lived field, and only
+// privately if one of its fields is private.
+type CacheControl struct {
+	// MaxAge is the hint of root fields and of fields returning objects that
+	// have no hint of their own. Scalar fields share the hint of their parent.
+	// Zero makes responses revalidate on every request.
+	MaxAge time.Duration
+	// FieldHints overrides the hint of fields, keyed by "Type.field". Fields
+	// missing from it use defaultCacheHints.
+	FieldHints map[string]CacheHint
+}
+
+// defaultCacheHints are the hints of fields that shouldn't use the default:
+// answers that change as users sign up, and edit history only authors look at.
+var defaultCacheHints = map[string]CacheHint{
+	"Query.emailAvailable":   {MaxAge: 0},
+	"Query.postRevisionDiff": {MaxAge: time.Minute, Scope: CachePrivate},
+	"Post.revisions":         {MaxAge: time.Minute, Scope: CachePrivate},
+}
+
+func (c CacheControl) hint(typeName, fieldName string) CacheHint {
+	key := typeName + "." + fieldName
+	if hint, ok := c.FieldHints[key]; ok {
+		return hint
+	}
+	if hint, ok := defaultCacheHints[key]; ok {
+		if hint.MaxAge > c.MaxAge {
+			hint.MaxAge = c.MaxAge
+		}
+		return hint
+	}
+	return CacheHint{MaxAge: c.MaxAge}
+}
+
+// cachePolicy collects the hints of the fields resolved for one request.
+type cachePolicy struct {
+	control CacheControl
+
+	mu     sync.Mutex
+	set    bool
+	maxAge time.Duration
+	scope  CacheScope
+}
+
+type cachePolicyKey struct{}
+
+func withCachePolicy(ctx context.Context, control CacheControl) (context.Context, *cachePolicy) {
+	policy := &cachePolicy{control: control}
+	return context.WithValue(ctx, cachePolicyKey{}, policy), policy
+}
+
+func cachePolicyFrom(ctx context.Context) *cachePolicy {
+	policy, _ := ctx.Value(cachePolicyKey{}).(*cachePolicy)
+	return policy
+}
+
+// restrict lowers the policy to a hint: the shorter max age and the narrower scope win.
+func (p *cachePolicy) restrict(hint CacheHint) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.set || hint.MaxAge < p.maxAge {
+		p.maxAge = hint.MaxAge
+	}
+	if hint.Scope == CachePrivate {
+		p.scope = CachePrivate
+	}
+	p.set = true
+}
+
+// header is the Cache-Control value of a response. Responses without any
+// object field, such as introspection, get the default max age.
+func (p *cachePolicy) header() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	maxAge, scope := p.maxAge, p.scope
+	if !p.set {
+		maxAge = p.control.MaxAge
+	}
+	directive := "no-cache"
+	if seconds := int(maxAge / time.Second); seconds > 0 {
+		directive = fmt.Sprintf("max-age=%d", seconds)
+	}
+	if scope == CachePrivate {
+		return directive + ", private"
+	}
+	return directive + ", public"
+}
+
+// cacheControlExtension records the hints of resolved fields in the request's
+// cachePolicy. Requests without one, such as POST requests, are left alone.
+type cacheControlExtension struct{}
+
+var _ graphql.Extension = cacheControlExtension{}
+
+func (cacheControlExtension) Init(ctx context.Context, _ *graphql.Params) context.Context { return ctx }
+func (cacheControlExtension) Name() string                                                { return "cacheControl" }
+func (cacheControlExtension) HasResult() bool                                             { return false }
+func (cacheControlExtension) GetResult(context.Context) interface{}                       { return nil }
+
+func (cacheControlExtension) ParseDidStart(ctx context.Context) (context.Context, graphql.ParseFinishFunc) {
+	return ctx, func(error) {}
+}
+
+func (cacheControlExtension) ValidationDidStart(ctx context.Context) (context.Context, graphql.ValidationFinishFunc) {
+	return ctx, func([]gqlerrors.FormattedError) {}
+}
+
+func (cacheControlExtension) ExecutionDidStart(ctx context.Context) (context.Context, graphql.ExecutionFinishFunc) {
+	return ctx, func(*graphql.Result) {}
+}
+
+func (cacheControlExtension) ResolveFieldDidStart(ctx context.Context, info *graphql.ResolveInfo) (context.Context, graphql.ResolveFieldFinishFunc) {
+	policy := cachePolicyFrom(ctx)
+	if policy == nil {
+		return ctx, func(interface{}, error) {}
+	}
+	if root := len(info.Path.AsArray()) == 1; root || !graphql.IsLeafType(info.ReturnType) {
+		policy.restrict(policy.control.hint(info.ParentType.Name(), info.FieldName))
+	}
+	return ctx, func(value interface{}, _ error) {
+		if !allPublished(value) {
+			policy.restrict(CacheHint{MaxAge: policy.control.MaxAge, Scope: CachePrivate})
+		}
+	}
+}
+
+// allPublished reports whether the posts in a resolved value, if any, are
+// all published. Drafts and scheduled posts must not end up in shared caches.
+func allPublished(value interface{}) bool {
+	switch v := value.(type) {
+	case models.Post:
+		return v.Status == models.Published
+	case *models.Post:
+		return v == nil || v.Status == models.Published
+	case []models.Post:
+		for _, post := range v {
+			if post.Status != models.Published {
+				return false
+			}
+		}
+	case []*models.Post:
+		for _, post := range v {
+			if post != nil && post.Status != models.Published {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// etag is the entity tag of a response body.
+func etag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// etagMatches reports whether an If-None-Match header lists the tag. Weak
+// comparison is used, as RFC 9110 asks for GET requests.
+func etagMatches(ifNoneMatch, tag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == tag || candidate == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// writeCached writes the response of a GET query with its cache headers, or
+// 304 Not Modified when the client already has it.
+func (h *Handler) writeCached(w http.ResponseWriter, r *http.Request, result *graphql.Result, policy *cachePolicy) {
+	header := w.Header()
+	header.Add("Vary", "Authorization, X-User-ID") // CORS may vary by Origin already
+	if len(result.Errors) > 0 {
+		header.Set("Cache-Control", "no-store")
+		h.writeResult(w, http.StatusOK, result)
+		return
+	}
+
+	body := h.encode(result)
+	tag := etag(body)
+	header.Set("Cache-Control", policy.header())
+	header.Set("ETag", tag)
+	if etagMatches(r.Header.Get("If-None-Match"), tag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	header.Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+}
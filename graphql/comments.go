@@ -0,0 +1,373 @@
+package graphql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mas-diq/go-graphql/auth"
+	"mas-diq/go-graphql/dto"
+	"mas-diq/go-graphql/loaders"
+	"mas-diq/go-graphql/models"
+	"mas-diq/go-graphql/validation"
+
+	"github.com/graphql-go/graphql"
+	"gorm.io/gorm"
+)
+
+// commentFromSource returns the Comment a field is being resolved on.
+func commentFromSource(source interface{}) (*models.Comment, bool) {
+	switch comment := source.(type) {
+	case models.Comment:
+		return &comment, true
+	case *models.Comment:
+		return comment, true
+	}
+	return nil, false
+}
+
+// pageInfoType follows the Relay connection spec.
+var pageInfoType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "PageInfo",
+	Fields: graphql.Fields{
+		"hasNextPage": &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean)},
+		"endCursor":   &graphql.Field{Type: graphql.String},
+	},
+})
+
+// connectionArgs are the pagination arguments of a connection field.
+var connectionArgs = graphql.FieldConfigArgument{
+	"first": &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 20},
+	"after": &graphql.ArgumentConfig{Type: graphql.String},
+}
+
+// newCommentTypes defines the GraphQL 'Comment' object and its 'CommentConnection'.
+func newCommentTypes(userType, postType *graphql.Object, db *gorm.DB) (*graphql.Object, *graphql.Object) {
+	commentType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Comment",
+		Fields: graphql.Fields{
+			"id":     &graphql.Field{Type: graphql.Int, Resolve: resolveID},
+			"body":   &graphql.Field{Type: graphql.String},
+			"status": &graphql.Field{Type: graphql.String}, // Moderation status: "approved", "pending" or "rejected"
+			"createdAt": &graphql.Field{
+				Type: graphql.String,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					comment, ok := commentFromSource(p.Source)
+					if !ok {
+						return nil, fmt.Errorf("could not cast source to Comment for createdAt")
+					}
+					return comment.CreatedAt.Format(timeLayout), nil
+				},
+			},
+		},
+	})
+
+	edgeType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "CommentEdge",
+		Fields: graphql.Fields{
+			"cursor": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"node":   &graphql.Field{Type: commentType},
+		},
+	})
+
+	connectionType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "CommentConnection",
+		Fields: graphql.Fields{
+			"edges":      &graphql.Field{Type: graphql.NewList(edgeType)},
+			"pageInfo":   &graphql.Field{Type: graphql.NewNonNull(pageInfoType)},
+			"totalCount": &graphql.Field{Type: graphql.Int},
+		},
+	})
+
+	commentType.AddFieldConfig("author", &graphql.Field{
+		Type: userType,
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			comment, ok := commentFromSource(p.Source)
+			if !ok {
+				return nil, fmt.Errorf("could not cast source to Comment for comment.author resolver")
+			}
+			if l := loaders.For(p.Context); l != nil {
+				users, err := l.Users.Load(p.Context, []uint{comment.AuthorID})
+				if err != nil || len(users) == 0 {
+					return nil, err
+				}
+				return users[0], nil
+			}
+			var author models.User
+			if err := db.WithContext(p.Context).First(&author, comment.AuthorID).Error; err != nil {
+				return nil, err
+			}
+			return &author, nil
+		},
+	})
+
+	commentType.AddFieldConfig("post", &graphql.Field{
+		Type: postType,
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			comment, ok := commentFromSource(p.Source)
+			if !ok {
+				return nil, fmt.Errorf("could not cast source to Comment for comment.post resolver")
+			}
+			var post models.Post
+			err := db.WithContext(p.Context).Scopes(models.VisiblePosts(auth.UserFromContext(p.Context))).First(&post, comment.PostID).Error
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, newCodedError("NOT_FOUND", "Post not found")
+			}
+			if err != nil {
+				return nil, err
+			}
+			return &post, nil
+		},
+	})
+
+	commentType.AddFieldConfig("parent", &graphql.Field{
+		Type: commentType,
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			comment, ok := commentFromSource(p.Source)
+			if !ok {
+				return nil, fmt.Errorf("could not cast source to Comment for comment.parent resolver")
+			}
+			if comment.ParentID == nil {
+				return nil, nil
+			}
+			var parent models.Comment
+			if err := db.WithContext(p.Context).First(&parent, *comment.ParentID).Error; err != nil {
+				// The parent may have been deleted; its replies stay visible.
+				return nil, nil
+			}
+			return &parent, nil
+		},
+	})
+
+	commentType.AddFieldConfig("replies", &graphql.Field{
+		Type: connectionType,
+		Args: connectionArgs,
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			comment, ok := commentFromSource(p.Source)
+			if !ok {
+				return nil, fmt.Errorf("could not cast source to Comment for comment.replies resolver")
+			}
+			return resolveCommentPage(p, comment.PostID, &comment.ID)
+		},
+	})
+
+	commentType.AddFieldConfig("replyCount", &graphql.Field{
+		Type: graphql.Int,
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			comment, ok := commentFromSource(p.Source)
+			if !ok {
+				return nil, fmt.Errorf("could not cast source to Comment for comment.replyCount resolver")
+			}
+			l := loaders.For(p.Context)
+			if l == nil {
+				return nil, fmt.Errorf("loaders not found in context")
+			}
+			return l.ReplyCounts.Load(p.Context, comment.ID)
+		},
+	})
+
+	return commentType, connectionType
+}
+
+// resolveCommentPage loads one page of comments and shapes it as a connection.
+func resolveCommentPage(p graphql.ResolveParams, postID uint, parentID *uint) (interface{}, error) {
+	first, _ := p.Args["first"].(int)
+	if first < 1 {
+		return nil, invalidArgument(p.Context, "first", "gte", "1")
+	}
+	if first > 100 {
+		return nil, invalidArgument(p.Context, "first", "lte", "100")
+	}
+	afterArg, _ := p.Args["after"].(string)
+	after, err := dto.DecodeCursor(afterArg)
+	if err != nil {
+		return nil, invalidArgument(p.Context, "after", "cursor", "")
+	}
+
+	var page models.CommentPage
+	if err := models.GetCommentPage(&page, postID, parentID, auth.UserFromContext(p.Context), after, first); err != nil {
+		return nil, err
+	}
+	primeComments(p.Context, page.Comments)
+
+	edges := make([]map[string]interface{}, 0, len(page.Comments))
+	for _, comment := range page.Comments {
+		edges = append(edges, map[string]interface{}{
+			"cursor": dto.EncodeCursor(comment.ID),
+			"node":   comment,
+		})
+	}
+	pageInfo := map[string]interface{}{"hasNextPage": page.HasNextPage}
+	if n := len(page.Comments); n > 0 {
+		pageInfo["endCursor"] = dto.EncodeCursor(page.Comments[n-1].ID)
+	}
+	return map[string]interface{}{
+		"edges":      edges,
+		"pageInfo":   pageInfo,
+		"totalCount": page.TotalCount,
+	}, nil
+}
+
+// primeComments queues the authors and reply counts of a page of comments.
+func primeComments(ctx context.Context, comments []models.Comment) {
+	l := loaders.For(ctx)
+	if l == nil {
+		return
+	}
+	for _, comment := range comments {
+		l.Users.Prime(comment.AuthorID)
+		l.ReplyCounts.Prime(comment.ID)
+	}
+}
+
+// addPostCommentFields adds 'comments' and 'commentCount' to the Post type.
+func addPostCommentFields(postType, connectionType *graphql.Object) {
+	postType.AddFieldConfig("comments", &graphql.Field{
+		Type:        connectionType,
+		Description: "Top level comments, oldest first. Replies are nested under each comment.",
+		Args:        connectionArgs,
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			post, ok := postFromSource(p.Source)
+			if !ok {
+				return nil, fmt.Errorf("could not cast source to Post or *Post for post.comments resolver")
+			}
+			return resolveCommentPage(p, post.ID, nil)
+		},
+	})
+
+	postType.AddFieldConfig("commentCount", &graphql.Field{
+		Type:        graphql.Int,
+		Description: "Number of approved comments, replies included.",
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			post, ok := postFromSource(p.Source)
+			if !ok {
+				return nil, fmt.Errorf("could not cast source to Post or *Post for post.commentCount resolver")
+			}
+			l := loaders.For(p.Context)
+			if l == nil {
+				return nil, fmt.Errorf("loaders not found in context")
+			}
+			return l.CommentCounts.Load(p.Context, post.ID)
+		},
+	})
+}
+
+// commentMutations returns the comment related fields of the root Mutation type.
+func commentMutations(commentType *graphql.Object) graphql.Fields {
+	createCommentInput := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "CreateCommentInput",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"postId":   &graphql.InputObjectFieldConfig{Type: graphql.Int},
+			"parentId": &graphql.InputObjectFieldConfig{Type: graphql.Int},
+			"body":     &graphql.InputObjectFieldConfig{Type: graphql.String},
+		},
+	})
+	updateCommentInput := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "UpdateCommentInput",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"body": &graphql.InputObjectFieldConfig{Type: graphql.String},
+		},
+	})
+	idArg := &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)}
+
+	loadComment := func(p graphql.ResolveParams) (*models.Comment, error) {
+		var comment models.Comment
+		if err := models.GetOneComment(&comment, uint64(p.Args["id"].(int))); err != nil {
+			return nil, err
+		}
+		return &comment, nil
+	}
+
+	return graphql.Fields{
+		"createComment": &graphql.Field{
+			Type: commentType,
+			Args: graphql.FieldConfigArgument{
+				"input": &graphql.ArgumentConfig{Type: graphql.NewNonNull(createCommentInput)},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				actor := auth.UserFromContext(p.Context)
+				if actor == nil {
+					return nil, newCodedError("UNAUTHENTICATED", "Authentication required")
+				}
+				var input dto.CreateCommentRequest
+				if err := decodeInput(p.Context, p.Args, &input); err != nil {
+					return nil, err
+				}
+				if input.PostID == 0 {
+					return nil, invalidArgument(p.Context, "postId", "required", "")
+				}
+
+				post, err := loadPost(p.Context, int(input.PostID), nil)
+				if err != nil {
+					return nil, err
+				}
+				comment := models.Comment{
+					PostID:   post.ID,
+					AuthorID: actor.ID,
+					ParentID: input.ParentID,
+					Body:     input.Body,
+				}
+				if err := models.CreateCommentData(&comment); err != nil {
+					return nil, translateError(p.Context, err)
+				}
+				return &comment, nil
+			},
+		},
+		"updateComment": &graphql.Field{
+			Type: commentType,
+			Args: graphql.FieldConfigArgument{
+				"id":    idArg,
+				"input": &graphql.ArgumentConfig{Type: graphql.NewNonNull(updateCommentInput)},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				var input dto.UpdateCommentRequest
+				if err := decodeInput(p.Context, p.Args, &input); err != nil {
+					return nil, err
+				}
+				comment, err := loadComment(p)
+				if err != nil {
+					return nil, err
+				}
+				comment.Body = input.Body
+				if err := models.UpdateCommentData(comment, auth.UserFromContext(p.Context)); err != nil {
+					return nil, translateError(p.Context, err)
+				}
+				return comment, nil
+			},
+		},
+		"moderateComment": &graphql.Field{
+			Type: commentType,
+			Args: graphql.FieldConfigArgument{
+				"id":     idArg,
+				"status": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				input := dto.ModerateCommentRequest{Status: p.Args["status"].(string)}
+				if err := validation.Validate(&input); err != nil {
+					return nil, validationError(p.Context, err)
+				}
+				comment, err := loadComment(p)
+				if err != nil {
+					return nil, err
+				}
+				if err := models.ModerateComment(comment, auth.UserFromContext(p.Context), models.CommentStatus(input.Status)); err != nil {
+					return nil, translateError(p.Context, err)
+				}
+				return comment, nil
+			},
+		},
+		"deleteComment": &graphql.Field{
+			Type: graphql.Boolean,
+			Args: graphql.FieldConfigArgument{"id": idArg},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				comment, err := loadComment(p)
+				if err != nil {
+					return nil, err
+				}
+				if err := models.DeleteComment(comment, auth.UserFromContext(p.Context)); err != nil {
+					return nil, translateError(p.Context, err)
+				}
+				return true, nil
+			},
+		},
+	}
+}
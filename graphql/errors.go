@@ -0,0 +1,98 @@
+package graphql
+
+import (
+	"context"
+	"errors"
+	"mas-diq/go-graphql/models"
+	"mas-diq/go-graphql/schemas"
+	"mas-diq/go-graphql/uploads"
+	"mas-diq/go-graphql/validation"
+)
+
+// codedError is a resolver error that carries a machine readable code in the
+// "extensions" member of the GraphQL error, mirroring schemas.Response.ErrorCode on REST.
+type codedError struct {
+	code       string
+	message    string
+	extensions map[string]interface{}
+}
+
+func (e *codedError) Error() string {
+	return e.message
+}
+
+func (e *codedError) Extensions() map[string]interface{} {
+	ext := map[string]interface{}{"code": e.code}
+	for k, v := range e.extensions {
+		ext[k] = v
+	}
+	return ext
+}
+
+func newCodedError(code, message string) error {
+	return &codedError{code: code, message: message}
+}
+
+// validationError translates a validator error into the same field list the
+// REST controllers return, placed under extensions.fields.
+func validationError(ctx context.Context, err error) error {
+	fields := validation.Translate(err, validation.LocaleFromContext(ctx))
+	if fields == nil {
+		return err
+	}
+	return &codedError{
+		code:       "VALIDATION_FAILED",
+		message:    "Validation failed",
+		extensions: map[string]interface{}{"fields": fields},
+	}
+}
+
+// invalidArgument reports a single invalid argument in the validation error shape.
+func invalidArgument(ctx context.Context, field, rule, param string) error {
+	return &codedError{
+		code:       "VALIDATION_FAILED",
+		message:    "Validation failed",
+		extensions: map[string]interface{}{"fields": []schemas.FieldError{validation.NewFieldError(validation.LocaleFromContext(ctx), field, rule, param)}},
+	}
+}
+
+// translateError maps known domain errors from the models package to coded
+// GraphQL errors, the GraphQL counterpart of controllers.abortWithError.
+func translateError(ctx context.Context, err error) error {
+	locale := validation.LocaleFromContext(ctx)
+
+	switch {
+	case errors.Is(err, models.ErrEmailTaken):
+		return &codedError{
+			code:       "EMAIL_TAKEN",
+			message:    "Email is already taken",
+			extensions: map[string]interface{}{"fields": []schemas.FieldError{validation.NewFieldError(locale, "email", "unique", "")}},
+		}
+	case errors.Is(err, models.ErrSlugTaken):
+		return &codedError{
+			code:       "SLUG_TAKEN",
+			message:    "Slug is already taken",
+			extensions: map[string]interface{}{"fields": []schemas.FieldError{validation.NewFieldError(locale, "slug", "unique", "")}},
+		}
+	case errors.Is(err, models.ErrTagTaken):
+		return &codedError{
+			code:       "TAG_TAKEN",
+			message:    "Tag already exists",
+			extensions: map[string]interface{}{"fields": []schemas.FieldError{validation.NewFieldError(locale, "name", "unique", "")}},
+		}
+	case errors.Is(err, models.ErrInvalidTagName):
+		return invalidArgument(ctx, "name", "tag", "")
+	case errors.Is(err, models.ErrInvalidParent):
+		return invalidArgument(ctx, "parentId", "parent", "")
+	case errors.Is(err, models.ErrInvalidTransition):
+		return newCodedError("INVALID_TRANSITION", err.Error())
+	case errors.Is(err, models.ErrForbidden):
+		return newCodedError("FORBIDDEN", err.Error())
+	case errors.Is(err, uploads.ErrUnsupportedType):
+		return newCodedError("UNSUPPORTED_MEDIA_TYPE", err.Error())
+	case errors.Is(err, uploads.ErrTooLarge):
+		return newCodedError("FILE_TOO_LARGE", err.Error())
+	default:
+		return err
+	}
+}
@@ -0,0 +1,406 @@
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mas-diq/go-graphql/auth"
+	"mas-diq/go-graphql/persisted"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/source"
+)
+
+// Request is a GraphQL operation as sent over HTTP.
+type Request struct {
+	Query         string                 `json:"query"`
+	Variables     map[string]interface{} `json:"variables"`
+	OperationName string                 `json:"operationName"`
+	Extensions    map[string]interface{} `json:"extensions"`
+}
+
+// Handler serves GraphQL over HTTP. It accepts JSON bodies, "application/graphql"
+// bodies and multipart requests following the GraphQL multipart request spec
+// (https://github.com/jaydenseric/graphql-multipart-request-spec) for uploads.
+// Queries can also be sent with GET, where responses carry cache headers, and
+// all operations, subscriptions included, over WebSocket (see websocket.go).
+type Handler struct {
+	Schema graphql.Schema
+	// Pretty indents the JSON responses.
+	Pretty bool
+	// MaxRequestBytes bounds the body of a request, files included.
+	MaxRequestBytes int64
+	// Limits reject operations that are too deep or too complex.
+	Limits Limits
+	// Persisted enables persisted queries, see PersistedQueries.
+	Persisted PersistedQueries
+	// Cache sets the Cache-Control header of GET responses.
+	Cache CacheControl
+	// RateLimit charges operations to the budget of their client.
+	RateLimit RateLimit
+
+	// CheckOrigin accepts or refuses WebSocket connections by their Origin
+	// header; nil accepts only pages of the same host.
+	CheckOrigin func(r *http.Request) bool
+	// Authenticate identifies the user of a WebSocket connection from the
+	// payload of its connection_init message. Connections it rejects are closed.
+	Authenticate func(ctx context.Context, payload map[string]interface{}) (context.Context, error)
+	// OperationContext prepares the context of each operation sent over a
+	// WebSocket and of every subscription event, like the HTTP route does for
+	// requests; they all share the connection's request otherwise.
+	OperationContext func(ctx context.Context) context.Context
+	// QueryContext prepares the context of query operations, which don't
+	// write, e.g. to let them read from replicas.
+	QueryContext func(ctx context.Context) context.Context
+
+	connections wsConnections
+}
+
+func NewHandler(schema graphql.Schema, maxRequestBytes int64) *Handler {
+	return &Handler{Schema: schema, Pretty: true, MaxRequestBytes: maxRequestBytes}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if websocket.IsWebSocketUpgrade(r) {
+		h.serveWebSocket(w, r)
+		return
+	}
+	if h.MaxRequestBytes > 0 {
+		r.Body = http.MaxBytesReader(w, r.Body, h.MaxRequestBytes)
+	}
+
+	req, err := h.parseRequest(r)
+	if err != nil {
+		status := http.StatusBadRequest
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			status = http.StatusRequestEntityTooLarge
+		}
+		h.writeResult(w, status, &graphql.Result{Errors: []gqlerrors.FormattedError{gqlerrors.NewFormattedError(err.Error())}})
+		return
+	}
+	if r.MultipartForm != nil {
+		defer r.MultipartForm.RemoveAll()
+	}
+
+	ctx, quota := withQuota(r.Context())
+	save, err := h.resolvePersisted(ctx, req)
+	if err != nil {
+		h.writeResult(w, http.StatusOK, &graphql.Result{Errors: []gqlerrors.FormattedError{formatError(err)}})
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		result, status := h.execute(ctx, req, save, false)
+		h.writeResult(w, quota.writeHeaders(w.Header(), status), result)
+		return
+	}
+
+	ctx, policy := withCachePolicy(ctx, h.Cache)
+	if auth.UserFromContext(ctx) != nil {
+		// What a signed in user sees may depend on who they are.
+		policy.restrict(CacheHint{MaxAge: h.Cache.MaxAge, Scope: CachePrivate})
+	}
+	result, status := h.execute(ctx, req, save, true)
+	if status = quota.writeHeaders(w.Header(), status); status != http.StatusOK {
+		if status != http.StatusTooManyRequests {
+			w.Header().Set("Allow", "POST")
+		}
+		h.writeResult(w, status, result)
+		return
+	}
+	h.writeCached(w, r, result, policy)
+}
+
+var (
+	errQueryOverGET         = newCodedError("METHOD_NOT_ALLOWED", "Only queries can be sent with GET; use POST for mutations")
+	errSubscriptionOverHTTP = newCodedError("BAD_REQUEST", "Subscriptions are served over WebSocket (graphql-transport-ws)")
+)
+
+// execute runs an operation sent over HTTP. With queryOnly, other operations
+// are rejected with 405 Method Not Allowed.
+func (h *Handler) execute(ctx context.Context, req *Request, persist, queryOnly bool) (*graphql.Result, int) {
+	doc, errs := h.prepare(ctx, req, persist)
+	if errs != nil {
+		return &graphql.Result{Errors: errs}, http.StatusOK
+	}
+	annotateOperation(ctx, doc, req)
+	switch operation := operationType(doc, req.OperationName); {
+	case operation == ast.OperationTypeSubscription:
+		return &graphql.Result{Errors: []gqlerrors.FormattedError{formatError(errSubscriptionOverHTTP)}}, http.StatusBadRequest
+	case queryOnly && operation != "" && operation != ast.OperationTypeQuery:
+		return &graphql.Result{Errors: []gqlerrors.FormattedError{formatError(errQueryOverGET)}}, http.StatusMethodNotAllowed
+	}
+	return h.executeDocument(ctx, req, doc), http.StatusOK
+}
+
+// prepare parses and validates an operation. It does what graphql.Do does
+// before executing, with the API key scopes and cost limits checked and the
+// operation charged to the rate limit after validation. Queries to persist are stored once they
+// passed these checks.
+func (h *Handler) prepare(ctx context.Context, req *Request, persist bool) (*ast.Document, []gqlerrors.FormattedError) {
+	doc, errs := h.parse(ctx, req)
+	if errs != nil {
+		return nil, errs
+	}
+	if errs := h.validate(ctx, req, doc); errs != nil {
+		return nil, errs
+	}
+
+	if persist {
+		query := persisted.Query{Hash: persisted.Hash(req.Query), Body: req.Query, OperationName: req.OperationName}
+		if err := h.Persisted.Store.Save(ctx, query); err != nil {
+			logger.ErrorContext(ctx, "Failed to store persisted query", "hash", query.Hash, "error", err)
+		}
+	}
+	return doc, nil
+}
+
+// parse parses the query of an operation.
+func (h *Handler) parse(ctx context.Context, req *Request) (*ast.Document, []gqlerrors.FormattedError) {
+	_, span := tracer.Start(ctx, "graphql.parse")
+	doc, err := parser.Parse(parser.ParseParams{
+		Source: source.NewSource(&source.Source{Body: []byte(req.Query), Name: "GraphQL request"}),
+	})
+	var errs []gqlerrors.FormattedError
+	if err != nil {
+		errs = gqlerrors.FormatErrors(err)
+	}
+	endSpan(span, errs)
+	return doc, errs
+}
+
+// validate checks an operation against the schema, then the scopes of the
+// caller's API key, the limits and the rate limit.
+func (h *Handler) validate(ctx context.Context, req *Request, doc *ast.Document) (errs []gqlerrors.FormattedError) {
+	_, span := tracer.Start(ctx, "graphql.validate")
+	defer func() { endSpan(span, errs) }()
+
+	if validation := graphql.ValidateDocument(&h.Schema, doc, nil); !validation.IsValid {
+		return validation.Errors
+	}
+	if err := h.checkScopes(ctx, doc, req.OperationName); err != nil {
+		return []gqlerrors.FormattedError{formatError(err)}
+	}
+	cost := h.Limits.Analyze(h.Schema, doc, req.OperationName, req.Variables)
+	if err := h.Limits.Check(cost); err != nil {
+		return []gqlerrors.FormattedError{formatError(err)}
+	}
+	if err := h.charge(ctx, cost); err != nil {
+		return []gqlerrors.FormattedError{formatError(err)}
+	}
+	return nil
+}
+
+func (h *Handler) executeDocument(ctx context.Context, req *Request, doc *ast.Document) *graphql.Result {
+	start := time.Now()
+	if h.QueryContext != nil && operationType(doc, req.OperationName) == ast.OperationTypeQuery {
+		ctx = h.QueryContext(ctx)
+	}
+	ctx, span := startExecuteSpan(ctx, doc, req)
+	result := graphql.Execute(graphql.ExecuteParams{
+		Schema:        h.Schema,
+		AST:           doc,
+		OperationName: req.OperationName,
+		Args:          req.Variables,
+		Context:       ctx,
+	})
+	endSpan(span, result.Errors)
+	observeOperation(doc, req, start, result)
+	withRequestID(ctx, result.Errors)
+	return result
+}
+
+// operationType returns the type of the operation a request runs. It is
+// empty when there is no such operation.
+func operationType(doc *ast.Document, operationName string) string {
+	if operation := findOperation(doc, operationName); operation != nil {
+		return operation.Operation
+	}
+	return ""
+}
+
+// findOperation returns the operation a request runs: the named one, or the
+// only one of the document.
+func findOperation(doc *ast.Document, operationName string) *ast.OperationDefinition {
+	var found *ast.OperationDefinition
+	for _, definition := range doc.Definitions {
+		operation, ok := definition.(*ast.OperationDefinition)
+		if !ok {
+			continue
+		}
+		if operationName == "" && found != nil {
+			return nil
+		}
+		if operationName == "" || (operation.Name != nil && operation.Name.Value == operationName) {
+			found = operation
+		}
+	}
+	return found
+}
+
+// formatError turns an error raised outside of resolvers into a response
+// error, keeping the extensions of coded errors.
+func formatError(err error) gqlerrors.FormattedError {
+	formatted := gqlerrors.FormatError(err)
+	if extended, ok := err.(gqlerrors.ExtendedError); ok {
+		formatted.Extensions = extended.Extensions()
+	}
+	return formatted
+}
+
+func (h *Handler) parseRequest(r *http.Request) (*Request, error) {
+	if r.Method == http.MethodGet {
+		return parseQueryString(r)
+	}
+	if r.Method != http.MethodPost {
+		return nil, fmt.Errorf("method %s is not supported", r.Method)
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case "application/graphql":
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			return nil, err
+		}
+		return &Request{Query: string(body)}, nil
+	case "multipart/form-data":
+		return parseMultipart(r)
+	default:
+		var req Request
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				return nil, err
+			}
+			return nil, fmt.Errorf("invalid JSON body: %w", err)
+		}
+		return &req, nil
+	}
+}
+
+// parseQueryString reads a GET request: ?query=...&variables=...&operationName=...,
+// with variables and extensions as JSON.
+func parseQueryString(r *http.Request) (*Request, error) {
+	values := r.URL.Query()
+	req := Request{Query: values.Get("query"), OperationName: values.Get("operationName")}
+	if variables := values.Get("variables"); variables != "" {
+		if err := json.Unmarshal([]byte(variables), &req.Variables); err != nil {
+			return nil, errors.New(`invalid "variables" parameter`)
+		}
+	}
+	if extensions := values.Get("extensions"); extensions != "" {
+		if err := json.Unmarshal([]byte(extensions), &req.Extensions); err != nil {
+			return nil, errors.New(`invalid "extensions" parameter`)
+		}
+	}
+	return &req, nil
+}
+
+// multipartMemory is how much of a multipart request is kept in memory; larger files go to disk.
+const multipartMemory = 8 << 20
+
+// parseMultipart reads a request of the GraphQL multipart request spec: the
+// "operations" field holds the JSON request with null in place of files, and
+// "map" tells which variables each file part goes to, e.g.
+// {"0": ["variables.file"], "1": ["variables.files.0"]}.
+func parseMultipart(r *http.Request) (*Request, error) {
+	if err := r.ParseMultipartForm(multipartMemory); err != nil {
+		return nil, err
+	}
+
+	var req Request
+	if err := json.Unmarshal([]byte(r.FormValue("operations")), &req); err != nil {
+		return nil, errors.New(`invalid "operations" field: batched operations are not supported`)
+	}
+	var fileMap map[string][]string
+	if err := json.Unmarshal([]byte(r.FormValue("map")), &fileMap); err != nil {
+		return nil, errors.New(`invalid "map" field`)
+	}
+
+	// Operations without variables still get files, e.g. {"0": ["variables.file"]}.
+	if req.Variables == nil {
+		req.Variables = map[string]interface{}{}
+	}
+	operation := map[string]interface{}{"variables": req.Variables}
+	for name, paths := range fileMap {
+		files := r.MultipartForm.File[name]
+		if len(files) != 1 {
+			return nil, fmt.Errorf("file %q is missing", name)
+		}
+		for _, path := range paths {
+			if err := setPath(operation, path, files[0]); err != nil {
+				return nil, err
+			}
+		}
+	}
+	req.Variables, _ = operation["variables"].(map[string]interface{})
+	return &req, nil
+}
+
+// setPath replaces the value at a dotted object path, such as
+// "variables.input.files.0", with value.
+func setPath(root map[string]interface{}, path string, value interface{}) error {
+	parts := strings.Split(path, ".")
+	if len(parts) < 2 || parts[0] != "variables" {
+		return fmt.Errorf("invalid file path %q", path)
+	}
+
+	var current interface{} = root
+	for i, part := range parts {
+		last := i == len(parts)-1
+		switch node := current.(type) {
+		case map[string]interface{}:
+			if _, ok := node[part]; !ok && !last {
+				return fmt.Errorf("invalid file path %q", path)
+			}
+			if last {
+				node[part] = value
+			} else {
+				current = node[part]
+			}
+		case []interface{}:
+			index, err := strconv.Atoi(part)
+			if err != nil || index < 0 || index >= len(node) {
+				return fmt.Errorf("invalid file path %q", path)
+			}
+			if last {
+				node[index] = value
+			} else {
+				current = node[index]
+			}
+		default:
+			return fmt.Errorf("invalid file path %q", path)
+		}
+	}
+	return nil
+}
+
+func (h *Handler) writeResult(w http.ResponseWriter, status int, result *graphql.Result) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	w.Write(h.encode(result))
+}
+
+func (h *Handler) encode(result *graphql.Result) []byte {
+	var body bytes.Buffer
+	encoder := json.NewEncoder(&body)
+	if h.Pretty {
+		encoder.SetIndent("", "\t")
+	}
+	encoder.Encode(result)
+	return body.Bytes()
+}
@@ -0,0 +1,132 @@
+package graphql
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/graphql-go/graphql"
+)
+
+func multipartRequest(t *testing.T, operations, fileMap string) *http.Request {
+	t.Helper()
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	writer.WriteField("operations", operations)
+	writer.WriteField("map", fileMap)
+	part, err := writer.CreateFormFile("0", "a.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write([]byte("hello"))
+	writer.Close()
+
+	r := httptest.NewRequest(http.MethodPost, "/graphql", &body)
+	r.Header.Set("Content-Type", writer.FormDataContentType())
+	return r
+}
+
+func TestParseMultipart(t *testing.T) {
+	tests := []struct {
+		name       string
+		operations string
+		fileMap    string
+		path       []string
+		wantErr    bool
+	}{
+		{
+			name:       "no variables",
+			operations: `{"query": "mutation($file: Upload!) { upload(file: $file) }"}`,
+			fileMap:    `{"0": ["variables.file"]}`,
+			path:       []string{"file"},
+		},
+		{
+			name:       "null variables",
+			operations: `{"query": "mutation($file: Upload!) { upload(file: $file) }", "variables": null}`,
+			fileMap:    `{"0": ["variables.file"]}`,
+			path:       []string{"file"},
+		},
+		{
+			name:       "nested list",
+			operations: `{"query": "", "variables": {"input": {"files": [null, null]}}}`,
+			fileMap:    `{"0": ["variables.input.files.1"]}`,
+			path:       []string{"input", "files", "1"},
+		},
+		{
+			name:       "missing object",
+			operations: `{"query": ""}`,
+			fileMap:    `{"0": ["variables.input.file"]}`,
+			wantErr:    true,
+		},
+		{
+			name:       "null object",
+			operations: `{"query": "", "variables": {"input": null}}`,
+			fileMap:    `{"0": ["variables.input.file"]}`,
+			wantErr:    true,
+		},
+		{
+			name:       "index out of range",
+			operations: `{"query": "", "variables": {"files": [null]}}`,
+			fileMap:    `{"0": ["variables.files.1"]}`,
+			wantErr:    true,
+		},
+		{
+			name:       "outside variables",
+			operations: `{"query": ""}`,
+			fileMap:    `{"0": ["query"]}`,
+			wantErr:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := parseMultipart(multipartRequest(t, tt.operations, tt.fileMap))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var value interface{} = req.Variables
+			for _, part := range tt.path {
+				switch node := value.(type) {
+				case map[string]interface{}:
+					value = node[part]
+				case []interface{}:
+					value = node[part[0]-'0']
+				}
+			}
+			if _, ok := value.(*multipart.FileHeader); !ok {
+				t.Fatalf("variables = %v, want a file at %v", req.Variables, tt.path)
+			}
+		})
+	}
+}
+
+func TestServeHTTPRejectsUnresolvableFilePath(t *testing.T) {
+	schema, err := graphql.NewSchema(graphql.SchemaConfig{
+		Query: graphql.NewObject(graphql.ObjectConfig{Name: "Query", Fields: graphql.Fields{
+			"ok": &graphql.Field{Type: graphql.Boolean},
+		}}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := NewHandler(schema, 0)
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, multipartRequest(t, `{"query": "{ ok }"}`, `{"0": ["variables.input.file"]}`))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+	var result struct{ Errors []struct{ Message string } }
+	if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil || len(result.Errors) != 1 {
+		t.Fatalf("body = %s", w.Body)
+	}
+}
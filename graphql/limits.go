@@ -0,0 +1,261 @@
+package graphql
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+// Limits bound how expensive a single operation may be. They are checked on
+// the parsed query, before any resolver runs. Zero disables a check.
+type Limits struct {
+	// MaxDepth is the deepest allowed nesting of fields.
+	MaxDepth int
+	// MaxComplexity is the budget of an operation, see Cost.
+	MaxComplexity int
+	// DefaultListSize is the assumed length of lists whose size isn't bounded
+	// by a 'limit' or 'first' argument.
+	DefaultListSize int
+	// FieldCosts overrides the cost of fields, keyed by "Type.field".
+	FieldCosts map[string]int
+}
+
+// defaultFieldCosts are fields that do more work than a plain lookup.
+var defaultFieldCosts = map[string]int{
+	"Query.search":           10, // full-text search
+	"Query.postRevisionDiff": 10, // diffs two revisions
+	"Query.emailAvailable":   2,
+	"Post.revisions":         2,
+	"Post.comments":          2,
+	"Comment.replies":        2,
+}
+
+// Cost is the size of an operation.
+//
+// Complexity counts one point per field returning an object (scalars are free,
+// costlier fields are listed in FieldCosts) and multiplies the cost of a field
+// and everything below it by the number of items it may return: the value of
+// its 'limit' or 'first' argument, or DefaultListSize for other lists. E.g.
+// "posts(limit: 10) { author { name } }" costs 10 * (1 + 1) = 20.
+type Cost struct {
+	Depth      int
+	Complexity int
+}
+
+// costAnalyzer walks the selected operation of a validated document.
+type costAnalyzer struct {
+	schema    graphql.Schema
+	fragments map[string]*ast.FragmentDefinition
+	variables map[string]interface{}
+	limits    Limits
+}
+
+// Analyze computes the cost of the operation a request executes. The document
+// must have passed validation, which guarantees fields exist and fragments don't loop.
+func (l Limits) Analyze(schema graphql.Schema, doc *ast.Document, operationName string, variables map[string]interface{}) Cost {
+	a := costAnalyzer{
+		schema:    schema,
+		fragments: map[string]*ast.FragmentDefinition{},
+		variables: variables,
+		limits:    l,
+	}
+
+	var operation *ast.OperationDefinition
+	for _, definition := range doc.Definitions {
+		switch definition := definition.(type) {
+		case *ast.FragmentDefinition:
+			a.fragments[definition.Name.Value] = definition
+		case *ast.OperationDefinition:
+			if operation == nil && (operationName == "" || definition.Name != nil && definition.Name.Value == operationName) {
+				operation = definition
+			}
+		}
+	}
+	if operation == nil {
+		return Cost{}
+	}
+
+	var root *graphql.Object
+	switch operation.Operation {
+	case ast.OperationTypeMutation:
+		root = schema.MutationType()
+	case ast.OperationTypeSubscription:
+		root = schema.SubscriptionType()
+	default:
+		root = schema.QueryType()
+	}
+	if root == nil {
+		return Cost{}
+	}
+	complexity, depth := a.selectionSet(root, operation.SelectionSet)
+	return Cost{Depth: depth, Complexity: complexity}
+}
+
+// Check returns a coded error when a cost exceeds the limits.
+func (l Limits) Check(cost Cost) error {
+	if l.MaxDepth > 0 && cost.Depth > l.MaxDepth {
+		return &codedError{
+			code:       "QUERY_TOO_DEEP",
+			message:    fmt.Sprintf("Query depth %d exceeds the maximum of %d", cost.Depth, l.MaxDepth),
+			extensions: map[string]interface{}{"depth": cost.Depth, "maxDepth": l.MaxDepth},
+		}
+	}
+	if l.MaxComplexity > 0 && cost.Complexity > l.MaxComplexity {
+		return &codedError{
+			code:       "QUERY_TOO_COMPLEX",
+			message:    fmt.Sprintf("Query complexity %d exceeds the maximum of %d", cost.Complexity, l.MaxComplexity),
+			extensions: map[string]interface{}{"complexity": cost.Complexity, "maxComplexity": l.MaxComplexity},
+		}
+	}
+	return nil
+}
+
+// selectionSet returns the complexity and depth of the selections on parent.
+func (a *costAnalyzer) selectionSet(parent graphql.Type, set *ast.SelectionSet) (complexity, depth int) {
+	if set == nil {
+		return 0, 0
+	}
+	for _, selection := range set.Selections {
+		var c, d int
+		switch selection := selection.(type) {
+		case *ast.Field:
+			c, d = a.field(parent, selection)
+		case *ast.InlineFragment:
+			c, d = a.selectionSet(a.typeCondition(parent, selection.TypeCondition), selection.SelectionSet)
+		case *ast.FragmentSpread:
+			if fragment, ok := a.fragments[selection.Name.Value]; ok {
+				c, d = a.selectionSet(a.typeCondition(parent, fragment.TypeCondition), fragment.SelectionSet)
+			}
+		}
+		complexity = capAdd(complexity, c)
+		depth = max(depth, d)
+	}
+	return complexity, depth
+}
+
+func (a *costAnalyzer) typeCondition(parent graphql.Type, condition *ast.Named) graphql.Type {
+	if condition == nil {
+		return parent
+	}
+	return a.schema.Type(condition.Name.Value)
+}
+
+func (a *costAnalyzer) field(parent graphql.Type, field *ast.Field) (complexity, depth int) {
+	name := field.Name.Value
+	// Introspection is answered from the schema in memory.
+	if strings.HasPrefix(name, "__") {
+		return 0, 0
+	}
+
+	var fields graphql.FieldDefinitionMap
+	switch parent := parent.(type) {
+	case *graphql.Object:
+		fields = parent.Fields()
+	case *graphql.Interface:
+		fields = parent.Fields()
+	}
+	definition, ok := fields[name]
+	if !ok {
+		return 0, 0
+	}
+
+	fieldType, isList := unwrapType(definition.Type)
+	childComplexity, childDepth := a.selectionSet(fieldType, field.SelectionSet)
+
+	cost := 0
+	if field.SelectionSet != nil {
+		cost = 1
+	}
+	if custom, ok := a.fieldCost(parent.Name() + "." + name); ok {
+		cost = custom
+	}
+
+	multiplier := 1
+	if size, ok := a.listSize(definition, field); ok {
+		multiplier = size
+	} else if isList {
+		multiplier = a.limits.DefaultListSize
+	}
+	return capMul(multiplier, capAdd(cost, childComplexity)), childDepth + 1
+}
+
+// maxCost caps complexities, so absurd limits can't overflow into small numbers.
+const maxCost = 1 << 40
+
+func capAdd(a, b int) int {
+	return min(a+b, maxCost)
+}
+
+func capMul(a, b int) int {
+	if a != 0 && b > maxCost/a {
+		return maxCost
+	}
+	return a * b
+}
+
+func (a *costAnalyzer) fieldCost(key string) (int, bool) {
+	if cost, ok := a.limits.FieldCosts[key]; ok {
+		return cost, true
+	}
+	cost, ok := defaultFieldCosts[key]
+	return cost, ok
+}
+
+// listSize reads the 'limit' or 'first' argument of a field, falling back to
+// the argument's default value.
+func (a *costAnalyzer) listSize(definition *graphql.FieldDefinition, field *ast.Field) (int, bool) {
+	for _, name := range []string{"limit", "first"} {
+		for _, argument := range field.Arguments {
+			if argument.Name.Value != name {
+				continue
+			}
+			if size, ok := a.intValue(argument.Value); ok && size > 0 {
+				return size, true
+			}
+		}
+		for _, argument := range definition.Args {
+			if argument.Name() != name {
+				continue
+			}
+			if size, ok := argument.DefaultValue.(int); ok && size > 0 {
+				return size, true
+			}
+		}
+	}
+	return 0, false
+}
+
+func (a *costAnalyzer) intValue(value ast.Value) (int, bool) {
+	switch value := value.(type) {
+	case *ast.IntValue:
+		var n int
+		_, err := fmt.Sscan(value.Value, &n)
+		return n, err == nil
+	case *ast.Variable:
+		switch v := a.variables[value.Name.Value].(type) {
+		case int:
+			return v, true
+		case float64: // numbers decoded from JSON
+			return int(v), true
+		}
+	}
+	return 0, false
+}
+
+// unwrapType strips non-null and list wrappers, reporting whether there was a list.
+func unwrapType(t graphql.Type) (graphql.Type, bool) {
+	isList := false
+	for {
+		switch wrapped := t.(type) {
+		case *graphql.NonNull:
+			t = wrapped.OfType
+		case *graphql.List:
+			isList = true
+			t = wrapped.OfType
+		default:
+			return t, isList
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package graphql
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/graphql-go/graphql/language/parser"
+)
+
+func TestAnalyze(t *testing.T) {
+	schema, err := NewSchema(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	limits := Limits{DefaultListSize: 20, FieldCosts: defaultFieldCosts}
+
+	tests := []struct {
+		name      string
+		query     string
+		variables map[string]interface{}
+		want      Cost
+	}{
+		{
+			name:  "scalars are free",
+			query: `{ emailAvailable(email: "a@example.com") }`,
+			want:  Cost{Depth: 1, Complexity: 2},
+		},
+		{
+			name:  "limit multiplies the cost below",
+			query: `{ posts(limit: 10) { title author { name } tags { name } } }`,
+			want:  Cost{Depth: 3, Complexity: 220},
+		},
+		{
+			name:  "default limit",
+			query: `{ posts { title } }`,
+			want:  Cost{Depth: 2, Complexity: defaultListLimit},
+		},
+		{
+			name:      "limit from a variable",
+			query:     `query($n: Int) { posts(limit: $n) { title } }`,
+			variables: map[string]interface{}{"n": float64(5)},
+			want:      Cost{Depth: 2, Complexity: 5},
+		},
+		{
+			name:  "custom field cost",
+			query: `{ postRevisionDiff(postId: 1, from: 1, to: 2) }`,
+			want:  Cost{Depth: 1, Complexity: 10},
+		},
+		{
+			name:  "fragments",
+			query: `{ user(id: 1) { ...author } } fragment author on User { name posts(limit: 2) { ... on Post { title } } }`,
+			want:  Cost{Depth: 3, Complexity: 3},
+		},
+		{
+			name:  "introspection is free",
+			query: `{ __schema { types { name fields { name } } } }`,
+			want:  Cost{},
+		},
+		{
+			name:  "huge limits don't overflow",
+			query: `{ posts(limit: 2000000000) { tags { posts(limit: 2000000000) { tags { posts(limit: 2000000000) { title } } } } } }`,
+			want:  Cost{Depth: 6, Complexity: maxCost},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := parser.Parse(parser.ParseParams{Source: tt.query})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := limits.Analyze(schema, doc, "", tt.variables); got != tt.want {
+				t.Fatalf("cost = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheck(t *testing.T) {
+	limits := Limits{MaxDepth: 5, MaxComplexity: 100}
+	tests := []struct {
+		cost Cost
+		code string
+	}{
+		{cost: Cost{Depth: 5, Complexity: 100}},
+		{cost: Cost{Depth: 6, Complexity: 1}, code: "QUERY_TOO_DEEP"},
+		{cost: Cost{Depth: 1, Complexity: 101}, code: "QUERY_TOO_COMPLEX"},
+	}
+	for _, tt := range tests {
+		err := limits.Check(tt.cost)
+		var coded *codedError
+		switch {
+		case tt.code == "" && err != nil:
+			t.Errorf("Check(%+v) = %v", tt.cost, err)
+		case tt.code != "" && (!errors.As(err, &coded) || coded.code != tt.code):
+			t.Errorf("Check(%+v) = %v, want %s", tt.cost, err, tt.code)
+		}
+	}
+
+	if err := (Limits{}).Check(Cost{Depth: 1000, Complexity: maxCost}); err != nil {
+		t.Errorf("zero limits checked: %v", err)
+	}
+}
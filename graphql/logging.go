@@ -0,0 +1,82 @@
+package graphql
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"mas-diq/go-graphql/logging"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+var logger = logging.For("graphql")
+
+// annotateOperation adds the name and type of the operation to the access
+// log line of the request.
+func annotateOperation(ctx context.Context, doc *ast.Document, req *Request) {
+	name, operationType := req.OperationName, ""
+	if operation := findOperation(doc, req.OperationName); operation != nil {
+		operationType = operation.Operation
+		if operation.Name != nil {
+			name = operation.Name.Value
+		}
+	}
+	logging.Annotate(ctx, slog.String("graphql_operation", name), slog.String("graphql_type", operationType))
+}
+
+// withRequestID adds the request ID to the extensions of errors, so the logs
+// of a failed operation can be found from the response.
+func withRequestID(ctx context.Context, errs []gqlerrors.FormattedError) {
+	id := logging.RequestID(ctx)
+	if id == "" {
+		return
+	}
+	for i := range errs {
+		if errs[i].Extensions == nil {
+			errs[i].Extensions = map[string]interface{}{}
+		}
+		errs[i].Extensions["requestId"] = id
+	}
+}
+
+// errorLogExtension logs the errors resolvers return. Coded errors are
+// expected (validation, permissions…) and only logged at debug level.
+type errorLogExtension struct{}
+
+var _ graphql.Extension = errorLogExtension{}
+
+func (errorLogExtension) Init(ctx context.Context, _ *graphql.Params) context.Context { return ctx }
+func (errorLogExtension) Name() string                                                { return "errorLog" }
+func (errorLogExtension) HasResult() bool                                             { return false }
+func (errorLogExtension) GetResult(context.Context) interface{}                       { return nil }
+
+func (errorLogExtension) ParseDidStart(ctx context.Context) (context.Context, graphql.ParseFinishFunc) {
+	return ctx, func(error) {}
+}
+
+func (errorLogExtension) ValidationDidStart(ctx context.Context) (context.Context, graphql.ValidationFinishFunc) {
+	return ctx, func([]gqlerrors.FormattedError) {}
+}
+
+func (errorLogExtension) ExecutionDidStart(ctx context.Context) (context.Context, graphql.ExecutionFinishFunc) {
+	return ctx, func(*graphql.Result) {}
+}
+
+func (errorLogExtension) ResolveFieldDidStart(ctx context.Context, info *graphql.ResolveInfo) (context.Context, graphql.ResolveFieldFinishFunc) {
+	return ctx, func(_ interface{}, err error) {
+		if err == nil {
+			return
+		}
+		level := slog.LevelError
+		var coded *codedError
+		if errors.As(err, &coded) {
+			level = slog.LevelDebug
+		}
+		logger.Log(ctx, level, "Resolver failed",
+			"field", info.ParentType.Name()+"."+info.FieldName,
+			"path", info.Path.AsArray(),
+			"error", err)
+	}
+}
@@ -0,0 +1,78 @@
+package graphql
+
+import (
+	"context"
+	"mas-diq/go-graphql/metrics"
+	"strings"
+	"time"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+// observeOperation records an executed operation in the GraphQL metrics.
+// Anonymous operations are counted together.
+func observeOperation(doc *ast.Document, req *Request, start time.Time, result *graphql.Result) {
+	name, operationType := "anonymous", "unknown"
+	if operation := findOperation(doc, req.OperationName); operation != nil {
+		operationType = operation.Operation
+		if operation.Name != nil {
+			name = operation.Name.Value
+		}
+	}
+	outcome := "ok"
+	if len(result.Errors) > 0 {
+		outcome = "error"
+	}
+	metrics.GraphQLOperations.WithLabelValues(name, operationType, outcome).Inc()
+	metrics.GraphQLDuration.WithLabelValues(name, operationType).Observe(time.Since(start).Seconds())
+}
+
+// metricsExtension times the fields that have a resolver of their own;
+// fields read straight from their parent are too cheap to be worth a series.
+type metricsExtension struct{}
+
+var _ graphql.Extension = metricsExtension{}
+
+func (metricsExtension) Init(ctx context.Context, _ *graphql.Params) context.Context { return ctx }
+func (metricsExtension) Name() string                                                { return "metrics" }
+func (metricsExtension) HasResult() bool                                             { return false }
+func (metricsExtension) GetResult(context.Context) interface{}                       { return nil }
+
+func (metricsExtension) ParseDidStart(ctx context.Context) (context.Context, graphql.ParseFinishFunc) {
+	return ctx, func(error) {}
+}
+
+func (metricsExtension) ValidationDidStart(ctx context.Context) (context.Context, graphql.ValidationFinishFunc) {
+	return ctx, func([]gqlerrors.FormattedError) {}
+}
+
+func (metricsExtension) ExecutionDidStart(ctx context.Context) (context.Context, graphql.ExecutionFinishFunc) {
+	return ctx, func(*graphql.Result) {}
+}
+
+func (metricsExtension) ResolveFieldDidStart(ctx context.Context, info *graphql.ResolveInfo) (context.Context, graphql.ResolveFieldFinishFunc) {
+	field, ok := resolvedField(info)
+	if !ok {
+		return ctx, func(interface{}, error) {}
+	}
+
+	start := time.Now()
+	return ctx, func(interface{}, error) {
+		metrics.ResolverDuration.WithLabelValues(field).Observe(time.Since(start).Seconds())
+	}
+}
+
+// resolvedField returns the name (Type.field) of the field being resolved
+// when it has a resolver of its own, outside of introspection.
+func resolvedField(info *graphql.ResolveInfo) (string, bool) {
+	parent, ok := info.ParentType.(*graphql.Object)
+	if !ok || strings.HasPrefix(parent.Name(), "__") {
+		return "", false
+	}
+	if field := parent.Fields()[info.FieldName]; field == nil || field.Resolve == nil {
+		return "", false
+	}
+	return parent.Name() + "." + info.FieldName, true
+}
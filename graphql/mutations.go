@@ -0,0 +1,405 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"mas-diq/go-graphql/auth"
+	"mas-diq/go-graphql/dto"
+	"mas-diq/go-graphql/events"
+	"mas-diq/go-graphql/models"
+	"mas-diq/go-graphql/validation"
+	"time"
+
+	"github.com/graphql-go/graphql"
+	"gorm.io/gorm"
+)
+
+// decodeInput copies the "input" argument into a dto struct and validates it
+// with the same `binding` rules the REST controllers use.
+func decodeInput(ctx context.Context, args map[string]interface{}, dst interface{}) error {
+	raw, err := json.Marshal(args["input"])
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(raw, dst); err != nil {
+		return validationError(ctx, err)
+	}
+	if err := validation.Validate(dst); err != nil {
+		return validationError(ctx, err)
+	}
+	return nil
+}
+
+// loadPost loads the post a mutation acts on: posts the caller can't see are
+// NOT_FOUND, like on the post queries, and those allowed doesn't let them act
+// on are FORBIDDEN.
+func loadPost(ctx context.Context, id int, allowed func(*models.User, *models.Post) bool) (*models.Post, error) {
+	var post models.Post
+	err := models.GetOnePost(&post, uint64(id))
+	viewer := auth.UserFromContext(ctx)
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && !models.CanViewPost(viewer, &post)) {
+		return nil, newCodedError("NOT_FOUND", "Post not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if allowed != nil && !allowed(viewer, &post) {
+		return nil, translateError(ctx, models.ErrForbidden)
+	}
+	return &post, nil
+}
+
+// newMutationType builds the root Mutation type.
+// Input fields are deliberately nullable so missing values are reported by our
+// validator (with field level details) rather than by GraphQL's own type check.
+func newMutationType(userType, postType, tagType *graphql.Object) *graphql.Object {
+	createUserInput := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "CreateUserInput",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"name":  &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"email": &graphql.InputObjectFieldConfig{Type: graphql.String},
+		},
+	})
+
+	updateUserInput := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "UpdateUserInput",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"name":  &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"email": &graphql.InputObjectFieldConfig{Type: graphql.String},
+		},
+	})
+
+	createPostInput := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "CreatePostInput",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"title":    &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"slug":     &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"subtitle": &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"image":    &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"content":  &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"status":   &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"tags":     &graphql.InputObjectFieldConfig{Type: graphql.NewList(graphql.String)},
+		},
+	})
+
+	updatePostInput := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "UpdatePostInput",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"title":    &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"slug":     &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"subtitle": &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"image":    &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"content":  &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"status":   &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"tags":     &graphql.InputObjectFieldConfig{Type: graphql.NewList(graphql.String)},
+		},
+	})
+
+	tagInput := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "TagInput",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"name": &graphql.InputObjectFieldConfig{Type: graphql.String},
+		},
+	})
+
+	idArg := &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)}
+
+	return graphql.NewObject(graphql.ObjectConfig{
+		Name: "Mutation",
+		Fields: graphql.Fields{
+			"createUser": &graphql.Field{
+				Type: userType,
+				Args: graphql.FieldConfigArgument{
+					"input": &graphql.ArgumentConfig{Type: graphql.NewNonNull(createUserInput)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					var input dto.CreateUserRequest
+					if err := decodeInput(p.Context, p.Args, &input); err != nil {
+						return nil, err
+					}
+
+					user := models.User{Name: input.Name, Email: input.Email}
+					if err := models.CreateUserData(&user); err != nil {
+						return nil, translateError(p.Context, err)
+					}
+					return &user, nil
+				},
+			},
+			"updateUser": &graphql.Field{
+				Type: userType,
+				Args: graphql.FieldConfigArgument{
+					"id":    idArg,
+					"input": &graphql.ArgumentConfig{Type: graphql.NewNonNull(updateUserInput)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					var input dto.UpdateUserRequest
+					if err := decodeInput(p.Context, p.Args, &input); err != nil {
+						return nil, err
+					}
+
+					var user models.User
+					if err := models.GetOneUser(&user, uint64(p.Args["id"].(int))); err != nil {
+						return nil, err
+					}
+
+					input.ApplyTo(&user)
+					if err := models.UpdateUserData(&user); err != nil {
+						return nil, translateError(p.Context, err)
+					}
+					return &user, nil
+				},
+			},
+			"deleteUser": &graphql.Field{
+				Type: graphql.Boolean,
+				Args: graphql.FieldConfigArgument{"id": idArg},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					var user models.User
+					if err := models.GetOneUser(&user, uint64(p.Args["id"].(int))); err != nil {
+						return nil, err
+					}
+					if err := models.DeleteUser(&user); err != nil {
+						return nil, err
+					}
+					return true, nil
+				},
+			},
+			"createPost": &graphql.Field{
+				Type: postType,
+				Args: graphql.FieldConfigArgument{
+					"input": &graphql.ArgumentConfig{Type: graphql.NewNonNull(createPostInput)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					var input dto.CreatePostRequest
+					if err := decodeInput(p.Context, p.Args, &input); err != nil {
+						return nil, err
+					}
+
+					actor := auth.UserFromContext(p.Context)
+					if actor == nil {
+						return nil, newCodedError("UNAUTHENTICATED", "Authentication required")
+					}
+					post := input.ToModel(actor.ID)
+					if input.Status != "" {
+						if err := models.TransitionPost(&post, actor, models.PostStatus(input.Status), nil); err != nil {
+							return nil, translateError(p.Context, err)
+						}
+					}
+					if err := models.CreatePostData(&post, input.Tags); err != nil {
+						return nil, translateError(p.Context, err)
+					}
+					events.Publish(events.Event{Type: events.PostCreated, Post: post})
+					return &post, nil
+				},
+			},
+			"updatePost": &graphql.Field{
+				Type: postType,
+				Args: graphql.FieldConfigArgument{
+					"id":    idArg,
+					"input": &graphql.ArgumentConfig{Type: graphql.NewNonNull(updatePostInput)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					actor := auth.UserFromContext(p.Context)
+					if actor == nil {
+						return nil, newCodedError("UNAUTHENTICATED", "Authentication required")
+					}
+					var input dto.UpdatePostRequest
+					if err := decodeInput(p.Context, p.Args, &input); err != nil {
+						return nil, err
+					}
+
+					post, err := loadPost(p.Context, p.Args["id"].(int), models.CanEditPost)
+					if err != nil {
+						return nil, err
+					}
+
+					previous := post.Status
+					input.ApplyTo(post)
+					if input.Status != "" {
+						if err := models.TransitionPost(post, actor, models.PostStatus(input.Status), nil); err != nil {
+							return nil, translateError(p.Context, err)
+						}
+					}
+					if err := models.UpdatePostData(post, actor, input.Tags); err != nil {
+						return nil, translateError(p.Context, err)
+					}
+					events.Publish(events.Updated(*post, previous))
+					return post, nil
+				},
+			},
+			"publishPost": &graphql.Field{
+				Type:        postType,
+				Description: "Publishes a post now, or schedules it when 'at' (RFC 3339) lies in the future.",
+				Args: graphql.FieldConfigArgument{
+					"id": idArg,
+					"at": &graphql.ArgumentConfig{Type: graphql.String},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					var at *time.Time
+					if raw, ok := p.Args["at"].(string); ok && raw != "" {
+						parsed, err := time.Parse(time.RFC3339, raw)
+						if err != nil {
+							return nil, invalidArgument(p.Context, "at", "datetime", time.RFC3339)
+						}
+						at = &parsed
+					}
+
+					var post models.Post
+					if err := models.GetOnePost(&post, uint64(p.Args["id"].(int))); err != nil {
+						return nil, err
+					}
+					previous := post.Status
+					if err := models.PublishPost(&post, auth.UserFromContext(p.Context), at); err != nil {
+						return nil, translateError(p.Context, err)
+					}
+					events.Publish(events.Updated(post, previous))
+					return &post, nil
+				},
+			},
+			"archivePost": &graphql.Field{
+				Type: postType,
+				Args: graphql.FieldConfigArgument{"id": idArg},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					var post models.Post
+					if err := models.GetOnePost(&post, uint64(p.Args["id"].(int))); err != nil {
+						return nil, err
+					}
+					previous := post.Status
+					if err := models.ArchivePost(&post, auth.UserFromContext(p.Context)); err != nil {
+						return nil, translateError(p.Context, err)
+					}
+					events.Publish(events.Updated(post, previous))
+					return &post, nil
+				},
+			},
+			"restoreRevision": &graphql.Field{
+				Type:        postType,
+				Description: "Restores the title, subtitle, image and content of an earlier revision.",
+				Args: graphql.FieldConfigArgument{
+					"postId":   idArg,
+					"revision": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					var post models.Post
+					if err := models.GetOnePost(&post, uint64(p.Args["postId"].(int))); err != nil {
+						return nil, err
+					}
+					if err := models.RestoreRevision(&post, p.Args["revision"].(int), auth.UserFromContext(p.Context)); err != nil {
+						return nil, translateError(p.Context, err)
+					}
+					events.Publish(events.Event{Type: events.PostUpdated, Post: post})
+					return &post, nil
+				},
+			},
+			"createTag": &graphql.Field{
+				Type: tagType,
+				Args: graphql.FieldConfigArgument{
+					"input": &graphql.ArgumentConfig{Type: graphql.NewNonNull(tagInput)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if auth.UserFromContext(p.Context) == nil {
+						return nil, newCodedError("UNAUTHENTICATED", "Authentication required")
+					}
+					var input dto.TagRequest
+					if err := decodeInput(p.Context, p.Args, &input); err != nil {
+						return nil, err
+					}
+
+					tag := models.Tag{Name: input.Name}
+					if err := models.CreateTagData(&tag); err != nil {
+						return nil, translateError(p.Context, err)
+					}
+					return &tag, nil
+				},
+			},
+			"updateTag": &graphql.Field{
+				Type: tagType,
+				Args: graphql.FieldConfigArgument{
+					"id":    idArg,
+					"input": &graphql.ArgumentConfig{Type: graphql.NewNonNull(tagInput)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if auth.UserFromContext(p.Context) == nil {
+						return nil, newCodedError("UNAUTHENTICATED", "Authentication required")
+					}
+					var input dto.TagRequest
+					if err := decodeInput(p.Context, p.Args, &input); err != nil {
+						return nil, err
+					}
+
+					var tag models.Tag
+					if err := models.GetOneTag(&tag, uint64(p.Args["id"].(int))); err != nil {
+						return nil, err
+					}
+					tag.Name = input.Name
+					if err := models.UpdateTagData(&tag); err != nil {
+						return nil, translateError(p.Context, err)
+					}
+					return &tag, nil
+				},
+			},
+			"deleteTag": &graphql.Field{
+				Type: graphql.Boolean,
+				Args: graphql.FieldConfigArgument{"id": idArg},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if auth.UserFromContext(p.Context) == nil {
+						return nil, newCodedError("UNAUTHENTICATED", "Authentication required")
+					}
+					var tag models.Tag
+					if err := models.GetOneTag(&tag, uint64(p.Args["id"].(int))); err != nil {
+						return nil, err
+					}
+					if err := models.DeleteTag(&tag); err != nil {
+						return nil, err
+					}
+					return true, nil
+				},
+			},
+			"setPostTags": &graphql.Field{
+				Type:        postType,
+				Description: "Replaces the tags of a post, creating tags that don't exist yet.",
+				Args: graphql.FieldConfigArgument{
+					"postId": idArg,
+					"tags":   &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(graphql.String)))},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					input := dto.SetPostTagsRequest{Tags: stringList(p.Args["tags"])}
+					if err := validation.Validate(&input); err != nil {
+						return nil, validationError(p.Context, err)
+					}
+
+					if auth.UserFromContext(p.Context) == nil {
+						return nil, newCodedError("UNAUTHENTICATED", "Authentication required")
+					}
+					post, err := loadPost(p.Context, p.Args["postId"].(int), models.CanEditPost)
+					if err != nil {
+						return nil, err
+					}
+					if err := models.SetPostTags(post, input.Tags); err != nil {
+						return nil, translateError(p.Context, err)
+					}
+					events.Publish(events.Event{Type: events.PostUpdated, Post: *post})
+					return post, nil
+				},
+			},
+			"deletePost": &graphql.Field{
+				Type: graphql.Boolean,
+				Args: graphql.FieldConfigArgument{"id": idArg},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if auth.UserFromContext(p.Context) == nil {
+						return nil, newCodedError("UNAUTHENTICATED", "Authentication required")
+					}
+					post, err := loadPost(p.Context, p.Args["id"].(int), models.CanEditPost)
+					if err != nil {
+						return nil, err
+					}
+					if err := models.DeletePost(post); err != nil {
+						return nil, err
+					}
+					events.Publish(events.Event{Type: events.PostDeleted, Post: *post})
+					return true, nil
+				},
+			},
+		},
+	})
+}
@@ -0,0 +1,76 @@
+package graphql
+
+import (
+	"context"
+	"errors"
+	"mas-diq/go-graphql/persisted"
+)
+
+// PersistedQueries configures how the handler uses persisted queries.
+// Clients send them the way Apollo's persisted query link does, with the hash
+// in extensions: {"extensions": {"persistedQuery": {"version": 1, "sha256Hash": "..."}}}.
+type PersistedQueries struct {
+	Store persisted.Store
+	// Automatic stores the queries clients send with their hash, so later
+	// requests can send the hash alone (APQ).
+	Automatic bool
+	// Only runs queries registered from client manifests and rejects everything else.
+	Only bool
+}
+
+var (
+	errPersistedQueryNotFound = newCodedError("PERSISTED_QUERY_NOT_FOUND", "PersistedQueryNotFound")
+	errPersistedNotSupported  = newCodedError("PERSISTED_QUERY_NOT_SUPPORTED", "PersistedQueryNotSupported")
+	errPersistedHashMismatch  = newCodedError("PERSISTED_QUERY_HASH_MISMATCH", "provided sha does not match query")
+	errPersistedQueryRequired = newCodedError("PERSISTED_QUERY_REQUIRED", "Only registered operations are allowed")
+)
+
+// persistedQueryHash reads extensions.persistedQuery.sha256Hash of a request.
+func persistedQueryHash(req *Request) string {
+	extension, _ := req.Extensions["persistedQuery"].(map[string]interface{})
+	if version, _ := extension["version"].(float64); version != 1 {
+		return ""
+	}
+	hash, _ := extension["sha256Hash"].(string)
+	return hash
+}
+
+// resolvePersisted fills in the text of a query sent by hash and enforces the
+// allowlist. It reports whether the query should be stored once it validates.
+func (h *Handler) resolvePersisted(ctx context.Context, req *Request) (save bool, err error) {
+	p := h.Persisted
+	hash := persistedQueryHash(req)
+	if hash == "" {
+		if !p.Only {
+			return false, nil
+		}
+		// A full query is only accepted if it is exactly a registered one.
+		hash = persisted.Hash(req.Query)
+	}
+	if p.Store == nil {
+		return false, errPersistedNotSupported
+	}
+
+	stored, err := p.Store.Get(ctx, hash)
+	if err != nil && !errors.Is(err, persisted.ErrNotFound) {
+		return false, err
+	}
+	switch {
+	case p.Only && (stored == nil || !stored.Registered):
+		if req.Query == "" {
+			return false, errPersistedQueryNotFound
+		}
+		return false, errPersistedQueryRequired
+	case req.Query == "":
+		if stored == nil {
+			return false, errPersistedQueryNotFound
+		}
+		req.Query = stored.Body
+		return false, nil
+	case persisted.Hash(req.Query) != hash:
+		return false, errPersistedHashMismatch
+	case stored == nil && !p.Automatic:
+		return false, errPersistedNotSupported
+	}
+	return stored == nil, nil
+}
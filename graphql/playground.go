@@ -0,0 +1,77 @@
+package graphql
+
+import (
+	"bytes"
+	"html/template"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/websocket"
+)
+
+// playgroundPage is GraphiQL, loaded from unpkg with major versions pinned.
+// Subscriptions go over graphql-ws, whose connection_init payload carries
+// the headers of the editor as they were when the connection opened.
+var playgroundPage = template.Must(template.New("playground").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <meta name="viewport" content="width=device-width, initial-scale=1">
+  <title>GraphiQL</title>
+  <link rel="stylesheet" href="https://unpkg.com/graphiql@3/graphiql.min.css">
+  <style>body { margin: 0; } #graphiql { height: 100vh; }</style>
+</head>
+<body>
+  <div id="graphiql">Loading…</div>
+  <script crossorigin src="https://unpkg.com/react@18/umd/react.production.min.js"></script>
+  <script crossorigin src="https://unpkg.com/react-dom@18/umd/react-dom.production.min.js"></script>
+  <script crossorigin src="https://unpkg.com/graphql-ws@5/umd/graphql-ws.min.js"></script>
+  <script crossorigin src="https://unpkg.com/graphiql@3/graphiql.min.js"></script>
+  <script>
+    const endpoint = {{.Endpoint}};
+    const wsClient = graphqlWs.createClient({
+      url: (location.protocol === "https:" ? "wss://" : "ws://") + location.host + endpoint,
+      connectionParams: () => {
+        try {
+          return JSON.parse(localStorage.getItem("graphiql:headers") || "{}");
+        } catch {
+          return {};
+        }
+      },
+    });
+    const fetcher = GraphiQL.createFetcher({ url: endpoint, wsClient });
+    ReactDOM.createRoot(document.getElementById("graphiql")).render(
+      React.createElement(GraphiQL, { fetcher, defaultEditorToolsVisibility: true }),
+    );
+  </script>
+</body>
+</html>
+`))
+
+// Playground returns a handler serving GraphiQL for the GraphQL endpoint at
+// path. The page loads its scripts and styles from unpkg, which the
+// Content-Security-Policy of the response must allow.
+func Playground(endpoint string) http.Handler {
+	var page bytes.Buffer
+	if err := playgroundPage.Execute(&page, struct{ Endpoint string }{endpoint}); err != nil {
+		panic(err)
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Write(page.Bytes())
+	})
+}
+
+// IsPlaygroundRequest reports whether r is a browser opening the GraphQL
+// endpoint, rather than a query sent with GET or a WebSocket connection.
+func IsPlaygroundRequest(r *http.Request) bool {
+	if r.Method != http.MethodGet || websocket.IsWebSocketUpgrade(r) {
+		return false
+	}
+	query := r.URL.Query()
+	if query.Has("query") || query.Has("extensions") {
+		return false
+	}
+	return strings.Contains(r.Header.Get("Accept"), "text/html")
+}
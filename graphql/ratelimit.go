@@ -0,0 +1,70 @@
+package graphql
+
+import (
+	"context"
+	"fmt"
+	"mas-diq/go-graphql/ratelimit"
+	"net/http"
+)
+
+// RateLimit charges operations to the budget of their client (see
+// ratelimit.Client) once they passed validation and the limits, so rejected
+// operations cost nothing. Operations over budget fail with RATE_LIMITED, and
+// 429 Too Many Requests over HTTP.
+type RateLimit struct {
+	// Limiter is the budget; nil disables rate limiting.
+	Limiter *ratelimit.Limiter
+	// Weighted charges the complexity of operations (see Cost) rather than
+	// one per operation, so a client can send many small queries or a few
+	// large ones.
+	Weighted bool
+}
+
+// quota holds the charge of an HTTP request, for its response headers.
+type quota struct {
+	result *ratelimit.Result
+}
+
+type quotaKey struct{}
+
+func withQuota(ctx context.Context) (context.Context, *quota) {
+	q := &quota{}
+	return context.WithValue(ctx, quotaKey{}, q), q
+}
+
+// charge takes the cost of an operation from the budget of its client.
+func (h *Handler) charge(ctx context.Context, cost Cost) error {
+	if h.RateLimit.Limiter == nil {
+		return nil
+	}
+	amount := 1
+	if h.RateLimit.Weighted {
+		amount = max(cost.Complexity, 1)
+	}
+	result := h.RateLimit.Limiter.Take(ctx, ratelimit.Client(ctx), float64(amount))
+	if q, ok := ctx.Value(quotaKey{}).(*quota); ok {
+		q.result = &result
+	}
+	if result.Allowed {
+		return nil
+	}
+	retryAfter := max(ratelimit.Seconds(result.RetryAfter), 1)
+	return &codedError{
+		code:       "RATE_LIMITED",
+		message:    fmt.Sprintf("Rate limit exceeded, retry in %d seconds", retryAfter),
+		extensions: map[string]interface{}{"cost": amount, "retryAfter": retryAfter},
+	}
+}
+
+// writeHeaders sets the RateLimit headers of the charge of a request, if it
+// was charged, and returns the status of its response: 429 when it was refused.
+func (q *quota) writeHeaders(header http.Header, status int) int {
+	if q.result == nil {
+		return status
+	}
+	q.result.WriteHeaders(header)
+	if !q.result.Allowed {
+		return http.StatusTooManyRequests
+	}
+	return status
+}
@@ -0,0 +1,111 @@
+package graphql
+
+import (
+	"context"
+	"fmt"
+	"mas-diq/go-graphql/loaders"
+	"mas-diq/go-graphql/models"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/graphql-go/graphql"
+)
+
+// resolveID reads the ID promoted from the embedded gorm.Model.
+// The default resolver only looks at direct struct fields, so it never finds it.
+func resolveID(p graphql.ResolveParams) (interface{}, error) {
+	v := reflect.ValueOf(p.Source)
+	if v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil, nil
+	}
+	if id := v.FieldByName("ID"); id.IsValid() {
+		return id.Interface(), nil
+	}
+	return nil, nil
+}
+
+// List fields take a 'limit' argument, which defaults to defaultListLimit,
+// the size the cost analysis prices them at (see Cost), and may not exceed
+// maxListLimit, so no query returns every row.
+const (
+	defaultListLimit = 20
+	maxListLimit     = 100
+)
+
+// limitArg returns the 'limit' argument of list fields.
+func limitArg() *graphql.ArgumentConfig {
+	return &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: defaultListLimit}
+}
+
+// listLimit reads the 'limit' argument of a list field, see limitArg.
+func listLimit(p graphql.ResolveParams) (int, error) {
+	limit, _ := p.Args["limit"].(int)
+	switch {
+	case limit < 1:
+		return 0, invalidArgument(p.Context, "limit", "gte", "1")
+	case limit > maxListLimit:
+		return 0, invalidArgument(p.Context, "limit", "lte", strconv.Itoa(maxListLimit))
+	}
+	return limit, nil
+}
+
+// timeLayout is how timestamps are exposed on the GraphQL types.
+const timeLayout = "2006-01-02 15:04:05"
+
+// postFromSource returns the Post a field is being resolved on.
+func postFromSource(source interface{}) (*models.Post, bool) {
+	switch post := source.(type) {
+	case models.Post:
+		return &post, true
+	case *models.Post:
+		return post, true
+	}
+	return nil, false
+}
+
+// postTimeResolver formats an optional timestamp of a post, returning null when unset.
+func postTimeResolver(get func(*models.Post) *time.Time) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		post, ok := postFromSource(p.Source)
+		if !ok {
+			return nil, fmt.Errorf("could not cast source to Post or *Post")
+		}
+		if t := get(post); t != nil {
+			return t.Format(timeLayout), nil
+		}
+		return nil, nil
+	}
+}
+
+// primePosts queues the authors and tags of a list of posts in the request's
+// loaders, so resolving them for every post costs one query each.
+func primePosts(ctx context.Context, posts []models.Post) {
+	l := loaders.For(ctx)
+	if l == nil {
+		return
+	}
+	for _, post := range posts {
+		l.Users.Prime(post.CreatedBy)
+		l.PostTags.Prime(post.ID)
+		l.CommentCounts.Prime(post.ID)
+		if post.Image != "" {
+			l.Uploads.Prime(post.Image)
+		}
+	}
+}
+
+// stringList converts a [String] argument into a slice.
+func stringList(arg interface{}) []string {
+	values, _ := arg.([]interface{})
+	list := make([]string, 0, len(values))
+	for _, v := range values {
+		if s, ok := v.(string); ok {
+			list = append(list, s)
+		}
+	}
+	return list
+}
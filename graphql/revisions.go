@@ -0,0 +1,98 @@
+package graphql
+
+import (
+	"fmt"
+	"mas-diq/go-graphql/auth"
+	"mas-diq/go-graphql/models"
+
+	"github.com/graphql-go/graphql"
+	"gorm.io/gorm"
+)
+
+// newRevisionType defines the GraphQL 'PostRevision' object, a snapshot of a post after a change.
+func newRevisionType(userType *graphql.Object, db *gorm.DB) *graphql.Object {
+	return graphql.NewObject(graphql.ObjectConfig{
+		Name: "PostRevision",
+		Fields: graphql.Fields{
+			"revision": &graphql.Field{Type: graphql.Int}, // Sequence number, starting at 1 for the created post
+			"title":    &graphql.Field{Type: graphql.String},
+			"slug":     &graphql.Field{Type: graphql.String},
+			"subtitle": &graphql.Field{Type: graphql.String},
+			"image":    &graphql.Field{Type: graphql.String},
+			"content":  &graphql.Field{Type: graphql.String},
+			"status":   &graphql.Field{Type: graphql.String},
+			"createdAt": &graphql.Field{ // When the change was made
+				Type: graphql.String,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					revision, ok := p.Source.(models.PostRevision)
+					if !ok {
+						return nil, fmt.Errorf("could not cast source to PostRevision for createdAt")
+					}
+					return revision.CreatedAt.Format(timeLayout), nil
+				},
+			},
+			"editor": &graphql.Field{ // Who made the change; null for system changes such as scheduled publication
+				Type: userType,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					revision, ok := p.Source.(models.PostRevision)
+					if !ok {
+						return nil, fmt.Errorf("could not cast source to PostRevision for editor")
+					}
+					if revision.EditorID == nil {
+						return nil, nil
+					}
+					var editor models.User
+					if err := db.WithContext(p.Context).First(&editor, *revision.EditorID).Error; err != nil {
+						return nil, err
+					}
+					return &editor, nil
+				},
+			},
+		},
+	})
+}
+
+// revisionsField is the 'revisions' field on Post, newest revision first.
+func revisionsField(revisionType *graphql.Object) *graphql.Field {
+	return &graphql.Field{
+		Type: graphql.NewList(revisionType),
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			post, ok := postFromSource(p.Source)
+			if !ok {
+				return nil, fmt.Errorf("could not cast source to Post or *Post for post.revisions resolver")
+			}
+			if !models.CanViewRevisions(auth.UserFromContext(p.Context), post) {
+				return nil, translateError(p.Context, models.ErrForbidden)
+			}
+			var revisions []models.PostRevision
+			if err := models.GetPostRevisions(&revisions, uint64(post.ID)); err != nil {
+				return nil, err
+			}
+			return revisions, nil
+		},
+	}
+}
+
+// revisionDiffField is the 'postRevisionDiff' query, a unified diff between two revisions.
+// Like the revisions field, it is only answered for the author of the post and moderators.
+func revisionDiffField(db *gorm.DB) *graphql.Field {
+	return &graphql.Field{
+		Type: graphql.String,
+		Args: graphql.FieldConfigArgument{
+			"postId": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+			"from":   &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+			"to":     &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+		},
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			viewer := auth.UserFromContext(p.Context)
+			var post models.Post
+			if err := db.WithContext(p.Context).Scopes(models.VisiblePosts(viewer)).First(&post, p.Args["postId"].(int)).Error; err != nil {
+				return nil, err
+			}
+			if !models.CanViewRevisions(viewer, &post) {
+				return nil, translateError(p.Context, models.ErrForbidden)
+			}
+			return models.DiffRevisions(uint64(p.Args["postId"].(int)), p.Args["from"].(int), p.Args["to"].(int))
+		},
+	}
+}
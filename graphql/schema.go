@@ -2,8 +2,10 @@ package graphql
 
 import (
 	"fmt"
+	"mas-diq/go-graphql/auth"
 	"mas-diq/go-graphql/loaders"
 	"mas-diq/go-graphql/models"
+	"time"
 
 	"github.com/graphql-go/graphql"
 	"gorm.io/gorm"
@@ -20,9 +22,10 @@ func NewSchema(db *gorm.DB) (graphql.Schema, error) {
 	userType := graphql.NewObject(graphql.ObjectConfig{
 		Name: "User", // Name of the type in the GraphQL schema
 		Fields: graphql.Fields{
-			"id":    &graphql.Field{Type: graphql.Int},    // User's unique identifier
-			"name":  &graphql.Field{Type: graphql.String}, // User's name
-			"email": &graphql.Field{Type: graphql.String}, // User's email address
+			"id":    &graphql.Field{Type: graphql.Int, Resolve: resolveID}, // User's unique identifier
+			"name":  &graphql.Field{Type: graphql.String},                  // User's name
+			"email": &graphql.Field{Type: graphql.String},                  // User's email address
+			"role":  &graphql.Field{Type: graphql.String},                  // User's role: "user", "moderator" or "admin"
 		},
 	})
 
@@ -31,12 +34,16 @@ func NewSchema(db *gorm.DB) (graphql.Schema, error) {
 	postType := graphql.NewObject(graphql.ObjectConfig{
 		Name: "Post", // Name of the type in the GraphQL schema
 		Fields: graphql.Fields{
-			"id":       &graphql.Field{Type: graphql.Int},    // Post's unique identifier
-			"title":    &graphql.Field{Type: graphql.String}, // Post's title
-			"subtitle": &graphql.Field{Type: graphql.String}, // Post's subtitle
-			"image":    &graphql.Field{Type: graphql.String}, // URL or path to the post's image
-			"content":  &graphql.Field{Type: graphql.String}, // Main content of the post
-			"status":   &graphql.Field{Type: graphql.String}, // Status of the post (e.g., "published", "draft")
+			"id":       &graphql.Field{Type: graphql.Int, Resolve: resolveID}, // Post's unique identifier
+			"title":    &graphql.Field{Type: graphql.String},                  // Post's title
+			"slug":     &graphql.Field{Type: graphql.String},                  // URL friendly identifier generated from the title
+			"subtitle": &graphql.Field{Type: graphql.String},                  // Post's subtitle
+			"image":    postImageField(),                                      // Uploaded image with its size, or an external URL (see uploads.go)
+			"content":  &graphql.Field{Type: graphql.String},                  // Main content of the post
+			"status":   &graphql.Field{Type: graphql.String},                  // Status of the post (e.g., "published", "draft", "scheduled")
+			// When the post went live, and when a scheduled post will go live.
+			"publishedAt":  &graphql.Field{Type: graphql.String, Resolve: postTimeResolver(func(p *models.Post) *time.Time { return p.PublishedAt })},
+			"scheduledFor": &graphql.Field{Type: graphql.String, Resolve: postTimeResolver(func(p *models.Post) *time.Time { return p.ScheduledFor })},
 			"createdAt": &graphql.Field{ // Post's creation timestamp
 				Type: graphql.String, // Exposed as a formatted string
 				// Resolve function for 'createdAt' field.
@@ -64,7 +71,7 @@ func NewSchema(db *gorm.DB) (graphql.Schema, error) {
 		Type: graphql.NewList(postType), // The field returns a list of 'Post' types
 		Args: graphql.FieldConfigArgument{
 			// 'limit' argument allows clients to specify the maximum number of posts to retrieve.
-			"limit": &graphql.ArgumentConfig{Type: graphql.Int},
+			"limit": limitArg(),
 		},
 		// Resolve function for 'posts' field on User.
 		// It fetches posts created by the specific user.
@@ -81,17 +88,20 @@ func NewSchema(db *gorm.DB) (graphql.Schema, error) {
 
 			var posts []models.Post
 			// Start building the GORM query to find posts where 'created_by' matches the user's ID.
-			query := db.Where("created_by = ?", user.ID)
+			query := db.WithContext(p.Context).Where("created_by = ?", user.ID).Scopes(models.VisiblePosts(auth.UserFromContext(p.Context)))
 
-			// Apply 'limit' if provided in the GraphQL query arguments.
-			if limit, ok := p.Args["limit"].(int); ok && limit > 0 {
-				query = query.Limit(limit)
+			// Apply 'limit', which defaults to defaultListLimit.
+			limit, err := listLimit(p)
+			if err != nil {
+				return nil, err
 			}
+			query = query.Limit(limit)
 
 			// Execute the query.
 			if err := query.Find(&posts).Error; err != nil {
 				return nil, err // Return error if database query fails
 			}
+			primePosts(p.Context, posts)
 			return posts, nil // Return the list of found posts
 		},
 	})
@@ -103,54 +113,49 @@ func NewSchema(db *gorm.DB) (graphql.Schema, error) {
 		// Resolve function for 'author' field on Post.
 		// It fetches the user who created the post, utilizing a DataLoader for efficiency.
 		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-			// Retrieve the UserLoader instance from the GraphQL context.
-			// DataLoaders are typically initialized per request and passed via context.
-			loader, ok := p.Context.Value("userLoader").(*loaders.UserLoader)
-			if !ok {
-				// Fallback or error if loader is not found; for this example, we'll try a direct fetch.
-				// In a real app, you might return an error:
-				// return nil, fmt.Errorf("userLoader not found in context")
+			// p.Source is the parent Post object.
+			post, okPost := postFromSource(p.Source)
+			if !okPost {
+				return nil, fmt.Errorf("could not cast source to Post or *Post for post.author resolver")
+			}
 
+			// Retrieve the per-request loaders from the GraphQL context (see routes.SetupRouter).
+			l := loaders.For(p.Context)
+			if l == nil {
 				// Fallback: Direct database query if loader is not available (less efficient for multiple author lookups)
-				post, okPost := p.Source.(models.Post)
-				if !okPost {
-					postPtr, okPostPtr := p.Source.(*models.Post)
-					if !okPostPtr {
-						return nil, fmt.Errorf("could not cast source to Post or *Post for post.author resolver (fallback)")
-					}
-					post = *postPtr
-				}
 				var author models.User
-				if err := db.First(&author, post.CreatedBy).Error; err != nil {
+				if err := db.WithContext(p.Context).First(&author, post.CreatedBy).Error; err != nil {
 					return nil, err
 				}
 				return &author, nil // Return a pointer to the user, GORM often works well with pointers for single results
 			}
 
-			// p.Source is the parent Post object.
-			post, okPost := p.Source.(models.Post)
-			if !okPost {
-				postPtr, okPostPtr := p.Source.(*models.Post)
-				if !okPostPtr {
-					return nil, fmt.Errorf("could not cast source to Post or *Post for post.author resolver (loader path)")
-				}
-				post = *postPtr
-			}
-
 			// Use the DataLoader to fetch the user.
 			// DataLoader batches and caches requests, preventing N+1 query problems.
 			// It expects a slice of keys (user IDs in this case) and returns a slice of users and a slice of errors.
-			users, err := loader.Load(p.Context, []uint{post.CreatedBy})
+			users, err := l.Users.Load(p.Context, []uint{post.CreatedBy})
 			if err != nil { // Check for error from the loader
 				return nil, err
 			}
-			if len(users) == 0 {
+			if len(users) == 0 || users[0] == nil {
 				return nil, fmt.Errorf("user not found by loader for ID: %d", post.CreatedBy)
 			}
 			return users[0], nil // Return the first user from the result (as we loaded by a single ID)
 		},
 	})
 
+	// Add 'revisions' field to 'postType': the change history of the post (see revisions.go).
+	revisionType := newRevisionType(userType, db)
+	postType.AddFieldConfig("revisions", revisionsField(revisionType))
+
+	// Add 'tags' to 'postType' and define the 'Tag' type with its 'posts' (see tags.go).
+	tagType := newTagType(postType)
+	postType.AddFieldConfig("tags", postTagsField(tagType, db))
+
+	// Define the 'Comment' type and add 'comments' and 'commentCount' to 'postType' (see comments.go).
+	commentType, commentConnectionType := newCommentTypes(userType, postType, db)
+	addPostCommentFields(postType, commentConnectionType)
+
 	// --- Define the Root Query type ---
 	// queryType is the entry point for all GraphQL read operations.
 	queryType := graphql.NewObject(graphql.ObjectConfig{
@@ -168,23 +173,51 @@ func NewSchema(db *gorm.DB) (graphql.Schema, error) {
 					id, _ := p.Args["id"].(int) // Get 'id' argument
 					var user models.User
 					// Fetch the user from the database using GORM.
-					if err := db.First(&user, id).Error; err != nil {
+					if err := db.WithContext(p.Context).First(&user, id).Error; err != nil {
 						return nil, err // Return error if user not found or DB error
 					}
 					return &user, nil // Return the found user (pointer often preferred for GORM results)
 				},
 			},
-			// 'post' query field: Fetches a single post by its ID.
+			// 'emailAvailable' query field: Tells signup forms whether an email can still be registered.
+			"emailAvailable": &graphql.Field{
+				Type: graphql.Boolean,
+				Args: graphql.FieldConfigArgument{
+					"email": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					email, _ := p.Args["email"].(string)
+					return models.IsEmailAvailable(email, 0)
+				},
+			},
+			// 'post' query field: Fetches a single post by its ID or slug.
 			"post": &graphql.Field{
 				Type: postType, // Specifies that this query returns a 'Post'
 				Args: graphql.FieldConfigArgument{
-					// 'id' argument is required to identify the post.
-					"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+					// Exactly one of 'id' or 'slug' identifies the post.
+					"id":   &graphql.ArgumentConfig{Type: graphql.Int},
+					"slug": &graphql.ArgumentConfig{Type: graphql.String},
 				},
 				// Resolve function for the 'post' query.
 				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-					id, _ := p.Args["id"].(int) // Get 'id' argument
+					id, hasID := p.Args["id"].(int) // Get 'id' argument
+					slug, hasSlug := p.Args["slug"].(string)
+					if hasID == hasSlug {
+						return nil, fmt.Errorf("exactly one of id or slug must be given")
+					}
+
+					viewer := auth.UserFromContext(p.Context)
 					var post models.Post
+					if hasSlug {
+						// Old slugs resolve to the post's current version; clients can read 'slug' to update links.
+						if _, err := models.GetPostBySlug(&post, slug); err != nil {
+							return nil, err
+						}
+						if !models.CanViewPost(viewer, &post) {
+							return nil, gorm.ErrRecordNotFound
+						}
+						return &post, nil
+					}
 					// Fetch the post from the database.
 					// 'Preload("User")' tells GORM to also fetch the associated User (author)
 					// to optimize and avoid a separate query if the 'author' field is requested.
@@ -193,12 +226,18 @@ func NewSchema(db *gorm.DB) (graphql.Schema, error) {
 					//       The GraphQL 'author' resolver will still run, potentially using a DataLoader.
 					//       If the DataLoader is smart or if the user is already on the `post.User` struct,
 					//       it can use this preloaded data.
-					if err := db.Preload("User").First(&post, id).Error; err != nil {
+					if err := db.WithContext(p.Context).Scopes(models.VisiblePosts(viewer)).Preload("User").First(&post, id).Error; err != nil {
 						return nil, err // Return error if post not found or DB error
 					}
 					return &post, nil // Return the found post
 				},
 			},
+			// 'tags' query field: Lists tags with their number of published posts, for tag clouds.
+			"tags": tagsQueryField(tagType),
+			// 'search' query field: Full-text search over published posts (see search.go).
+			"search": searchField(postType),
+			// 'postRevisionDiff' query field: Compares two revisions of a post.
+			"postRevisionDiff": revisionDiffField(db),
 			// 'posts' query field: Fetches a list of posts, with optional filters.
 			"posts": &graphql.Field{
 				Type: graphql.NewList(postType), // Specifies that this query returns a list of 'Post'
@@ -206,14 +245,19 @@ func NewSchema(db *gorm.DB) (graphql.Schema, error) {
 					// 'status' argument to filter posts by their status.
 					"status": &graphql.ArgumentConfig{Type: graphql.String},
 					// 'limit' argument to restrict the number of posts returned.
-					"limit": &graphql.ArgumentConfig{Type: graphql.Int},
+					"limit": limitArg(),
 					// 'authorId' argument to filter posts by the author's ID.
 					"authorId": &graphql.ArgumentConfig{Type: graphql.Int},
+					// 'tagsAny' keeps posts with at least one of the tags, 'tagsAll' posts with all of them (names or slugs).
+					"tagsAny": &graphql.ArgumentConfig{Type: graphql.NewList(graphql.String)},
+					"tagsAll": &graphql.ArgumentConfig{Type: graphql.NewList(graphql.String)},
 				},
 				// Resolve function for the 'posts' query.
 				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
 					var posts []models.Post
-					query := db.Model(&models.Post{}) // Start with a base GORM query for Post model
+					query := db.WithContext(p.Context).Model(&models.Post{}) // Start with a base GORM query for Post model
+					// Unpublished posts are only listed for their author and moderators.
+					query = query.Scopes(models.VisiblePosts(auth.UserFromContext(p.Context)))
 
 					// Apply 'status' filter if provided.
 					if status, ok := p.Args["status"].(string); ok && status != "" {
@@ -225,26 +269,54 @@ func NewSchema(db *gorm.DB) (graphql.Schema, error) {
 						query = query.Where("created_by = ?", authorId)
 					}
 
-					// Apply 'limit' if provided.
-					if limit, ok := p.Args["limit"].(int); ok && limit > 0 {
-						query = query.Limit(limit)
+					// Apply tag filters if provided.
+					query = query.Scopes(
+						models.WithAnyTag(stringList(p.Args["tagsAny"])),
+						models.WithAllTags(stringList(p.Args["tagsAll"])),
+					)
+
+					// Apply 'limit', which defaults to defaultListLimit.
+					limit, err := listLimit(p)
+					if err != nil {
+						return nil, err
 					}
+					query = query.Limit(limit)
 
 					// Execute the query to find all matching posts.
 					if err := query.Find(&posts).Error; err != nil {
 						return nil, err // Return error if database query fails
 					}
+					primePosts(p.Context, posts)
 					return posts, nil // Return the list of found posts
 				},
 			},
 		},
 	})
 
+	// --- Define the Root Mutation type ---
+	// mutationType is the entry point for all GraphQL write operations (see mutations.go).
+	mutationType := newMutationType(userType, postType, tagType)
+	for name, field := range commentMutations(commentType) {
+		mutationType.AddFieldConfig(name, field)
+	}
+	mutationType.AddFieldConfig("uploadImage", uploadImageField())
+
+	// --- Define the Root Subscription type ---
+	// subscriptionType streams post events over WebSocket (see subscriptions.go).
+	subscriptionType := newSubscriptionType(postType)
+
 	// --- Create and return the GraphQL schema ---
-	// The schema is configured with the root query type.
-	// Mutations and Subscriptions would also be added here if defined.
+	// The schema is configured with the root query, mutation and subscription types.
 	return graphql.NewSchema(graphql.SchemaConfig{
-		Query: queryType, // Set the root query type
-		// Mutation: mutationType, // Example if you had mutations
+		Query:        queryType,        // Set the root query type
+		Mutation:     mutationType,     // Set the root mutation type
+		Subscription: subscriptionType, // Set the root subscription type
+		Extensions: []graphql.Extension{
+			cacheControlExtension{}, // Collects cache hints for GET responses (see cache.go)
+			eventContextExtension{}, // Gives subscription events their own context (see subscriptions.go)
+			errorLogExtension{},     // Logs resolver errors (see logging.go)
+			metricsExtension{},      // Times resolvers (see metrics.go)
+			tracingExtension{},      // Traces resolvers (see tracing.go)
+		},
 	})
 }
@@ -0,0 +1,125 @@
+package graphql
+
+import (
+	"context"
+	"fmt"
+	"mas-diq/go-graphql/auth"
+	"mas-diq/go-graphql/models"
+	"strings"
+
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+// fieldScopes are the scopes API keys need for root fields, keyed by
+// "Type.field", like the REST routes of the same resources. A key allowed a
+// root field may read what is nested below it, as the REST responses embed
+// related resources. Fields that aren't listed need unlistedScopes.
+var fieldScopes = map[string]string{
+	"Query.user":             models.ScopeUsersRead,
+	"Query.emailAvailable":   models.ScopeUsersRead,
+	"Query.post":             models.ScopePostsRead,
+	"Query.posts":            models.ScopePostsRead,
+	"Query.search":           models.ScopePostsRead,
+	"Query.postRevisionDiff": models.ScopePostsRead,
+	"Query.tags":             models.ScopePostsRead,
+
+	"Mutation.createUser":      models.ScopeUsersWrite,
+	"Mutation.updateUser":      models.ScopeUsersWrite,
+	"Mutation.deleteUser":      models.ScopeUsersWrite,
+	"Mutation.createPost":      models.ScopePostsWrite,
+	"Mutation.updatePost":      models.ScopePostsWrite,
+	"Mutation.publishPost":     models.ScopePostsWrite,
+	"Mutation.archivePost":     models.ScopePostsWrite,
+	"Mutation.restoreRevision": models.ScopePostsWrite,
+	"Mutation.setPostTags":     models.ScopePostsWrite,
+	"Mutation.createTag":       models.ScopePostsWrite,
+	"Mutation.updateTag":       models.ScopePostsWrite,
+	"Mutation.deleteTag":       models.ScopePostsWrite,
+	"Mutation.deletePost":      models.ScopePostsWrite,
+	"Mutation.createComment":   models.ScopePostsWrite,
+	"Mutation.updateComment":   models.ScopePostsWrite,
+	"Mutation.moderateComment": models.ScopePostsWrite,
+	"Mutation.deleteComment":   models.ScopePostsWrite,
+	"Mutation.uploadImage":     models.ScopePostsWrite,
+
+	"Subscription.postCreated":   models.ScopePostsRead,
+	"Subscription.postUpdated":   models.ScopePostsRead,
+	"Subscription.postPublished": models.ScopePostsRead,
+}
+
+// unlistedScopes are the scopes of root fields missing from fieldScopes:
+// every write scope, so that a field added without one isn't open to all
+// keys. Introspection fields need no scope.
+var unlistedScopes = []string{models.ScopeUsersWrite, models.ScopePostsWrite}
+
+// checkScopes rejects operations of API keys that select a root field the
+// key lacks the scope of, with INSUFFICIENT_SCOPE and the missing scopes.
+func (h *Handler) checkScopes(ctx context.Context, doc *ast.Document, operationName string) error {
+	key := auth.APIKeyFromContext(ctx)
+	operation := findOperation(doc, operationName)
+	if key == nil || operation == nil {
+		return nil
+	}
+
+	var root string
+	switch operation.Operation {
+	case ast.OperationTypeMutation:
+		root = h.Schema.MutationType().Name()
+	case ast.OperationTypeSubscription:
+		root = h.Schema.SubscriptionType().Name()
+	default:
+		root = h.Schema.QueryType().Name()
+	}
+
+	fragments := map[string]*ast.FragmentDefinition{}
+	for _, definition := range doc.Definitions {
+		if fragment, ok := definition.(*ast.FragmentDefinition); ok {
+			fragments[fragment.Name.Value] = fragment
+		}
+	}
+
+	var missing []string
+	seen := map[string]bool{}
+	var visit func(*ast.SelectionSet)
+	visit = func(set *ast.SelectionSet) {
+		if set == nil {
+			return
+		}
+		for _, selection := range set.Selections {
+			switch selection := selection.(type) {
+			case *ast.Field:
+				name := selection.Name.Value
+				if strings.HasPrefix(name, "__") {
+					continue
+				}
+				scopes := unlistedScopes
+				if scope, ok := fieldScopes[root+"."+name]; ok {
+					scopes = []string{scope}
+				}
+				for _, scope := range scopes {
+					if !seen[scope] && !key.HasScope(scope) {
+						missing = append(missing, scope)
+					}
+					seen[scope] = true
+				}
+			case *ast.InlineFragment:
+				visit(selection.SelectionSet)
+			case *ast.FragmentSpread:
+				// Validation rejected fragments that spread themselves.
+				if fragment, ok := fragments[selection.Name.Value]; ok {
+					visit(fragment.SelectionSet)
+				}
+			}
+		}
+	}
+	visit(operation.SelectionSet)
+
+	if len(missing) == 0 {
+		return nil
+	}
+	return &codedError{
+		code:       "INSUFFICIENT_SCOPE",
+		message:    fmt.Sprintf("API key lacks the %s scope", strings.Join(missing, ", ")),
+		extensions: map[string]interface{}{"scopes": missing},
+	}
+}
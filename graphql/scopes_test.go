@@ -0,0 +1,122 @@
+package graphql
+
+import (
+	"context"
+	"mas-diq/go-graphql/auth"
+	"mas-diq/go-graphql/models"
+	"reflect"
+	"testing"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/parser"
+)
+
+func TestCheckScopes(t *testing.T) {
+	schema, err := NewSchema(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := &Handler{Schema: schema}
+
+	tests := []struct {
+		name    string
+		scopes  string // "" checks a request without an API key
+		query   string
+		missing []string
+	}{
+		{name: "no API key", query: `mutation { deletePost(id: 1) }`},
+		{name: "read", scopes: models.ScopePostsRead, query: `{ posts { title author { name } } }`},
+		{name: "read without the scope", scopes: models.ScopeUsersRead, query: `{ posts { title } }`, missing: []string{models.ScopePostsRead}},
+		{name: "write", scopes: models.ScopePostsWrite, query: `mutation { createPost(input: {title: "t"}) { id } }`},
+		{name: "write with the read scope", scopes: models.ScopePostsRead, query: `mutation { createPost(input: {title: "t"}) { id } }`, missing: []string{models.ScopePostsWrite}},
+		{name: "comments", scopes: models.ScopeUsersWrite, query: `mutation { createComment(input: {postId: 1, body: "b"}) { id } }`, missing: []string{models.ScopePostsWrite}},
+		{name: "moderation", scopes: models.ScopePostsWrite, query: `mutation { moderateComment(id: 1, status: "rejected") { id } }`},
+		{name: "uploads", scopes: models.ScopePostsRead, query: `mutation($file: Upload!) { uploadImage(file: $file) { url } }`, missing: []string{models.ScopePostsWrite}},
+		{name: "subscription", scopes: models.ScopePostsRead, query: `subscription { postCreated { id } }`},
+		{name: "fragments", scopes: models.ScopePostsRead, query: `{ ...q } fragment q on Query { ... on Query { user(id: 1) { name } } }`, missing: []string{models.ScopeUsersRead}},
+		{name: "several scopes", scopes: models.ScopePostsRead, query: `{ user(id: 1) { name } emailAvailable(email: "a@b.c") tags { name } }`, missing: []string{models.ScopeUsersRead}},
+		{name: "introspection", scopes: models.ScopePostsRead, query: `{ __typename __schema { types { name } } __type(name: "Post") { name } }`},
+		{name: "typename of a mutation", scopes: models.ScopePostsWrite, query: `mutation { __typename deletePost(id: 1) }`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.scopes != "" {
+				ctx = auth.WithAPIKey(ctx, &models.APIKey{Scopes: tt.scopes})
+			}
+			doc, err := parser.Parse(parser.ParseParams{Source: tt.query})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = h.checkScopes(ctx, doc, "")
+			if tt.missing == nil {
+				if err != nil {
+					t.Fatalf("err = %v, want none", err)
+				}
+				return
+			}
+			coded, ok := err.(*codedError)
+			if !ok || coded.code != "INSUFFICIENT_SCOPE" {
+				t.Fatalf("err = %v, want INSUFFICIENT_SCOPE", err)
+			}
+			if got := coded.extensions["scopes"]; !reflect.DeepEqual(got, tt.missing) {
+				t.Fatalf("missing scopes = %v, want %v", got, tt.missing)
+			}
+		})
+	}
+}
+
+func TestCheckScopesUnlistedField(t *testing.T) {
+	schema, err := graphql.NewSchema(graphql.SchemaConfig{
+		Query: graphql.NewObject(graphql.ObjectConfig{
+			Name:   "Query",
+			Fields: graphql.Fields{"unlisted": &graphql.Field{Type: graphql.String}},
+		}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := &Handler{Schema: schema}
+	doc, err := parser.Parse(parser.ParseParams{Source: `{ unlisted }`})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		scopes  string
+		missing []string
+	}{
+		{scopes: models.ScopePostsRead + "," + models.ScopeUsersRead, missing: unlistedScopes},
+		{scopes: models.ScopePostsWrite, missing: []string{models.ScopeUsersWrite}},
+		{scopes: models.ScopeUsersWrite + "," + models.ScopePostsWrite},
+	}
+	for _, tt := range tests {
+		err := h.checkScopes(auth.WithAPIKey(context.Background(), &models.APIKey{Scopes: tt.scopes}), doc, "")
+		var got []string
+		if coded, ok := err.(*codedError); ok {
+			got, _ = coded.extensions["scopes"].([]string)
+		} else if err != nil {
+			t.Fatalf("%s: err = %v", tt.scopes, err)
+		}
+		if !reflect.DeepEqual(got, tt.missing) {
+			t.Errorf("%s: missing scopes = %v, want %v", tt.scopes, got, tt.missing)
+		}
+	}
+}
+
+// Root fields without a scope need every write scope, which would lock keys
+// out of new reads: every field of the schema should be listed.
+func TestFieldScopesListEveryRootField(t *testing.T) {
+	schema, err := NewSchema(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, root := range []*graphql.Object{schema.QueryType(), schema.MutationType(), schema.SubscriptionType()} {
+		for name := range root.Fields() {
+			if _, ok := fieldScopes[root.Name()+"."+name]; !ok {
+				t.Errorf("%s.%s has no entry in fieldScopes", root.Name(), name)
+			}
+		}
+	}
+}
@@ -0,0 +1,284 @@
+package graphql
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/graphql-go/graphql"
+)
+
+// builtinScalars are the scalars every schema has; the SDL leaves them out.
+var builtinScalars = map[string]bool{"String": true, "Int": true, "Float": true, "Boolean": true, "ID": true}
+
+// PrintSchema returns the schema in the GraphQL schema definition language,
+// for client teams and code generators. Types, fields, arguments and enum
+// values are sorted by name, so that printing the same schema twice gives
+// the same text and snapshots of it diff cleanly.
+func PrintSchema(schema graphql.Schema) string {
+	var blocks []string
+	if block := printSchemaDefinition(schema); block != "" {
+		blocks = append(blocks, block)
+	}
+
+	directives := append([]*graphql.Directive(nil), schema.Directives()...)
+	sort.Slice(directives, func(i, j int) bool { return directives[i].Name < directives[j].Name })
+	for _, directive := range directives {
+		if !isSpecifiedDirective(directive) {
+			blocks = append(blocks, printDirective(directive))
+		}
+	}
+
+	typeMap := schema.TypeMap()
+	names := make([]string, 0, len(typeMap))
+	for name := range typeMap {
+		if !strings.HasPrefix(name, "__") && !builtinScalars[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		blocks = append(blocks, printType(typeMap[name]))
+	}
+	return strings.Join(blocks, "\n\n") + "\n"
+}
+
+// printSchemaDefinition prints the schema block, which is only needed when
+// the root types aren't named Query, Mutation and Subscription.
+func printSchemaDefinition(schema graphql.Schema) string {
+	roots := []struct {
+		operation string
+		object    *graphql.Object
+	}{
+		{"query", schema.QueryType()},
+		{"mutation", schema.MutationType()},
+		{"subscription", schema.SubscriptionType()},
+	}
+	conventional := true
+	var lines []string
+	for _, root := range roots {
+		if root.object == nil {
+			continue
+		}
+		if root.object.Name() != strings.ToUpper(root.operation[:1])+root.operation[1:] {
+			conventional = false
+		}
+		lines = append(lines, "  "+root.operation+": "+root.object.Name())
+	}
+	if conventional {
+		return ""
+	}
+	return "schema {\n" + strings.Join(lines, "\n") + "\n}"
+}
+
+func isSpecifiedDirective(directive *graphql.Directive) bool {
+	for _, specified := range graphql.SpecifiedDirectives {
+		if directive.Name == specified.Name {
+			return true
+		}
+	}
+	return false
+}
+
+func printDirective(directive *graphql.Directive) string {
+	return printDescription(directive.Description, "") +
+		"directive @" + directive.Name + printArgs(directive.Args, "") +
+		" on " + strings.Join(directive.Locations, " | ")
+}
+
+func printType(t graphql.Type) string {
+	description := printDescription(t.Description(), "")
+	switch t := t.(type) {
+	case *graphql.Scalar:
+		return description + "scalar " + t.Name()
+	case *graphql.Object:
+		implements := ""
+		if interfaces := t.Interfaces(); len(interfaces) > 0 {
+			names := make([]string, len(interfaces))
+			for i, iface := range interfaces {
+				names[i] = iface.Name()
+			}
+			sort.Strings(names)
+			implements = " implements " + strings.Join(names, " & ")
+		}
+		return description + "type " + t.Name() + implements + printFields(t.Fields())
+	case *graphql.Interface:
+		return description + "interface " + t.Name() + printFields(t.Fields())
+	case *graphql.Union:
+		members := make([]string, len(t.Types()))
+		for i, member := range t.Types() {
+			members[i] = member.Name()
+		}
+		sort.Strings(members)
+		return description + "union " + t.Name() + " = " + strings.Join(members, " | ")
+	case *graphql.Enum:
+		values := append([]*graphql.EnumValueDefinition(nil), t.Values()...)
+		sort.Slice(values, func(i, j int) bool { return values[i].Name < values[j].Name })
+		var b strings.Builder
+		b.WriteString(description + "enum " + t.Name() + " {\n")
+		for _, value := range values {
+			b.WriteString(printDescription(value.Description, "  ") + "  " + value.Name + printDeprecated(value.DeprecationReason) + "\n")
+		}
+		b.WriteString("}")
+		return b.String()
+	case *graphql.InputObject:
+		fields := t.Fields()
+		names := make([]string, 0, len(fields))
+		for name := range fields {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		var b strings.Builder
+		b.WriteString(description + "input " + t.Name() + " {\n")
+		for _, name := range names {
+			field := fields[name]
+			b.WriteString(printDescription(field.Description(), "  ") + "  " + name + ": " + field.Type.String() + printDefault(field.DefaultValue, field.Type) + "\n")
+		}
+		b.WriteString("}")
+		return b.String()
+	}
+	return ""
+}
+
+func printFields(fields graphql.FieldDefinitionMap) string {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var b strings.Builder
+	b.WriteString(" {\n")
+	for _, name := range names {
+		field := fields[name]
+		b.WriteString(printDescription(field.Description, "  ") + "  " + name + printArgs(field.Args, "  ") + ": " + field.Type.String() + printDeprecated(field.DeprecationReason) + "\n")
+	}
+	b.WriteString("}")
+	return b.String()
+}
+
+// printArgs prints arguments on one line, or one per line when any of them
+// has a description.
+func printArgs(args []*graphql.Argument, indent string) string {
+	if len(args) == 0 {
+		return ""
+	}
+	sorted := append([]*graphql.Argument(nil), args...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name() < sorted[j].Name() })
+
+	described := false
+	printed := make([]string, len(sorted))
+	for i, arg := range sorted {
+		printed[i] = arg.Name() + ": " + arg.Type.String() + printDefault(arg.DefaultValue, arg.Type)
+		described = described || arg.Description() != ""
+	}
+	if !described {
+		return "(" + strings.Join(printed, ", ") + ")"
+	}
+	var b strings.Builder
+	b.WriteString("(\n")
+	for i, arg := range sorted {
+		b.WriteString(printDescription(arg.Description(), indent+"  ") + indent + "  " + printed[i] + "\n")
+	}
+	b.WriteString(indent + ")")
+	return b.String()
+}
+
+func printDefault(value interface{}, t graphql.Input) string {
+	if value == nil {
+		return ""
+	}
+	return " = " + printValue(value, t)
+}
+
+func printDeprecated(reason string) string {
+	switch reason {
+	case "":
+		return ""
+	case graphql.DefaultDeprecationReason:
+		return " @deprecated"
+	}
+	return " @deprecated(reason: " + printString(reason) + ")"
+}
+
+// printDescription prints a description above what it describes, as a
+// block string when it spans lines.
+func printDescription(description, indent string) string {
+	if description == "" {
+		return ""
+	}
+	if !strings.Contains(description, "\n") {
+		return indent + printString(description) + "\n"
+	}
+	lines := strings.Split(strings.ReplaceAll(description, `"""`, `\"""`), "\n")
+	for i, line := range lines {
+		if line != "" {
+			lines[i] = indent + line
+		}
+	}
+	return indent + `"""` + "\n" + strings.Join(lines, "\n") + "\n" + indent + `"""` + "\n"
+}
+
+// printString quotes s; the escapes of JSON strings are those of GraphQL.
+func printString(s string) string {
+	var b bytes.Buffer
+	encoder := json.NewEncoder(&b)
+	encoder.SetEscapeHTML(false)
+	encoder.Encode(s)
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// printValue prints the default value of an argument or input field as a
+// GraphQL literal of type t.
+func printValue(value interface{}, t graphql.Input) string {
+	if value == nil {
+		return "null"
+	}
+	switch t := t.(type) {
+	case *graphql.NonNull:
+		return printValue(value, t.OfType)
+	case *graphql.List:
+		v := reflect.ValueOf(value)
+		if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+			// A single value stands for a list of one.
+			return printValue(value, t.OfType)
+		}
+		items := make([]string, v.Len())
+		for i := range items {
+			items[i] = printValue(v.Index(i).Interface(), t.OfType)
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	case *graphql.InputObject:
+		fields, ok := value.(map[string]interface{})
+		if !ok {
+			break
+		}
+		names := make([]string, 0, len(fields))
+		for name := range fields {
+			if _, ok := t.Fields()[name]; ok {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+		items := make([]string, len(names))
+		for i, name := range names {
+			items[i] = name + ": " + printValue(fields[name], t.Fields()[name].Type)
+		}
+		return "{" + strings.Join(items, ", ") + "}"
+	case *graphql.Enum:
+		for _, enumValue := range t.Values() {
+			if reflect.DeepEqual(enumValue.Value, value) {
+				return enumValue.Name
+			}
+		}
+	}
+	switch value := value.(type) {
+	case string:
+		return printString(value)
+	case bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return fmt.Sprint(value)
+	}
+	return printString(fmt.Sprint(value))
+}
@@ -0,0 +1,87 @@
+package graphql
+
+import (
+	"fmt"
+	"mas-diq/go-graphql/models"
+	"mas-diq/go-graphql/search"
+	"strings"
+
+	"github.com/graphql-go/graphql"
+)
+
+// searchHighlightsType holds HTML escaped text with the matching words in <mark> tags.
+var searchHighlightsType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "SearchHighlights",
+	Fields: graphql.Fields{
+		"title":    &graphql.Field{Type: graphql.String},
+		"subtitle": &graphql.Field{Type: graphql.String},
+		"content":  &graphql.Field{Type: graphql.String}, // A snippet around the best matching passage
+	},
+})
+
+// searchField is the root 'search' query: published posts matching the words of
+// 'query' in their title, subtitle or content, most relevant first.
+func searchField(postType *graphql.Object) *graphql.Field {
+	resultType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "SearchResult",
+		Fields: graphql.Fields{
+			"post": &graphql.Field{
+				Type: postType,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					result, ok := p.Source.(search.Result)
+					if !ok {
+						return nil, fmt.Errorf("could not cast source to search.Result for post resolver")
+					}
+					return result.Post, nil
+				},
+			},
+			"score": &graphql.Field{Type: graphql.Float},
+			"highlights": &graphql.Field{
+				Type: searchHighlightsType,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					result, ok := p.Source.(search.Result)
+					if !ok {
+						return nil, fmt.Errorf("could not cast source to search.Result for highlights resolver")
+					}
+					return result.Highlights, nil
+				},
+			},
+		},
+	})
+
+	return &graphql.Field{
+		Type: graphql.NewList(resultType),
+		Args: graphql.FieldConfigArgument{
+			"query":  &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+			"limit":  limitArg(),
+			"offset": &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 0},
+		},
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			query, _ := p.Args["query"].(string)
+			offset, _ := p.Args["offset"].(int)
+			switch n := len([]rune(strings.TrimSpace(query))); {
+			case n < 2:
+				return nil, invalidArgument(p.Context, "query", "min", "2")
+			case n > 200:
+				return nil, invalidArgument(p.Context, "query", "max", "200")
+			case offset < 0:
+				return nil, invalidArgument(p.Context, "offset", "gte", "0")
+			}
+
+			limit, err := listLimit(p)
+			if err != nil {
+				return nil, err
+			}
+			results, err := search.Posts(p.Context, query, limit, offset)
+			if err != nil {
+				return nil, err
+			}
+			posts := make([]models.Post, len(results))
+			for i, result := range results {
+				posts[i] = result.Post
+			}
+			primePosts(p.Context, posts)
+			return results, nil
+		},
+	}
+}
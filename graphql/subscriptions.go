@@ -0,0 +1,131 @@
+package graphql
+
+import (
+	"context"
+	"mas-diq/go-graphql/auth"
+	"mas-diq/go-graphql/events"
+	"mas-diq/go-graphql/models"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+)
+
+// newSubscriptionType defines the root 'Subscription' type. Its fields stream
+// post events from the events bus; they are served over WebSocket (see websocket.go).
+func newSubscriptionType(postType *graphql.Object) *graphql.Object {
+	return graphql.NewObject(graphql.ObjectConfig{
+		Name: "Subscription",
+		Fields: graphql.Fields{
+			"postCreated": &graphql.Field{
+				Type:        postType,
+				Description: "Posts as they are created.",
+				Subscribe: subscribePosts(func(p graphql.ResolveParams, event events.Event) bool {
+					return event.Type == events.PostCreated
+				}),
+				Resolve: resolveEvent,
+			},
+			"postUpdated": &graphql.Field{
+				Type:        postType,
+				Description: "A post every time it is changed, published included.",
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+				},
+				Subscribe: subscribePosts(func(p graphql.ResolveParams, event events.Event) bool {
+					id, _ := p.Args["id"].(int)
+					return event.Post.ID == uint(id) && (event.Type == events.PostUpdated || event.Type == events.PostPublished)
+				}),
+				Resolve: resolveEvent,
+			},
+			"postPublished": &graphql.Field{
+				Type:        postType,
+				Description: "Posts as they go live, scheduled ones included; 'authorId' narrows them down to one author.",
+				Args: graphql.FieldConfigArgument{
+					"authorId": &graphql.ArgumentConfig{Type: graphql.Int},
+				},
+				Subscribe: subscribePosts(func(p graphql.ResolveParams, event events.Event) bool {
+					if event.Type != events.PostPublished {
+						return false
+					}
+					authorID, ok := p.Args["authorId"].(int)
+					return !ok || event.Post.CreatedBy == uint(authorID)
+				}),
+				Resolve: resolveEvent,
+			},
+		},
+	})
+}
+
+// subscribePosts subscribes to the post events accepted by filter, leaving
+// out posts the subscriber may not read (see models.CanViewPost). The events
+// are sent as *models.Post on a chan interface{}, the type
+// graphql.ExecuteSubscription reads, until the subscription's context ends.
+func subscribePosts(filter func(p graphql.ResolveParams, event events.Event) bool) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		viewer := auth.UserFromContext(p.Context)
+		source := events.Subscribe(p.Context, func(event events.Event) bool {
+			return models.CanViewPost(viewer, &event.Post) && filter(p, event)
+		})
+		posts := make(chan interface{})
+		go func() {
+			defer close(posts)
+			for event := range source {
+				post := event.Post
+				select {
+				case posts <- &post:
+				case <-p.Context.Done():
+					return
+				}
+			}
+		}()
+		return posts, nil
+	}
+}
+
+// resolveEvent resolves a subscription field to the post of the event being sent.
+func resolveEvent(p graphql.ResolveParams) (interface{}, error) {
+	post, _ := p.Source.(*models.Post)
+	return post, nil
+}
+
+type eventContextKey struct{}
+
+// withEventContext has every event of a subscription executed in a context
+// prepared by prepare, as if it were a request of its own. graphql-go
+// executes all events in the context of the subscription, whose loaders
+// would keep serving what they cached for earlier events.
+func withEventContext(ctx context.Context, prepare func(context.Context) context.Context) context.Context {
+	if prepare == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, eventContextKey{}, prepare)
+}
+
+// eventContextExtension applies withEventContext: graphql-go runs every
+// event through graphql.Execute, which lets extensions replace the context.
+type eventContextExtension struct{}
+
+var _ graphql.Extension = eventContextExtension{}
+
+func (eventContextExtension) Init(ctx context.Context, _ *graphql.Params) context.Context { return ctx }
+func (eventContextExtension) Name() string                                                { return "eventContext" }
+func (eventContextExtension) HasResult() bool                                             { return false }
+func (eventContextExtension) GetResult(context.Context) interface{}                       { return nil }
+
+func (eventContextExtension) ParseDidStart(ctx context.Context) (context.Context, graphql.ParseFinishFunc) {
+	return ctx, func(error) {}
+}
+
+func (eventContextExtension) ValidationDidStart(ctx context.Context) (context.Context, graphql.ValidationFinishFunc) {
+	return ctx, func([]gqlerrors.FormattedError) {}
+}
+
+func (eventContextExtension) ExecutionDidStart(ctx context.Context) (context.Context, graphql.ExecutionFinishFunc) {
+	if prepare, ok := ctx.Value(eventContextKey{}).(func(context.Context) context.Context); ok {
+		ctx = prepare(ctx)
+	}
+	return ctx, func(*graphql.Result) {}
+}
+
+func (eventContextExtension) ResolveFieldDidStart(ctx context.Context, _ *graphql.ResolveInfo) (context.Context, graphql.ResolveFieldFinishFunc) {
+	return ctx, func(interface{}, error) {}
+}
@@ -0,0 +1,68 @@
+package graphql
+
+import (
+	"context"
+	"mas-diq/go-graphql/auth"
+	"mas-diq/go-graphql/events"
+	"mas-diq/go-graphql/models"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/graphql-go/graphql"
+	"gorm.io/gorm"
+)
+
+func TestSubscribePostsHidesUnreadablePosts(t *testing.T) {
+	author := &models.User{Model: gorm.Model{ID: 1}, Role: models.RoleUser}
+	posts := []models.Post{
+		{Model: gorm.Model{ID: 1}, CreatedBy: author.ID, Status: models.Draft},
+		{Model: gorm.Model{ID: 2}, CreatedBy: author.ID, Status: models.Published},
+		{Model: gorm.Model{ID: 3}, CreatedBy: author.ID, Status: models.Archived},
+	}
+	// The last event is public, so every subscriber gets it and knows the others went by.
+	last := models.Post{Model: gorm.Model{ID: 99}, CreatedBy: author.ID, Status: models.Published}
+
+	tests := []struct {
+		name   string
+		viewer *models.User
+		want   []uint
+	}{
+		{name: "anonymous", viewer: nil, want: []uint{2}},
+		{name: "author", viewer: author, want: []uint{1, 2, 3}},
+		{name: "other user", viewer: &models.User{Model: gorm.Model{ID: 2}, Role: models.RoleUser}, want: []uint{2}},
+		{name: "moderator", viewer: &models.User{Model: gorm.Model{ID: 3}, Role: models.RoleModerator}, want: []uint{1, 2, 3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(auth.WithUser(context.Background(), tt.viewer))
+			defer cancel()
+
+			subscribe := subscribePosts(func(graphql.ResolveParams, events.Event) bool { return true })
+			source, err := subscribe(graphql.ResolveParams{Context: ctx})
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, post := range append(posts, last) {
+				events.Publish(events.Event{Type: events.PostUpdated, Post: post})
+			}
+
+			var got []uint
+			for {
+				select {
+				case received := <-source.(chan interface{}):
+					post := received.(*models.Post)
+					if post.ID == last.ID {
+						if !reflect.DeepEqual(got, tt.want) {
+							t.Fatalf("received posts %v, want %v", got, tt.want)
+						}
+						return
+					}
+					got = append(got, post.ID)
+				case <-time.After(time.Second):
+					t.Fatalf("timed out after posts %v", got)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,160 @@
+package graphql
+
+import (
+	"context"
+	"fmt"
+	"mas-diq/go-graphql/loaders"
+	"mas-diq/go-graphql/models"
+
+	"github.com/graphql-go/graphql"
+	"gorm.io/gorm"
+)
+
+// tagFromSource returns the Tag a field is being resolved on, and its post
+// count when the source already carries one (the 'tags' query computes them).
+func tagFromSource(source interface{}) (*models.Tag, *int64, bool) {
+	switch tag := source.(type) {
+	case models.Tag:
+		return &tag, nil, true
+	case *models.Tag:
+		return tag, nil, true
+	case models.TagCount:
+		return &tag.Tag, &tag.PostCount, true
+	}
+	return nil, nil, false
+}
+
+// newTagType defines the GraphQL 'Tag' object.
+func newTagType(postType *graphql.Object) *graphql.Object {
+	tagField := func(get func(*models.Tag) interface{}) graphql.FieldResolveFn {
+		return func(p graphql.ResolveParams) (interface{}, error) {
+			tag, _, ok := tagFromSource(p.Source)
+			if !ok {
+				return nil, fmt.Errorf("could not cast source to Tag")
+			}
+			return get(tag), nil
+		}
+	}
+
+	return graphql.NewObject(graphql.ObjectConfig{
+		Name: "Tag",
+		Fields: graphql.Fields{
+			"id":   &graphql.Field{Type: graphql.Int, Resolve: tagField(func(t *models.Tag) interface{} { return t.ID })},
+			"name": &graphql.Field{Type: graphql.String, Resolve: tagField(func(t *models.Tag) interface{} { return t.Name })},
+			"slug": &graphql.Field{Type: graphql.String, Resolve: tagField(func(t *models.Tag) interface{} { return t.Slug })},
+			// Number of published posts with this tag, batched through the TagCounts loader.
+			"postCount": &graphql.Field{
+				Type: graphql.Int,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					tag, count, ok := tagFromSource(p.Source)
+					if !ok {
+						return nil, fmt.Errorf("could not cast source to Tag for tag.postCount resolver")
+					}
+					if count != nil {
+						return *count, nil
+					}
+					l := loaders.For(p.Context)
+					if l == nil {
+						return nil, fmt.Errorf("loaders not found in context")
+					}
+					return l.TagCounts.Load(p.Context, tag.ID)
+				},
+			},
+			// Published posts with this tag, newest first and at most
+			// loaders.MaxTagPosts, batched through the TagPosts loader.
+			"posts": &graphql.Field{
+				Type: graphql.NewList(postType),
+				Args: graphql.FieldConfigArgument{
+					"limit": limitArg(),
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					limit, err := listLimit(p)
+					if err != nil {
+						return nil, err
+					}
+					tag, _, ok := tagFromSource(p.Source)
+					if !ok {
+						return nil, fmt.Errorf("could not cast source to Tag for tag.posts resolver")
+					}
+					l := loaders.For(p.Context)
+					if l == nil {
+						return nil, fmt.Errorf("loaders not found in context")
+					}
+					posts, err := l.TagPosts.Load(p.Context, tag.ID)
+					if err != nil {
+						return nil, err
+					}
+					if limit < len(posts) {
+						posts = posts[:limit]
+					}
+					primePosts(p.Context, posts)
+					return posts, nil
+				},
+			},
+		},
+	})
+}
+
+// postTagsField is the 'tags' field on Post, batched through the PostTags loader.
+func postTagsField(tagType *graphql.Object, db *gorm.DB) *graphql.Field {
+	return &graphql.Field{
+		Type: graphql.NewList(tagType),
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			post, ok := postFromSource(p.Source)
+			if !ok {
+				return nil, fmt.Errorf("could not cast source to Post or *Post for post.tags resolver")
+			}
+			l := loaders.For(p.Context)
+			if l == nil {
+				// Fallback: Direct database query if loader is not available
+				var tags []models.Tag
+				err := db.WithContext(p.Context).Model(post).Association("Tags").Find(&tags)
+				return tags, err
+			}
+			tags, err := l.PostTags.Load(p.Context, post.ID)
+			if err != nil {
+				return nil, err
+			}
+			primeTags(p.Context, tags)
+			return tags, nil
+		},
+	}
+}
+
+// tagsQueryField is the root 'tags' query: tags with post counts, most used first.
+func tagsQueryField(tagType *graphql.Object) *graphql.Field {
+	return &graphql.Field{
+		Type: graphql.NewList(tagType),
+		Args: graphql.FieldConfigArgument{
+			"limit": limitArg(),
+		},
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			limit, err := listLimit(p)
+			if err != nil {
+				return nil, err
+			}
+			var tags []models.TagCount
+			if err := models.GetTagCounts(&tags, limit); err != nil {
+				return nil, err
+			}
+			if l := loaders.For(p.Context); l != nil {
+				for _, tag := range tags {
+					l.TagPosts.Prime(tag.ID)
+				}
+			}
+			return tags, nil
+		},
+	}
+}
+
+// primeTags queues the posts and counts of tags for batch loading.
+func primeTags(ctx context.Context, tags []models.Tag) {
+	l := loaders.For(ctx)
+	if l == nil {
+		return
+	}
+	for _, tag := range tags {
+		l.TagCounts.Prime(tag.ID)
+		l.TagPosts.Prime(tag.ID)
+	}
+}
@@ -0,0 +1,99 @@
+package graphql
+
+import (
+	"context"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/ast"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("mas-diq/go-graphql/graphql")
+
+// startExecuteSpan starts the span of the execution of an operation, named
+// and typed after it.
+func startExecuteSpan(ctx context.Context, doc *ast.Document, req *Request) (context.Context, trace.Span) {
+	ctx, span := tracer.Start(ctx, "graphql.execute")
+	if operation := findOperation(doc, req.OperationName); operation != nil {
+		span.SetAttributes(semconv.GraphqlOperationTypeKey.String(operation.Operation))
+		if operation.Name != nil {
+			span.SetAttributes(semconv.GraphqlOperationName(operation.Name.Value))
+		}
+	}
+	return ctx, span
+}
+
+// endSpan ends a span of a GraphQL phase, failed when it had errors.
+func endSpan(span trace.Span, errs []gqlerrors.FormattedError) {
+	if len(errs) > 0 {
+		span.SetStatus(codes.Error, errs[0].Message)
+	}
+	span.End()
+}
+
+// tracingExtension gives the fields that have a resolver of their own a span,
+// named Type.field, so the queries and loads they make are found under them.
+//
+// graphql-go calls the finish function of a field as soon as its resolver
+// returned, before its children are resolved, and carries the context an
+// extension returns over to the next fields. Field spans are hence all started
+// from the context of the execution, as siblings, rather than nested.
+type tracingExtension struct{}
+
+var _ graphql.Extension = tracingExtension{}
+
+type executionContextKey struct{}
+
+// execution holds the context field spans are started from. It refers to
+// itself, so field contexts keep pointing to it.
+type execution struct{ ctx context.Context }
+
+func (tracingExtension) Init(ctx context.Context, _ *graphql.Params) context.Context { return ctx }
+func (tracingExtension) Name() string                                                { return "tracing" }
+func (tracingExtension) HasResult() bool                                             { return false }
+func (tracingExtension) GetResult(context.Context) interface{}                       { return nil }
+
+func (tracingExtension) ParseDidStart(ctx context.Context) (context.Context, graphql.ParseFinishFunc) {
+	return ctx, func(error) {}
+}
+
+func (tracingExtension) ValidationDidStart(ctx context.Context) (context.Context, graphql.ValidationFinishFunc) {
+	return ctx, func([]gqlerrors.FormattedError) {}
+}
+
+// ExecutionDidStart remembers the context of the execution. Subscription
+// events, which graphql-go executes on its own, get a span of their own here;
+// other operations have theirs from executeDocument.
+func (tracingExtension) ExecutionDidStart(ctx context.Context) (context.Context, graphql.ExecutionFinishFunc) {
+	finish := func(*graphql.Result) {}
+	if ctx.Value(eventContextKey{}) != nil {
+		var span trace.Span
+		ctx, span = tracer.Start(ctx, "graphql.event", trace.WithAttributes(semconv.GraphqlOperationTypeSubscription))
+		finish = func(result *graphql.Result) { endSpan(span, result.Errors) }
+	}
+	e := &execution{}
+	ctx = context.WithValue(ctx, executionContextKey{}, e)
+	e.ctx = ctx
+	return ctx, finish
+}
+
+func (tracingExtension) ResolveFieldDidStart(ctx context.Context, info *graphql.ResolveInfo) (context.Context, graphql.ResolveFieldFinishFunc) {
+	field, ok := resolvedField(info)
+	e, started := ctx.Value(executionContextKey{}).(*execution)
+	if !ok || !started {
+		return ctx, func(interface{}, error) {}
+	}
+
+	ctx, span := tracer.Start(e.ctx, field)
+	return ctx, func(_ interface{}, err error) {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}
+}
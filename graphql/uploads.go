@@ -0,0 +1,132 @@
+package graphql
+
+import (
+	"fmt"
+	"mas-diq/go-graphql/auth"
+	"mas-diq/go-graphql/loaders"
+	"mas-diq/go-graphql/models"
+	"mas-diq/go-graphql/storage"
+	"mas-diq/go-graphql/uploads"
+	"mime/multipart"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+// uploadScalar is a file sent with the GraphQL multipart request spec. The
+// handler puts the file parts into the variables, so it only works as a variable.
+var uploadScalar = graphql.NewScalar(graphql.ScalarConfig{
+	Name:        "Upload",
+	Description: "A file sent as part of a multipart request.",
+	Serialize: func(value interface{}) interface{} {
+		return nil
+	},
+	ParseValue: func(value interface{}) interface{} {
+		if file, ok := value.(*multipart.FileHeader); ok {
+			return file
+		}
+		return nil
+	},
+	ParseLiteral: func(valueAST ast.Value) interface{} {
+		return nil
+	},
+})
+
+// imageValue is what the 'Image' type resolves from. Width, height and the
+// thumbnail are only known for uploaded images, not for external URLs.
+type imageValue struct {
+	Key       *string     `json:"key"`
+	URL       string      `json:"url"`
+	Width     *int        `json:"width"`
+	Height    *int        `json:"height"`
+	Thumbnail *imageValue `json:"thumbnail"`
+}
+
+func newUploadImage(upload *models.Upload) *imageValue {
+	return &imageValue{
+		Key:    &upload.Key,
+		URL:    storage.Default.URL(upload.Key),
+		Width:  &upload.Width,
+		Height: &upload.Height,
+		Thumbnail: &imageValue{
+			URL:    storage.Default.URL(upload.ThumbnailKey),
+			Width:  &upload.ThumbnailWidth,
+			Height: &upload.ThumbnailHeight,
+		},
+	}
+}
+
+// imageType defines the GraphQL 'Image' object.
+var imageType = func() *graphql.Object {
+	image := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Image",
+		Fields: graphql.Fields{
+			"key":    &graphql.Field{Type: graphql.String}, // Storage key of an uploaded image, to set as a post's image
+			"url":    &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"width":  &graphql.Field{Type: graphql.Int},
+			"height": &graphql.Field{Type: graphql.Int},
+		},
+	})
+	image.AddFieldConfig("thumbnail", &graphql.Field{Type: image})
+	return image
+}()
+
+// postImageField resolves 'Post.image'. Posts keep either the key of an
+// upload or an external URL in their image column.
+func postImageField() *graphql.Field {
+	return &graphql.Field{
+		Type: imageType,
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			post, ok := postFromSource(p.Source)
+			if !ok {
+				return nil, fmt.Errorf("could not cast source to Post or *Post for post.image resolver")
+			}
+			if post.Image == "" {
+				return nil, nil
+			}
+			if l := loaders.For(p.Context); l != nil {
+				upload, err := l.Uploads.Load(p.Context, post.Image)
+				if err != nil {
+					return nil, err
+				}
+				if upload != nil {
+					return newUploadImage(upload), nil
+				}
+			}
+			return &imageValue{URL: post.Image}, nil
+		},
+	}
+}
+
+// uploadImageField is the 'uploadImage' mutation.
+func uploadImageField() *graphql.Field {
+	return &graphql.Field{
+		Type:        imageType,
+		Description: "Stores an image and its thumbnail. Use the returned key as a post's image.",
+		Args: graphql.FieldConfigArgument{
+			"file": &graphql.ArgumentConfig{Type: graphql.NewNonNull(uploadScalar)},
+		},
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			actor := auth.UserFromContext(p.Context)
+			if actor == nil {
+				return nil, newCodedError("UNAUTHENTICATED", "Authentication required")
+			}
+			header, _ := p.Args["file"].(*multipart.FileHeader)
+			if header == nil {
+				return nil, invalidArgument(p.Context, "file", "required", "")
+			}
+
+			file, err := header.Open()
+			if err != nil {
+				return nil, err
+			}
+			defer file.Close()
+
+			upload, err := uploads.SaveImage(p.Context, storage.Default, file, actor.ID)
+			if err != nil {
+				return nil, translateError(p.Context, err)
+			}
+			return newUploadImage(upload), nil
+		},
+	}
+}
@@ -0,0 +1,399 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+// The graphql-transport-ws protocol, as implemented by the graphql-ws library:
+// https://github.com/enisdenjo/graphql-ws/blob/master/PROTOCOL.md
+const (
+	transportWSProtocol = "graphql-transport-ws"
+
+	// connectionInitTimeout is how long a client has to send connection_init.
+	connectionInitTimeout = 10 * time.Second
+	// writeTimeout bounds the time to write one message to a client.
+	writeTimeout = 10 * time.Second
+	// drainInterval is how often Shutdown checks for running operations.
+	drainInterval = 50 * time.Millisecond
+)
+
+// Close codes of the protocol.
+const (
+	closeBadRequest          = 4400
+	closeUnauthorized        = 4401
+	closeForbidden           = 4403
+	closeSubprotocolNotValid = 4406
+	closeInitTimeout         = 4408
+	closeSubscriberExists    = 4409
+	closeTooManyInits        = 4429
+)
+
+type wsMessage struct {
+	ID      string          `json:"id,omitempty"`
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// wsConnection is a WebSocket client and the operations it has running.
+type wsConnection struct {
+	h    *Handler
+	conn *websocket.Conn
+	// ctx is the context of the operations, authenticated by connection_init.
+	ctx context.Context
+
+	writeMu sync.Mutex
+
+	mu           sync.Mutex
+	acknowledged bool
+	operations   map[string]*wsOperation
+}
+
+// wsConnections tracks the WebSocket connections of a Handler and their
+// running operations, so they can be drained on shutdown.
+type wsConnections struct {
+	mu          sync.Mutex
+	draining    bool
+	connections map[*wsConnection]struct{}
+	operations  int
+}
+
+// add registers a connection. It reports false when draining.
+func (t *wsConnections) add(c *wsConnection) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.draining {
+		return false
+	}
+	if t.connections == nil {
+		t.connections = make(map[*wsConnection]struct{})
+	}
+	t.connections[c] = struct{}{}
+	return true
+}
+
+func (t *wsConnections) remove(c *wsConnection) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.connections, c)
+}
+
+// startOperation counts a running operation. It reports false when draining.
+func (t *wsConnections) startOperation() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.draining {
+		return false
+	}
+	t.operations++
+	return true
+}
+
+func (t *wsConnections) endOperation() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.operations--
+}
+
+// drain refuses new connections and operations, and reports whether
+// operations are still running.
+func (t *wsConnections) drain() (busy bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.draining = true
+	return t.operations > 0
+}
+
+// closeAll closes every connection with 1001 Going Away.
+func (t *wsConnections) closeAll() {
+	t.mu.Lock()
+	connections := make([]*wsConnection, 0, len(t.connections))
+	for c := range t.connections {
+		connections = append(connections, c)
+	}
+	t.mu.Unlock()
+
+	for _, c := range connections {
+		c.close(websocket.CloseGoingAway, "Server is shutting down")
+	}
+}
+
+var errShuttingDown = newCodedError("UNAVAILABLE", "Server is shutting down")
+
+// Shutdown drains the WebSocket connections: new connections and operations
+// are refused, running ones are waited for, then every connection is closed
+// with 1001 Going Away so clients reconnect to another instance. Subscriptions
+// run until their events end, so close the events bus first. When ctx ends
+// first, connections are closed with operations still running.
+func (h *Handler) Shutdown(ctx context.Context) error {
+	defer h.connections.closeAll()
+
+	ticker := time.NewTicker(drainInterval)
+	defer ticker.Stop()
+	for h.connections.drain() {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+	return nil
+}
+
+// wsOperation is a running operation. Clients may reuse the id of a
+// completed operation, so an operation only unregisters itself.
+type wsOperation struct {
+	cancel context.CancelFunc
+}
+
+// serveWebSocket upgrades the request and serves operations over it until the
+// client goes away.
+func (h *Handler) serveWebSocket(w http.ResponseWriter, r *http.Request) {
+	upgrader := websocket.Upgrader{Subprotocols: []string{transportWSProtocol}, CheckOrigin: h.CheckOrigin}
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// The upgrader already replied with an error.
+		return
+	}
+	defer conn.Close()
+	if h.MaxRequestBytes > 0 {
+		conn.SetReadLimit(h.MaxRequestBytes)
+	}
+
+	if conn.Subprotocol() != transportWSProtocol {
+		closeWebSocket(conn, closeSubprotocolNotValid, "Subprotocol not acceptable")
+		return
+	}
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	c := &wsConnection{h: h, conn: conn, ctx: ctx, operations: make(map[string]*wsOperation)}
+	if !h.connections.add(c) {
+		closeWebSocket(conn, websocket.CloseGoingAway, "Server is shutting down")
+		return
+	}
+	defer h.connections.remove(c)
+
+	initTimer := time.AfterFunc(connectionInitTimeout, func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if !c.acknowledged {
+			c.close(closeInitTimeout, "Connection initialisation timeout")
+		}
+	})
+	defer initTimer.Stop()
+
+	for {
+		var msg wsMessage
+		if err := conn.ReadJSON(&msg); err != nil {
+			var syntaxErr *json.SyntaxError
+			var typeErr *json.UnmarshalTypeError
+			if errors.As(err, &syntaxErr) || errors.As(err, &typeErr) {
+				c.close(closeBadRequest, "Invalid message received")
+			}
+			return
+		}
+		if !c.handle(msg) {
+			return
+		}
+	}
+}
+
+// handle processes a message from the client. It reports false when the
+// connection was closed.
+func (c *wsConnection) handle(msg wsMessage) bool {
+	switch msg.Type {
+	case "connection_init":
+		return c.init(msg.Payload)
+	case "ping":
+		c.write(wsMessage{Type: "pong"})
+	case "pong":
+	case "subscribe":
+		c.mu.Lock()
+		acknowledged := c.acknowledged
+		_, exists := c.operations[msg.ID]
+		c.mu.Unlock()
+		switch {
+		case !acknowledged:
+			return c.close(closeUnauthorized, "Unauthorized")
+		case msg.ID == "":
+			return c.close(closeBadRequest, "Invalid message received")
+		case exists:
+			return c.close(closeSubscriberExists, fmt.Sprintf("Subscriber for %s already exists", msg.ID))
+		}
+		var req Request
+		if err := json.Unmarshal(msg.Payload, &req); err != nil {
+			return c.close(closeBadRequest, "Invalid message received")
+		}
+		c.start(msg.ID, &req)
+	case "complete":
+		c.stop(msg.ID)
+	default:
+		return c.close(closeBadRequest, "Invalid message received")
+	}
+	return true
+}
+
+// init authenticates the connection with the payload of connection_init.
+func (c *wsConnection) init(payload json.RawMessage) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.acknowledged {
+		return c.close(closeTooManyInits, "Too many initialisation requests")
+	}
+
+	var params map[string]interface{}
+	if len(payload) > 0 {
+		if err := json.Unmarshal(payload, &params); err != nil {
+			return c.close(closeBadRequest, "Invalid message received")
+		}
+	}
+	if c.h.Authenticate != nil {
+		ctx, err := c.h.Authenticate(c.ctx, params)
+		if err != nil {
+			return c.close(closeForbidden, "Forbidden")
+		}
+		c.ctx = ctx
+	}
+	c.acknowledged = true
+	c.write(wsMessage{Type: "connection_ack"})
+	return true
+}
+
+// start runs an operation in the background. Subscriptions send a result per
+// event until either side completes them; queries and mutations send one.
+func (c *wsConnection) start(id string, req *Request) {
+	if !c.h.connections.startOperation() {
+		c.sendError(id, []gqlerrors.FormattedError{formatError(errShuttingDown)})
+		return
+	}
+	ctx, cancel := context.WithCancel(c.ctx)
+	operation := &wsOperation{cancel: cancel}
+	c.mu.Lock()
+	c.operations[id] = operation
+	c.mu.Unlock()
+
+	go func() {
+		defer func() {
+			c.mu.Lock()
+			if c.operations[id] == operation {
+				delete(c.operations, id)
+			}
+			c.mu.Unlock()
+			cancel()
+			c.h.connections.endOperation()
+		}()
+
+		if errs := c.run(ctx, id, req); errs != nil {
+			c.sendError(id, errs)
+			return
+		}
+		// The client doesn't expect complete for operations it completed itself.
+		if ctx.Err() == nil {
+			c.write(wsMessage{ID: id, Type: "complete"})
+		}
+	}()
+}
+
+// run executes an operation, sending its results. It returns the errors of
+// operations that couldn't start, which end with an error message instead.
+func (c *wsConnection) run(ctx context.Context, id string, req *Request) []gqlerrors.FormattedError {
+	save, err := c.h.resolvePersisted(ctx, req)
+	if err != nil {
+		return []gqlerrors.FormattedError{formatError(err)}
+	}
+	doc, errs := c.h.prepare(ctx, req, save)
+	if errs != nil {
+		return errs
+	}
+
+	if operationType(doc, req.OperationName) != ast.OperationTypeSubscription {
+		c.sendNext(id, c.h.executeDocument(c.h.operationContext(ctx), req, doc))
+		return nil
+	}
+
+	results := graphql.ExecuteSubscription(graphql.ExecuteParams{
+		Schema:        c.h.Schema,
+		AST:           doc,
+		OperationName: req.OperationName,
+		Args:          req.Variables,
+		Context:       withEventContext(ctx, c.h.OperationContext),
+	})
+	first := true
+	// Drain the channel even after the operation was completed, so the
+	// goroutine of ExecuteSubscription can finish.
+	for result := range results {
+		if ctx.Err() != nil || errs != nil {
+			continue
+		}
+		// Errors before the first event mean the subscription couldn't start.
+		if first && result.Data == nil && len(result.Errors) > 0 {
+			errs = result.Errors
+			continue
+		}
+		first = false
+		c.sendNext(id, result)
+	}
+	return errs
+}
+
+// stop cancels a running operation when the client completes it.
+func (c *wsConnection) stop(id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if operation, ok := c.operations[id]; ok {
+		operation.cancel()
+		delete(c.operations, id)
+	}
+}
+
+func (h *Handler) operationContext(ctx context.Context) context.Context {
+	if h.OperationContext == nil {
+		return ctx
+	}
+	return h.OperationContext(ctx)
+}
+
+func (c *wsConnection) sendNext(id string, result *graphql.Result) {
+	payload, _ := json.Marshal(result)
+	c.write(wsMessage{ID: id, Type: "next", Payload: payload})
+}
+
+func (c *wsConnection) sendError(id string, errs []gqlerrors.FormattedError) {
+	payload, _ := json.Marshal(errs)
+	c.write(wsMessage{ID: id, Type: "error", Payload: payload})
+}
+
+func (c *wsConnection) write(msg wsMessage) {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	c.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	if err := c.conn.WriteJSON(msg); err != nil {
+		logger.WarnContext(c.ctx, "Failed to write to WebSocket", "error", err)
+	}
+}
+
+// close ends the connection with a protocol close code. It returns false so
+// handle can return its result.
+func (c *wsConnection) close(code int, reason string) bool {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	closeWebSocket(c.conn, code, reason)
+	return false
+}
+
+func closeWebSocket(conn *websocket.Conn, code int, reason string) {
+	message := websocket.FormatCloseMessage(code, reason)
+	conn.WriteControl(websocket.CloseMessage, message, time.Now().Add(writeTimeout))
+	conn.Close()
+}
@@ -0,0 +1,97 @@
+// Package health tells load balancers and orchestrators whether the instance
+// can take requests.
+package health
+
+import (
+	"context"
+	"errors"
+	"mas-diq/go-graphql/logging"
+	"mas-diq/go-graphql/models"
+	"sync/atomic"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var logger = logging.For("health")
+
+// checkTimeout bounds each readiness check.
+const checkTimeout = 2 * time.Second
+
+var (
+	draining atomic.Bool
+	// migrated is set once the schema was found up to date; the check isn't
+	// repeated after that, a running instance doesn't lose its columns.
+	migrated atomic.Bool
+)
+
+// Drain makes the instance report itself as not ready, so it stops getting
+// new traffic while it shuts down.
+func Drain() {
+	draining.Store(true)
+}
+
+// Draining reports whether Drain was called.
+func Draining() bool {
+	return draining.Load()
+}
+
+// Check is the result of one readiness check: "ok", "unavailable", or
+// "pending" for migrations. Errors are logged rather than shown, the
+// endpoint being public.
+type Check struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+}
+
+// Ready checks that the database answers and its schema is up to date. It
+// reports whether all checks passed, and each of their results.
+func Ready(ctx context.Context, db *gorm.DB) (bool, []Check) {
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+
+	checks := []Check{
+		{Name: "database", Status: status(ctx, "database", ping(ctx, db))},
+		{Name: "migrations", Status: status(ctx, "migrations", checkMigrations(ctx, db))},
+	}
+	if Draining() {
+		checks = append(checks, Check{Name: "shutdown", Status: "draining"})
+	}
+	ready := true
+	for _, check := range checks {
+		ready = ready && check.Status == "ok"
+	}
+	return ready, checks
+}
+
+func ping(ctx context.Context, db *gorm.DB) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
+}
+
+func checkMigrations(ctx context.Context, db *gorm.DB) error {
+	if migrated.Load() {
+		return nil
+	}
+	if err := models.CheckMigrations(db.WithContext(ctx)); err != nil {
+		return err
+	}
+	migrated.Store(true)
+	return nil
+}
+
+func status(ctx context.Context, check string, err error) string {
+	switch {
+	case err == nil:
+		return "ok"
+	case errors.Is(err, models.ErrPendingMigrations):
+		logger.WarnContext(ctx, "Not ready", "check", check, "error", err)
+		return "pending"
+	default:
+		logger.ErrorContext(ctx, "Not ready", "check", check, "error", err)
+		return "unavailable"
+	}
+}
@@ -0,0 +1,101 @@
+package loaders
+
+import (
+	"context"
+	"mas-diq/go-graphql/metrics"
+	"sync"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("mas-diq/go-graphql/loaders")
+
+// startBatchSpan starts the span of a batch, named loader.<name>.
+func startBatchSpan(ctx context.Context, name string, size int) (context.Context, trace.Span) {
+	return tracer.Start(ctx, "loader."+name, trace.WithAttributes(
+		attribute.String("loader.name", name),
+		attribute.Int("loader.batch_size", size),
+	))
+}
+
+// endBatchSpan ends the span of a batch, failed when the fetch failed.
+func endBatchSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// BatchFunc fetches the values for a set of keys in one go. Keys without a
+// value may be left out of the returned map.
+type BatchFunc[K comparable, V any] func(ctx context.Context, keys []K) (map[K]V, error)
+
+// Loader caches values per request and fetches them in batches.
+// Resolvers of a list Prime the keys their children will ask for, so the
+// first child's Load fetches the whole list at once instead of one row per item.
+type Loader[K comparable, V any] struct {
+	name    string // Label of the loader metrics
+	fetch   BatchFunc[K, V]
+	cache   map[K]V
+	pending map[K]struct{}
+	mutex   sync.Mutex
+}
+
+func NewLoader[K comparable, V any](name string, fetch BatchFunc[K, V]) *Loader[K, V] {
+	return &Loader[K, V]{
+		name:    name,
+		fetch:   fetch,
+		cache:   make(map[K]V),
+		pending: make(map[K]struct{}),
+	}
+}
+
+// Prime queues keys to be fetched with the next batch.
+func (l *Loader[K, V]) Prime(keys ...K) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	for _, key := range keys {
+		if _, cached := l.cache[key]; !cached {
+			l.pending[key] = struct{}{}
+		}
+	}
+}
+
+// Load returns the value for key, fetching it together with all primed keys
+// when it isn't cached yet. Missing keys yield the zero value.
+func (l *Loader[K, V]) Load(ctx context.Context, key K) (V, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if value, cached := l.cache[key]; cached {
+		metrics.LoaderLoads.WithLabelValues(l.name, "hit").Inc()
+		return value, nil
+	}
+	metrics.LoaderLoads.WithLabelValues(l.name, "miss").Inc()
+
+	l.pending[key] = struct{}{}
+	keys := make([]K, 0, len(l.pending))
+	for k := range l.pending {
+		keys = append(keys, k)
+	}
+	metrics.LoaderBatchSize.WithLabelValues(l.name).Observe(float64(len(keys)))
+
+	ctx, span := startBatchSpan(ctx, l.name, len(keys))
+	values, err := l.fetch(ctx, keys)
+	endBatchSpan(span, err)
+	if err != nil {
+		var zero V
+		return zero, err
+	}
+	// Remember misses too, so they aren't fetched again.
+	for _, k := range keys {
+		l.cache[k] = values[k]
+		delete(l.pending, k)
+	}
+	return l.cache[key], nil
+}
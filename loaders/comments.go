@@ -0,0 +1,44 @@
+package loaders
+
+import (
+	"context"
+	"mas-diq/go-graphql/models"
+
+	"gorm.io/gorm"
+)
+
+// NewCommentCountLoader counts the approved comments (replies included) of posts, keyed by post ID.
+func NewCommentCountLoader(db *gorm.DB) *Loader[uint, int64] {
+	return NewLoader("commentCounts", func(ctx context.Context, postIDs []uint) (map[uint]int64, error) {
+		return countComments(ctx, db, "post_id", postIDs)
+	})
+}
+
+// NewReplyCountLoader counts the approved direct replies of comments, keyed by comment ID.
+func NewReplyCountLoader(db *gorm.DB) *Loader[uint, int64] {
+	return NewLoader("replyCounts", func(ctx context.Context, commentIDs []uint) (map[uint]int64, error) {
+		return countComments(ctx, db, "parent_id", commentIDs)
+	})
+}
+
+func countComments(ctx context.Context, db *gorm.DB, column string, ids []uint) (map[uint]int64, error) {
+	var rows []struct {
+		RefID uint
+		Total int64
+	}
+	err := db.WithContext(ctx).
+		Model(&models.Comment{}).
+		Select(column+" AS ref_id, COUNT(*) AS total").
+		Where(column+" IN ? AND status = ?", ids, models.CommentApproved).
+		Group(column).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[uint]int64, len(rows))
+	for _, row := range rows {
+		result[row.RefID] = row.Total
+	}
+	return result, nil
+}
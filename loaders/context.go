@@ -0,0 +1,47 @@
+package loaders
+
+import (
+	"context"
+	"mas-diq/go-graphql/models"
+
+	"gorm.io/gorm"
+)
+
+type contextKey string
+
+const loadersKey contextKey = "loaders"
+
+// Loaders bundles the per-request DataLoaders used by the GraphQL resolvers.
+type Loaders struct {
+	Users         *UserLoader
+	PostTags      *Loader[uint, []models.Tag]
+	TagPosts      *Loader[uint, []models.Post]
+	TagCounts     *Loader[uint, int64]
+	CommentCounts *Loader[uint, int64]
+	ReplyCounts   *Loader[uint, int64]
+	Uploads       *Loader[string, *models.Upload]
+}
+
+// New creates a fresh set of loaders; create one per request so caches don't leak between users.
+func New(db *gorm.DB) *Loaders {
+	return &Loaders{
+		Users:         NewUserLoader(db),
+		PostTags:      NewPostTagsLoader(db),
+		TagPosts:      NewTagPostsLoader(db),
+		TagCounts:     NewTagCountLoader(db),
+		CommentCounts: NewCommentCountLoader(db),
+		ReplyCounts:   NewReplyCountLoader(db),
+		Uploads:       NewUploadLoader(db),
+	}
+}
+
+// WithLoaders stores the loaders in the request context.
+func WithLoaders(ctx context.Context, l *Loaders) context.Context {
+	return context.WithValue(ctx, loadersKey, l)
+}
+
+// For returns the loaders of the request, or nil outside of a request.
+func For(ctx context.Context) *Loaders {
+	l, _ := ctx.Value(loadersKey).(*Loaders)
+	return l
+}
@@ -2,6 +2,7 @@ package loaders
 
 import (
 	"context"
+	"mas-diq/go-graphql/metrics"
 	"mas-diq/go-graphql/models"
 	"sync"
 
@@ -9,15 +10,29 @@ import (
 )
 
 type UserLoader struct {
-	db    *gorm.DB
-	cache map[uint]*models.User
-	mutex sync.Mutex
+	db      *gorm.DB
+	cache   map[uint]*models.User
+	pending map[uint]struct{}
+	mutex   sync.Mutex
 }
 
 func NewUserLoader(db *gorm.DB) *UserLoader {
 	return &UserLoader{
-		db:    db,
-		cache: make(map[uint]*models.User),
+		db:      db,
+		cache:   make(map[uint]*models.User),
+		pending: make(map[uint]struct{}),
+	}
+}
+
+// Prime queues user IDs to be fetched together with the next Load.
+func (l *UserLoader) Prime(ids ...uint) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	for _, id := range ids {
+		if _, exists := l.cache[id]; !exists {
+			l.pending[id] = struct{}{}
+		}
 	}
 }
 
@@ -25,23 +40,36 @@ func (l *UserLoader) Load(ctx context.Context, ids []uint) ([]*models.User, erro
 	l.mutex.Lock()
 	defer l.mutex.Unlock()
 
-	// Find uncached IDs
+	// Find uncached IDs, plus the primed ones
 	var uncached []uint
 	for _, id := range ids {
 		if _, exists := l.cache[id]; !exists {
-			uncached = append(uncached, id)
+			l.pending[id] = struct{}{}
+			metrics.LoaderLoads.WithLabelValues("users", "miss").Inc()
+		} else {
+			metrics.LoaderLoads.WithLabelValues("users", "hit").Inc()
 		}
 	}
+	for id := range l.pending {
+		uncached = append(uncached, id)
+	}
 
 	// Fetch uncached users
 	if len(uncached) > 0 {
+		metrics.LoaderBatchSize.WithLabelValues("users").Observe(float64(len(uncached)))
+		ctx, span := startBatchSpan(ctx, "users", len(uncached))
 		var users []*models.User
-		if err := l.db.Find(&users, uncached).Error; err != nil {
+		err := l.db.WithContext(ctx).Find(&users, uncached).Error
+		endBatchSpan(span, err)
+		if err != nil {
 			return nil, err
 		}
 		for _, user := range users {
 			l.cache[user.ID] = user
 		}
+		for _, id := range uncached {
+			delete(l.pending, id)
+		}
 	}
 
 	// Return results in requested order
@@ -0,0 +1,93 @@
+package loaders
+
+import (
+	"context"
+	"mas-diq/go-graphql/models"
+
+	"gorm.io/gorm"
+)
+
+// NewPostTagsLoader loads the tags of posts, keyed by post ID.
+func NewPostTagsLoader(db *gorm.DB) *Loader[uint, []models.Tag] {
+	return NewLoader("postTags", func(ctx context.Context, postIDs []uint) (map[uint][]models.Tag, error) {
+		var rows []struct {
+			models.Tag
+			PostID uint
+		}
+		err := db.WithContext(ctx).
+			Table("tags").
+			Select("tags.*, post_tags.post_id").
+			Joins("JOIN post_tags ON post_tags.tag_id = tags.id").
+			Where("post_tags.post_id IN ?", postIDs).
+			Order("tags.name").
+			Scan(&rows).Error
+		if err != nil {
+			return nil, err
+		}
+
+		result := make(map[uint][]models.Tag, len(postIDs))
+		for _, row := range rows {
+			result[row.PostID] = append(result[row.PostID], row.Tag)
+		}
+		return result, nil
+	})
+}
+
+// MaxTagPosts is the most posts the TagPosts loader returns per tag.
+const MaxTagPosts = 100
+
+// NewTagPostsLoader loads the published posts carrying a tag, newest first
+// and at most MaxTagPosts of them, keyed by tag ID.
+func NewTagPostsLoader(db *gorm.DB) *Loader[uint, []models.Post] {
+	return NewLoader("tagPosts", func(ctx context.Context, tagIDs []uint) (map[uint][]models.Post, error) {
+		ranked := db.
+			Table("posts").
+			Select("posts.*, post_tags.tag_id, ROW_NUMBER() OVER (PARTITION BY post_tags.tag_id ORDER BY posts.id DESC) AS post_rank").
+			Joins("JOIN post_tags ON post_tags.post_id = posts.id").
+			Where("post_tags.tag_id IN ? AND posts.status = ? AND posts.deleted_at IS NULL", tagIDs, models.Published)
+		var rows []struct {
+			models.Post
+			TagID uint
+		}
+		err := db.WithContext(ctx).
+			Table("(?) AS ranked", ranked).
+			Where("post_rank <= ?", MaxTagPosts).
+			Order("tag_id, id DESC").
+			Scan(&rows).Error
+		if err != nil {
+			return nil, err
+		}
+
+		result := make(map[uint][]models.Post, len(tagIDs))
+		for _, row := range rows {
+			result[row.TagID] = append(result[row.TagID], row.Post)
+		}
+		return result, nil
+	})
+}
+
+// NewTagCountLoader counts the published posts of tags, keyed by tag ID.
+func NewTagCountLoader(db *gorm.DB) *Loader[uint, int64] {
+	return NewLoader("tagCounts", func(ctx context.Context, tagIDs []uint) (map[uint]int64, error) {
+		var rows []struct {
+			TagID     uint
+			PostCount int64
+		}
+		err := db.WithContext(ctx).
+			Table("post_tags").
+			Select("post_tags.tag_id, COUNT(*) AS post_count").
+			Joins("JOIN posts ON posts.id = post_tags.post_id AND posts.deleted_at IS NULL").
+			Where("post_tags.tag_id IN ? AND posts.status = ?", tagIDs, models.Published).
+			Group("post_tags.tag_id").
+			Scan(&rows).Error
+		if err != nil {
+			return nil, err
+		}
+
+		result := make(map[uint]int64, len(rows))
+		for _, row := range rows {
+			result[row.TagID] = row.PostCount
+		}
+		return result, nil
+	})
+}
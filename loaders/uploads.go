@@ -0,0 +1,25 @@
+package loaders
+
+import (
+	"context"
+	"mas-diq/go-graphql/models"
+
+	"gorm.io/gorm"
+)
+
+// NewUploadLoader loads uploads by storage key, the value posts keep in Post.Image.
+func NewUploadLoader(db *gorm.DB) *Loader[string, *models.Upload] {
+	return NewLoader("uploads", func(ctx context.Context, keys []string) (map[string]*models.Upload, error) {
+		var uploads []models.Upload
+		// A map condition lets GORM quote "key", a reserved word in MySQL.
+		if err := db.WithContext(ctx).Where(map[string]interface{}{"key": keys}).Find(&uploads).Error; err != nil {
+			return nil, err
+		}
+
+		result := make(map[string]*models.Upload, len(uploads))
+		for i := range uploads {
+			result[uploads[i].Key] = &uploads[i]
+		}
+		return result, nil
+	})
+}
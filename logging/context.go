@@ -0,0 +1,52 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+)
+
+type requestIDKey struct{}
+
+// WithRequestID returns a copy of ctx carrying the ID of the request it serves.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the request ID of ctx, empty outside of requests.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+type annotationsKey struct{}
+
+// annotations are attributes added to the access log line of a request by
+// the code serving it.
+type annotations struct {
+	mu    sync.Mutex
+	attrs []slog.Attr
+}
+
+// WithAnnotations returns a copy of ctx that collects Annotate calls, and a
+// function returning what they added.
+func WithAnnotations(ctx context.Context) (context.Context, func() []slog.Attr) {
+	a := &annotations{}
+	return context.WithValue(ctx, annotationsKey{}, a), func() []slog.Attr {
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		return a.attrs
+	}
+}
+
+// Annotate adds attributes to the access log line of the request of ctx,
+// e.g. the GraphQL operation it ran. It does nothing outside of requests.
+func Annotate(ctx context.Context, attrs ...slog.Attr) {
+	a, ok := ctx.Value(annotationsKey{}).(*annotations)
+	if !ok {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.attrs = append(a.attrs, attrs...)
+}
@@ -0,0 +1,87 @@
+package logging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/utils"
+)
+
+var gormLog = For("gorm")
+
+// Gorm returns a GORM logger writing to the "gorm" package logger: failed
+// queries as errors, queries slower than slowQuery as warnings and the others
+// at debug level. Queries run with a request context (db.WithContext) carry
+// its request ID.
+func Gorm(slowQuery time.Duration) logger.Interface {
+	return &gormLogger{slowQuery: slowQuery}
+}
+
+type gormLogger struct {
+	slowQuery time.Duration
+	// mode is set by LogMode: Silent drops everything, Info (what db.Debug()
+	// asks for) logs every query at info level.
+	mode logger.LogLevel
+}
+
+func (l *gormLogger) LogMode(mode logger.LogLevel) logger.Interface {
+	copied := *l
+	copied.mode = mode
+	return &copied
+}
+
+func (l *gormLogger) Info(ctx context.Context, msg string, args ...interface{}) {
+	l.log(ctx, slog.LevelInfo, msg, args)
+}
+
+func (l *gormLogger) Warn(ctx context.Context, msg string, args ...interface{}) {
+	l.log(ctx, slog.LevelWarn, msg, args)
+}
+
+func (l *gormLogger) Error(ctx context.Context, msg string, args ...interface{}) {
+	l.log(ctx, slog.LevelError, msg, args)
+}
+
+func (l *gormLogger) log(ctx context.Context, level slog.Level, msg string, args []interface{}) {
+	if l.mode == logger.Silent {
+		return
+	}
+	gormLog.Log(ctx, level, fmt.Sprintf(msg, args...), slog.String("source", utils.FileWithLineNum()))
+}
+
+func (l *gormLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	if l.mode == logger.Silent {
+		return
+	}
+	elapsed := time.Since(begin)
+
+	level, msg := slog.LevelDebug, "Query"
+	switch {
+	case err != nil && !errors.Is(err, gorm.ErrRecordNotFound):
+		level, msg = slog.LevelError, "Query failed"
+	case l.slowQuery > 0 && elapsed > l.slowQuery:
+		level, msg = slog.LevelWarn, "Slow query"
+	case l.mode == logger.Info:
+		level = slog.LevelInfo
+	}
+	if !gormLog.Enabled(ctx, level) {
+		return
+	}
+
+	sql, rows := fc()
+	attrs := []slog.Attr{
+		slog.String("sql", sql),
+		slog.Int64("rows", rows),
+		slog.Float64("duration_ms", float64(elapsed.Microseconds())/1000),
+		slog.String("source", utils.FileWithLineNum()),
+	}
+	if level == slog.LevelError {
+		attrs = append(attrs, slog.Any("error", err))
+	}
+	gormLog.LogAttrs(ctx, level, msg, attrs...)
+}
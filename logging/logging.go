@@ -0,0 +1,110 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"mas-diq/go-graphql/config"
+	"os"
+	"strings"
+	"sync/atomic"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// settings is what Setup configured: where records go and the levels.
+type settings struct {
+	handler slog.Handler
+	level   slog.Level
+	levels  map[string]slog.Level
+}
+
+func (s *settings) levelOf(pkg string) slog.Level {
+	if level, ok := s.levels[pkg]; ok {
+		return level
+	}
+	return s.level
+}
+
+var current atomic.Pointer[settings]
+
+// allLevels lets every record through the base handlers; filtering is done by handler.Enabled.
+var allLevels = &slog.HandlerOptions{Level: slog.Level(-100)}
+
+func init() {
+	current.Store(&settings{handler: slog.NewJSONHandler(os.Stderr, allLevels), level: slog.LevelInfo})
+}
+
+// Setup configures the loggers returned by For, which may have been created
+// before, and makes the default slog logger (and so the log package) write
+// through them as the "app" package.
+func Setup(cfg config.LogConfig) error {
+	level, err := parseLevel(cfg.Level)
+	if err != nil {
+		return fmt.Errorf("log level: %w", err)
+	}
+	levels := make(map[string]slog.Level, len(cfg.Levels))
+	for pkg, name := range cfg.Levels {
+		if levels[pkg], err = parseLevel(name); err != nil {
+			return fmt.Errorf("level of %s: %w", pkg, err)
+		}
+	}
+
+	var base slog.Handler
+	switch cfg.Format {
+	case "json", "":
+		base = slog.NewJSONHandler(os.Stderr, allLevels)
+	case "text":
+		base = slog.NewTextHandler(os.Stderr, allLevels)
+	default:
+		return fmt.Errorf("unknown log format %q", cfg.Format)
+	}
+
+	current.Store(&settings{handler: base, level: level, levels: levels})
+	slog.SetDefault(For("app"))
+	return nil
+}
+
+func parseLevel(name string) (slog.Level, error) {
+	var level slog.Level
+	err := level.UnmarshalText([]byte(strings.ToUpper(name)))
+	return level, err
+}
+
+// For returns the logger of a package. Its records carry the package name,
+// the request ID and trace of the context they are logged with, and are
+// dropped below the level configured for the package.
+func For(pkg string) *slog.Logger {
+	return slog.New(&handler{pkg: pkg, with: func(h slog.Handler) slog.Handler { return h }})
+}
+
+// handler applies the current settings to each record, so loggers can be
+// package variables created before Setup runs.
+type handler struct {
+	pkg string
+	// with adds the attributes and groups of Logger.With and Logger.WithGroup.
+	with func(slog.Handler) slog.Handler
+}
+
+func (h *handler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= current.Load().levelOf(h.pkg)
+}
+
+func (h *handler) Handle(ctx context.Context, record slog.Record) error {
+	if id := RequestID(ctx); id != "" {
+		record.AddAttrs(slog.String("request_id", id))
+	}
+	if span := trace.SpanContextFromContext(ctx); span.IsValid() {
+		record.AddAttrs(slog.String("trace_id", span.TraceID().String()), slog.String("span_id", span.SpanID().String()))
+	}
+	base := current.Load().handler.WithAttrs([]slog.Attr{slog.String("package", h.pkg)})
+	return h.with(base).Handle(ctx, record)
+}
+
+func (h *handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &handler{pkg: h.pkg, with: func(base slog.Handler) slog.Handler { return h.with(base).WithAttrs(attrs) }}
+}
+
+func (h *handler) WithGroup(name string) slog.Handler {
+	return &handler{pkg: h.pkg, with: func(base slog.Handler) slog.Handler { return h.with(base).WithGroup(name) }}
+}
@@ -1,24 +1,126 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"mas-diq/go-graphql/cli"
 	"mas-diq/go-graphql/config"
+	"mas-diq/go-graphql/logging"
+	"mas-diq/go-graphql/metrics"
 	"mas-diq/go-graphql/models"
 	"mas-diq/go-graphql/routes"
+	"mas-diq/go-graphql/scheduler"
+	"mas-diq/go-graphql/search"
+	"mas-diq/go-graphql/storage"
+	"mas-diq/go-graphql/tracing"
+	"mas-diq/go-graphql/webhooks"
+	"os"
+	"os/signal"
+	"syscall"
 )
 
 func main() {
+	// Structured logs, for the server and commands alike
+	if err := logging.Setup(config.App.Log); err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid log settings: %v\n", err)
+		os.Exit(2)
+	}
+
+	// Commands such as "persisted register" run instead of the server
+	if len(os.Args) > 1 {
+		os.Exit(cli.Run(os.Args[1:]))
+	}
+
+	// Export spans and accept W3C trace context from clients
+	shutdownTracing, err := tracing.Setup(context.Background(), config.App.Tracing)
+	if err != nil {
+		fatal("Failed to set up tracing", err)
+	}
+
 	// Initialize database
-	config.ConnectDatabase()
+	if err := config.ConnectDatabase(logging.Gorm(config.App.Log.SlowQuery)); err != nil {
+		fatal("Failed to connect to database", err)
+	}
+
+	// Trace the statements of traced requests
+	if err := tracing.RegisterGorm(config.DB); err != nil {
+		fatal("Failed to set up database tracing", err)
+	}
+
+	// Export connection pool statistics
+	if err := metrics.RegisterDB(config.DB, "main"); err != nil {
+		fatal("Failed to register database metrics", err)
+	}
 
 	// Auto migrate
-	config.DB.AutoMigrate(
-		&models.User{},
-		&models.Post{},
-	)
+	if err := models.Migrate(config.DB); err != nil {
+		fatal("Failed to migrate database", err)
+	}
+
+	// Prepare full-text search for the database in use
+	if err := search.Setup(config.DB); err != nil {
+		fatal("Failed to set up search", err)
+	}
+
+	// Open the storage backend for uploads
+	if err := storage.Setup(config.App.Storage); err != nil {
+		fatal("Failed to set up storage", err)
+	}
 
-	// Setup routes
-	r := routes.SetupRouter()
+	// SIGTERM (or Ctrl+C) stops the background workers and drains the server
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// Publish scheduled posts in the background
+	schedulerDone := scheduler.Start(ctx, config.App.Posts.ScheduleInterval)
+
+	// Deliver outbox events to webhooks in the background
+	webhooksDone := webhooks.Start(ctx, config.App.Webhooks)
 
 	// Start server
-	r.Run(":8000")
+	server, err := routes.NewServer(config.App.Server)
+	if err != nil {
+		fatal("Failed to set up routes", err)
+	}
+	serveErr := make(chan error, 1)
+	go func() { serveErr <- server.ListenAndServe() }()
+
+	select {
+	case err := <-serveErr:
+		if err != nil {
+			fatal("Failed to start server", err)
+		}
+	case <-ctx.Done():
+	}
+	stop() // A second signal kills the process
+	slog.Info("Shutting down", "timeout", config.App.Server.ShutdownTimeout)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), config.App.Server.ShutdownTimeout)
+	defer cancel()
+	var errs []error
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		errs = append(errs, fmt.Errorf("server: %w", err))
+	}
+	<-schedulerDone
+	<-webhooksDone
+	if err := config.CloseDatabase(); err != nil {
+		errs = append(errs, fmt.Errorf("database: %w", err))
+	}
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		errs = append(errs, fmt.Errorf("tracing: %w", err))
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		slog.Error("Unclean shutdown", "error", err)
+		os.Exit(1)
+	}
+	slog.Info("Shut down")
+}
+
+// fatal logs why the server can't start and exits.
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
 }
@@ -0,0 +1,83 @@
+// Package metrics holds the Prometheus metrics of the service and serves them.
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"gorm.io/gorm"
+)
+
+// Registry has the metrics of this package plus the Go runtime and process
+// collectors. It is separate from prometheus.DefaultRegisterer so libraries
+// can't add metrics behind our back.
+var Registry = prometheus.NewRegistry()
+
+var factory = promauto.With(Registry)
+
+var (
+	HTTPRequests = factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "HTTP requests served, by route template and status.",
+	}, []string{"method", "route", "status"})
+	HTTPDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "Time to serve HTTP requests, by route template and status.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	GraphQLOperations = factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "graphql_operations_total",
+		Help: "GraphQL operations executed, by operation name, type and result (ok or error).",
+	}, []string{"operation", "type", "result"})
+	GraphQLDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "graphql_operation_duration_seconds",
+		Help:    "Time to execute GraphQL operations, by operation name and type.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"operation", "type"})
+	ResolverDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "graphql_resolver_duration_seconds",
+		Help:    "Time spent in GraphQL resolvers, by field (Type.field).",
+		Buckets: []float64{.0001, .0005, .001, .005, .01, .025, .05, .1, .25, .5, 1},
+	}, []string{"field"})
+
+	LoaderBatchSize = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "loader_batch_size",
+		Help:    "Keys fetched per DataLoader batch.",
+		Buckets: []float64{1, 2, 5, 10, 20, 50, 100, 200, 500},
+	}, []string{"loader"})
+	LoaderLoads = factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "loader_loads_total",
+		Help: "DataLoader loads, by result: hit when the value was cached, miss when it was fetched.",
+	}, []string{"loader", "result"})
+
+	RateLimited = factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "rate_limited_requests_total",
+		Help: "Requests and GraphQL operations refused for exceeding their client's budget, by budget (rest or graphql).",
+	}, []string{"budget"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+// RegisterDB adds the connection pool statistics of db (open, in use and
+// idle connections, waits for a connection...) as the go_sql_* metrics.
+func RegisterDB(db *gorm.DB, name string) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	return Registry.Register(collectors.NewDBStatsCollector(sqlDB, name))
+}
+
+// Handler serves the metrics in the Prometheus text format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
@@ -0,0 +1,228 @@
+package middlewares
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mas-diq/go-graphql/auth"
+	"mas-diq/go-graphql/config"
+	"mas-diq/go-graphql/models"
+	"mas-diq/go-graphql/schemas"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Authenticate identifies the caller and stores the user in the request context
+// (see auth.UserFromContext). Services authenticate with an API key, sent as
+// "Authorization: ApiKey <key>", and act as the key's owner within its scopes
+// (see auth.HasScope). Requests without credentials continue anonymously.
+func Authenticate() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if secret, ok := apiKeyCredential(c.GetHeader("Authorization")); ok {
+			user, key, err := userFromAPIKey(c.Request.Context(), secret)
+			if err != nil {
+				abortUnauthenticated(c, apiKeyErrorInfo(err))
+				return
+			}
+			setCurrentUser(c, user)
+			c.Request = c.Request.WithContext(auth.WithAPIKey(c.Request.Context(), key))
+		} else if header := c.GetHeader("X-User-ID"); header != "" && config.App.Auth.TrustUserHeader {
+			user, err := userFromHeader(header)
+			if err != nil {
+				abortUnauthenticated(c, "Unknown user")
+				return
+			}
+			setCurrentUser(c, user)
+		}
+		c.Next()
+	}
+}
+
+var (
+	// ErrUnknownUser is returned for credentials that don't match any user.
+	ErrUnknownUser = errors.New("unknown user")
+	// ErrUnknownAPIKey is returned for API keys that don't exist.
+	ErrUnknownAPIKey = errors.New("unknown API key")
+)
+
+// AuthenticatePayload identifies the caller from credentials sent in a
+// message rather than in headers, as browsers can't set headers on
+// WebSockets: the payload of GraphQL's connection_init carries the same keys,
+// e.g. {"X-User-ID": "1"} or {"Authorization": "ApiKey <key>"}. Payloads
+// without credentials keep ctx as it is.
+func AuthenticatePayload(ctx context.Context, payload map[string]interface{}) (context.Context, error) {
+	if value, ok := payloadValue(payload, "Authorization"); ok {
+		if secret, ok := apiKeyCredential(fmt.Sprint(value)); ok {
+			user, key, err := userFromAPIKey(ctx, secret)
+			if err != nil {
+				return ctx, err
+			}
+			return auth.WithAPIKey(auth.WithUser(ctx, user), key), nil
+		}
+	}
+	if value, ok := payloadValue(payload, "X-User-ID"); ok && config.App.Auth.TrustUserHeader {
+		header := fmt.Sprint(value)
+		if number, ok := value.(float64); ok {
+			header = strconv.FormatFloat(number, 'f', -1, 64)
+		}
+		user, err := userFromHeader(header)
+		if err != nil {
+			return ctx, err
+		}
+		return auth.WithUser(ctx, user), nil
+	}
+	return ctx, nil
+}
+
+// payloadValue looks a header up in a payload, ignoring case like HTTP does.
+func payloadValue(payload map[string]interface{}, name string) (interface{}, bool) {
+	for key, value := range payload {
+		if strings.EqualFold(key, name) {
+			return value, true
+		}
+	}
+	return nil, false
+}
+
+// apiKeyCredential returns the key of an "ApiKey <key>" Authorization header.
+func apiKeyCredential(header string) (string, bool) {
+	scheme, secret, ok := strings.Cut(strings.TrimSpace(header), " ")
+	if !ok || !strings.EqualFold(scheme, "ApiKey") {
+		return "", false
+	}
+	secret = strings.TrimSpace(secret)
+	return secret, secret != ""
+}
+
+// userFromAPIKey loads a valid API key and its owner, and records its use.
+func userFromAPIKey(ctx context.Context, secret string) (*models.User, *models.APIKey, error) {
+	var key models.APIKey
+	if err := models.GetAPIKeyByHash(&key, auth.HashAPIKey(secret)); err != nil {
+		return nil, nil, ErrUnknownAPIKey
+	}
+	now := time.Now()
+	if err := key.Valid(now); err != nil {
+		return nil, nil, err
+	}
+	user, err := userFromHeader(strconv.FormatUint(uint64(key.UserID), 10))
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := models.TouchAPIKey(&key, now); err != nil {
+		accessLog.WarnContext(ctx, "Failed to record API key use", "api_key_id", key.ID, "error", err)
+	}
+	return user, &key, nil
+}
+
+// apiKeyErrorInfo is the message of the 401 response to an unusable API key.
+func apiKeyErrorInfo(err error) string {
+	switch {
+	case errors.Is(err, models.ErrAPIKeyRevoked):
+		return "API key was revoked"
+	case errors.Is(err, models.ErrAPIKeyExpired):
+		return "API key expired"
+	default:
+		return "Invalid API key"
+	}
+}
+
+// userFromHeader loads the user named by an X-User-ID value.
+func userFromHeader(header string) (*models.User, error) {
+	id, err := strconv.ParseUint(header, 10, 64)
+	if err != nil {
+		return nil, ErrUnknownUser
+	}
+	var user models.User
+	if err := models.GetOneUser(&user, id); err != nil {
+		return nil, ErrUnknownUser
+	}
+	return &user, nil
+}
+
+// RequireUser rejects anonymous requests with 401.
+func RequireUser() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if auth.UserFromContext(c.Request.Context()) == nil {
+			abortUnauthenticated(c, "Authentication required")
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireRole rejects anonymous requests with 401 and users without one of
+// the roles with 403.
+func RequireRole(roles ...models.UserRole) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user := auth.UserFromContext(c.Request.Context())
+		if user == nil {
+			abortUnauthenticated(c, "Authentication required")
+			return
+		}
+		for _, role := range roles {
+			if user.Role == role {
+				c.Next()
+				return
+			}
+		}
+		c.AbortWithStatusJSON(http.StatusForbidden, schemas.Response{
+			Code:      http.StatusForbidden,
+			Info:      models.ErrForbidden.Error(),
+			ErrorCode: "FORBIDDEN",
+		})
+	}
+}
+
+// RequireScope rejects requests authenticated with an API key that wasn't
+// granted the read scope, for GET and HEAD requests, or the write scope, for
+// the others, with 403. Other callers pass.
+func RequireScope(read, write string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		scope := write
+		if c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead {
+			scope = read
+		}
+		if !auth.HasScope(c.Request.Context(), scope) {
+			c.AbortWithStatusJSON(http.StatusForbidden, schemas.Response{
+				Code:      http.StatusForbidden,
+				Info:      "API key lacks the " + scope + " scope",
+				ErrorCode: "INSUFFICIENT_SCOPE",
+			})
+			return
+		}
+		c.Next()
+	}
+}
+
+// RejectAPIKeys refuses requests authenticated with an API key with 403, for
+// routes only people may use, such as managing the keys themselves.
+func RejectAPIKeys() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if auth.APIKeyFromContext(c.Request.Context()) != nil {
+			c.AbortWithStatusJSON(http.StatusForbidden, schemas.Response{
+				Code:      http.StatusForbidden,
+				Info:      "API keys can't be used here",
+				ErrorCode: "FORBIDDEN",
+			})
+			return
+		}
+		c.Next()
+	}
+}
+
+func setCurrentUser(c *gin.Context, user *models.User) {
+	c.Set("user", user)
+	c.Request = c.Request.WithContext(auth.WithUser(c.Request.Context(), user))
+}
+
+func abortUnauthenticated(c *gin.Context, info string) {
+	c.AbortWithStatusJSON(http.StatusUnauthorized, schemas.Response{
+		Code:      http.StatusUnauthorized,
+		Info:      info,
+		ErrorCode: "UNAUTHENTICATED",
+	})
+}
@@ -0,0 +1,49 @@
+package middlewares
+
+import (
+	"mas-diq/go-graphql/auth"
+	"mas-diq/go-graphql/models"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRequireScope(t *testing.T) {
+	tests := []struct {
+		name   string
+		scopes string // "" sends the request without an API key
+		method string
+		want   int
+	}{
+		{name: "no API key, read", method: http.MethodGet, want: http.StatusOK},
+		{name: "no API key, write", method: http.MethodPost, want: http.StatusOK},
+		{name: "read", scopes: models.ScopePostsRead, method: http.MethodGet, want: http.StatusOK},
+		{name: "head", scopes: models.ScopePostsRead, method: http.MethodHead, want: http.StatusOK},
+		{name: "write with the read scope", scopes: models.ScopePostsRead, method: http.MethodPost, want: http.StatusForbidden},
+		{name: "put with the read scope", scopes: models.ScopePostsRead, method: http.MethodPut, want: http.StatusForbidden},
+		{name: "delete", scopes: models.ScopePostsRead + "," + models.ScopePostsWrite, method: http.MethodDelete, want: http.StatusOK},
+		{name: "write only", scopes: models.ScopePostsWrite, method: http.MethodGet, want: http.StatusForbidden},
+		{name: "scopes of another resource", scopes: models.ScopeUsersRead + "," + models.ScopeUsersWrite, method: http.MethodPost, want: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := gin.New()
+			r.Use(func(c *gin.Context) {
+				if tt.scopes != "" {
+					c.Request = c.Request.WithContext(auth.WithAPIKey(c.Request.Context(), &models.APIKey{Scopes: tt.scopes}))
+				}
+			})
+			r.Handle(tt.method, "/comments/1", RequireScope(models.ScopePostsRead, models.ScopePostsWrite), func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(tt.method, "/comments/1", nil))
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package middlewares
+
+import (
+	"mas-diq/go-graphql/config"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CORS lets browsers call the API from the origins of cfg.AllowedOrigins. It
+// answers preflight requests itself, with 204, so it must run before the
+// middlewares that authenticate or limit requests; responses to requests from
+// other origins get no CORS headers, which makes browsers block them.
+func CORS(cfg config.CORSConfig) gin.HandlerFunc {
+	methods := strings.Join(cfg.AllowedMethods, ", ")
+	headers := strings.Join(cfg.AllowedHeaders, ", ")
+	anyHeader := len(cfg.AllowedHeaders) == 1 && cfg.AllowedHeaders[0] == "*"
+	exposed := strings.Join(cfg.ExposedHeaders, ", ")
+	maxAge := strconv.Itoa(int(cfg.MaxAge.Seconds()))
+
+	return func(c *gin.Context) {
+		origin := c.GetHeader("Origin")
+		if len(cfg.AllowedOrigins) == 0 || origin == "" {
+			c.Next()
+			return
+		}
+
+		header := c.Writer.Header()
+		// The answer depends on the origin, unless every origin gets "*".
+		wildcard := !cfg.AllowCredentials && len(cfg.AllowedOrigins) == 1 && cfg.AllowedOrigins[0] == "*"
+		if !wildcard {
+			header.Add("Vary", "Origin")
+		}
+		if !AllowedOrigin(cfg, origin) {
+			c.Next()
+			return
+		}
+
+		if wildcard {
+			header.Set("Access-Control-Allow-Origin", "*")
+		} else {
+			header.Set("Access-Control-Allow-Origin", origin)
+		}
+		if cfg.AllowCredentials {
+			header.Set("Access-Control-Allow-Credentials", "true")
+		}
+
+		if c.Request.Method == http.MethodOptions && c.GetHeader("Access-Control-Request-Method") != "" {
+			header.Set("Access-Control-Allow-Methods", methods)
+			if anyHeader {
+				header.Set("Access-Control-Allow-Headers", c.GetHeader("Access-Control-Request-Headers"))
+			} else if headers != "" {
+				header.Set("Access-Control-Allow-Headers", headers)
+			}
+			if cfg.MaxAge > 0 {
+				header.Set("Access-Control-Max-Age", maxAge)
+			}
+			c.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+
+		if exposed != "" {
+			header.Set("Access-Control-Expose-Headers", exposed)
+		}
+		c.Next()
+	}
+}
+
+// AllowedOrigin reports whether browsers may call the API from origin.
+func AllowedOrigin(cfg config.CORSConfig, origin string) bool {
+	origin = strings.ToLower(origin)
+	for _, pattern := range cfg.AllowedOrigins {
+		if matchOrigin(strings.ToLower(pattern), origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchOrigin matches an origin against a pattern with up to one "*", which
+// stands for one or more subdomains: "https://*.example.com" matches
+// "https://app.example.com" but neither "https://example.com" nor
+// "https://evil.com/.example.com".
+func matchOrigin(pattern, origin string) bool {
+	if pattern == "*" || pattern == origin {
+		return true
+	}
+	prefix, suffix, ok := strings.Cut(pattern, "*")
+	if !ok || len(origin) <= len(prefix)+len(suffix) || !strings.HasPrefix(origin, prefix) || !strings.HasSuffix(origin, suffix) {
+		return false
+	}
+	middle := origin[len(prefix) : len(origin)-len(suffix)]
+	return !strings.ContainsAny(middle, "/:@?#")
+}
+
+// CheckOrigin is the origin check of GraphQL WebSocket connections, which
+// CORS doesn't cover: browsers send any page's Origin with the upgrade
+// request. Connections from the API's own host and from the CORS origins
+// are accepted; clients that aren't browsers send no Origin and pass.
+func CheckOrigin(cfg config.CORSConfig) func(r *http.Request) bool {
+	return func(r *http.Request) bool {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			return true
+		}
+		if u, err := url.Parse(origin); err == nil && strings.EqualFold(u.Host, r.Host) {
+			return true
+		}
+		return AllowedOrigin(cfg, origin)
+	}
+}
@@ -0,0 +1,208 @@
+package middlewares
+
+import (
+	"mas-diq/go-graphql/config"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestAllowedOrigin(t *testing.T) {
+	cfg := config.CORSConfig{AllowedOrigins: []string{"https://app.example.com", "https://*.example.org", "HTTP://LOCALHOST:3000"}}
+	tests := []struct {
+		origin string
+		want   bool
+	}{
+		{origin: "https://app.example.com", want: true},
+		{origin: "https://APP.example.com", want: true},
+		{origin: "http://app.example.com"},
+		{origin: "https://app.example.com.evil.com"},
+		{origin: "https://a.example.org", want: true},
+		{origin: "https://a.b.example.org", want: true},
+		{origin: "https://example.org"},
+		{origin: "https://.example.org"},
+		{origin: "https://evil.com/.example.org"},
+		{origin: "https://evil.com?.example.org"},
+		{origin: "https://user@evil.com:.example.org"},
+		{origin: "http://localhost:3000", want: true},
+		{origin: "http://localhost:3001"},
+	}
+	for _, tt := range tests {
+		if got := AllowedOrigin(cfg, tt.origin); got != tt.want {
+			t.Errorf("AllowedOrigin(%q) = %v, want %v", tt.origin, got, tt.want)
+		}
+	}
+
+	if !AllowedOrigin(config.CORSConfig{AllowedOrigins: []string{"*"}}, "https://anything.test") {
+		t.Error("\"*\" doesn't allow every origin")
+	}
+}
+
+func corsRouter(cfg config.CORSConfig) *gin.Engine {
+	r := gin.New()
+	r.Use(CORS(cfg))
+	r.GET("/", func(c *gin.Context) { c.Status(http.StatusOK) })
+	return r
+}
+
+func corsRequest(r http.Handler, method, origin string, header map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, "/", nil)
+	if origin != "" {
+		req.Header.Set("Origin", origin)
+	}
+	for name, value := range header {
+		req.Header.Set(name, value)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestCORS(t *testing.T) {
+	cfg := config.CORSConfig{
+		AllowedOrigins:   []string{"https://app.example.com"},
+		AllowedMethods:   []string{"GET", "POST"},
+		AllowedHeaders:   []string{"Authorization", "Content-Type"},
+		ExposedHeaders:   []string{"RateLimit-Remaining"},
+		AllowCredentials: true,
+		MaxAge:           10 * time.Minute,
+	}
+	preflight := map[string]string{"Access-Control-Request-Method": "POST", "Access-Control-Request-Headers": "authorization"}
+
+	tests := []struct {
+		name   string
+		cfg    config.CORSConfig
+		method string
+		origin string
+		header map[string]string
+		status int
+		want   map[string]string
+	}{
+		{
+			name:   "preflight",
+			cfg:    cfg,
+			method: http.MethodOptions,
+			origin: "https://app.example.com",
+			header: preflight,
+			status: http.StatusNoContent,
+			want: map[string]string{
+				"Access-Control-Allow-Origin":      "https://app.example.com",
+				"Access-Control-Allow-Credentials": "true",
+				"Access-Control-Allow-Methods":     "GET, POST",
+				"Access-Control-Allow-Headers":     "Authorization, Content-Type",
+				"Access-Control-Max-Age":           "600",
+				"Access-Control-Expose-Headers":    "",
+				"Vary":                             "Origin",
+			},
+		},
+		{
+			name:   "preflight from another origin",
+			cfg:    cfg,
+			method: http.MethodOptions,
+			origin: "https://evil.com",
+			header: preflight,
+			status: http.StatusNotFound,
+			want: map[string]string{
+				"Access-Control-Allow-Origin":  "",
+				"Access-Control-Allow-Methods": "",
+				"Vary":                         "Origin",
+			},
+		},
+		{
+			name:   "request",
+			cfg:    cfg,
+			method: http.MethodGet,
+			origin: "https://app.example.com",
+			status: http.StatusOK,
+			want: map[string]string{
+				"Access-Control-Allow-Origin":      "https://app.example.com",
+				"Access-Control-Allow-Credentials": "true",
+				"Access-Control-Allow-Methods":     "",
+				"Access-Control-Expose-Headers":    "RateLimit-Remaining",
+			},
+		},
+		{
+			name:   "request from another origin",
+			cfg:    cfg,
+			method: http.MethodGet,
+			origin: "https://evil.com",
+			status: http.StatusOK,
+			want:   map[string]string{"Access-Control-Allow-Origin": "", "Access-Control-Allow-Credentials": ""},
+		},
+		{
+			name:   "request without an origin",
+			cfg:    cfg,
+			method: http.MethodGet,
+			status: http.StatusOK,
+			want:   map[string]string{"Access-Control-Allow-Origin": "", "Vary": ""},
+		},
+		{
+			name:   "any origin",
+			cfg:    config.CORSConfig{AllowedOrigins: []string{"*"}, AllowedMethods: []string{"GET"}, AllowedHeaders: []string{"*"}},
+			method: http.MethodOptions,
+			origin: "https://anything.test",
+			header: preflight,
+			status: http.StatusNoContent,
+			want: map[string]string{
+				"Access-Control-Allow-Origin":  "*",
+				"Access-Control-Allow-Headers": "authorization",
+				"Access-Control-Max-Age":       "",
+				"Vary":                         "",
+			},
+		},
+		{
+			name:   "any origin with credentials",
+			cfg:    config.CORSConfig{AllowedOrigins: []string{"*"}, AllowCredentials: true},
+			method: http.MethodGet,
+			origin: "https://anything.test",
+			status: http.StatusOK,
+			want:   map[string]string{"Access-Control-Allow-Origin": "https://anything.test", "Vary": "Origin"},
+		},
+		{
+			name:   "disabled",
+			cfg:    config.CORSConfig{},
+			method: http.MethodGet,
+			origin: "https://app.example.com",
+			status: http.StatusOK,
+			want:   map[string]string{"Access-Control-Allow-Origin": "", "Vary": ""},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := corsRequest(corsRouter(tt.cfg), tt.method, tt.origin, tt.header)
+			if w.Code != tt.status {
+				t.Errorf("status = %d, want %d", w.Code, tt.status)
+			}
+			for name, value := range tt.want {
+				if got := w.Header().Get(name); got != value {
+					t.Errorf("%s = %q, want %q", name, got, value)
+				}
+			}
+		})
+	}
+}
+
+func TestCheckOrigin(t *testing.T) {
+	check := CheckOrigin(config.CORSConfig{AllowedOrigins: []string{"https://app.example.com"}})
+	tests := []struct {
+		origin string
+		want   bool
+	}{
+		{origin: "", want: true},
+		{origin: "https://api.example.com", want: true},
+		{origin: "https://app.example.com", want: true},
+		{origin: "https://evil.com"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "https://api.example.com/graphql", nil)
+		if tt.origin != "" {
+			req.Header.Set("Origin", tt.origin)
+		}
+		if got := check(req); got != tt.want {
+			t.Errorf("CheckOrigin(%q) = %v, want %v", tt.origin, got, tt.want)
+		}
+	}
+}
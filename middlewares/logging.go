@@ -0,0 +1,114 @@
+package middlewares
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"mas-diq/go-graphql/auth"
+	"mas-diq/go-graphql/logging"
+	"mas-diq/go-graphql/schemas"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+var accessLog = logging.For("http")
+
+// maxRequestIDLength bounds the X-Request-ID values accepted from clients.
+const maxRequestIDLength = 128
+
+// RequestID gives every request an ID: the X-Request-ID header when a client
+// or proxy sent a usable one, a random one otherwise. It is echoed in the
+// response header and carried by the request context (see logging.RequestID),
+// so every log line of the request has it.
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader("X-Request-ID")
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		c.Header("X-Request-ID", id)
+		c.Request = c.Request.WithContext(logging.WithRequestID(c.Request.Context(), id))
+		c.Next()
+	}
+}
+
+// validRequestID accepts short IDs of printable ASCII, so clients can't put
+// anything else in the logs.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// AccessLog logs a line per request once it is served, with what handlers
+// added through logging.Annotate (e.g. the GraphQL operation). Server errors
+// are logged as errors.
+func AccessLog() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		ctx, annotations := logging.WithAnnotations(c.Request.Context())
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+
+		status := c.Writer.Status()
+		attrs := []slog.Attr{
+			slog.String("method", c.Request.Method),
+			slog.String("route", c.FullPath()),
+			slog.String("path", c.Request.URL.Path),
+			slog.Int("status", status),
+			slog.Int("bytes", c.Writer.Size()),
+			slog.Float64("duration_ms", float64(time.Since(start).Microseconds())/1000),
+			slog.String("client_ip", c.ClientIP()),
+		}
+		if user := auth.UserFromContext(c.Request.Context()); user != nil {
+			attrs = append(attrs, slog.Uint64("user_id", uint64(user.ID)))
+		}
+		if key := auth.APIKeyFromContext(c.Request.Context()); key != nil {
+			attrs = append(attrs, slog.Uint64("api_key_id", uint64(key.ID)))
+		}
+		attrs = append(attrs, annotations()...)
+
+		level := slog.LevelInfo
+		if status >= http.StatusInternalServerError {
+			level = slog.LevelError
+		}
+		accessLog.LogAttrs(c.Request.Context(), level, "Request", attrs...)
+	}
+}
+
+// Recover turns panics into 500 responses, logging them with their stack.
+func Recover() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				if recovered == http.ErrAbortHandler {
+					panic(recovered)
+				}
+				accessLog.ErrorContext(c.Request.Context(), "Panic while serving request",
+					"panic", recovered, "stack", string(debug.Stack()))
+				c.AbortWithStatusJSON(http.StatusInternalServerError, schemas.Response{
+					Code:      http.StatusInternalServerError,
+					Info:      "Internal server error",
+					ErrorCode: "INTERNAL",
+				})
+			}
+		}()
+		c.Next()
+	}
+}
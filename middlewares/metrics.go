@@ -0,0 +1,27 @@
+package middlewares
+
+import (
+	"mas-diq/go-graphql/metrics"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Metrics counts requests and their duration by route template, e.g.
+// /posts/:id rather than /posts/42, so the number of series stays bounded.
+// Requests that matched no route are counted under "unmatched".
+func Metrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		status := strconv.Itoa(c.Writer.Status())
+		metrics.HTTPRequests.WithLabelValues(c.Request.Method, route, status).Inc()
+		metrics.HTTPDuration.WithLabelValues(c.Request.Method, route, status).Observe(time.Since(start).Seconds())
+	}
+}
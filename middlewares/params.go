@@ -0,0 +1,36 @@
+package middlewares
+
+import (
+	"mas-diq/go-graphql/schemas"
+	"mas-diq/go-graphql/validation"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ParseID reads the ":id" path parameter, validates it and stores it in the
+// gin context under "id" as uint64 for the controllers to pick up.
+func ParseID() gin.HandlerFunc {
+	return ParseUintParam("id")
+}
+
+// ParseUintParam works like ParseID for any named path parameter.
+func ParseUintParam(name string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseUint(c.Param(name), 10, 64)
+		if err != nil || id == 0 {
+			locale := validation.ParseLocale(c.GetHeader("Accept-Language"))
+			c.AbortWithStatusJSON(http.StatusBadRequest, schemas.Response{
+				Code:      http.StatusBadRequest,
+				Info:      "Validation failed",
+				ErrorCode: "VALIDATION_FAILED",
+				Errors:    []schemas.FieldError{validation.NewFieldError(locale, name, "gt", "0")},
+			})
+			return
+		}
+
+		c.Set(name, id)
+		c.Next()
+	}
+}
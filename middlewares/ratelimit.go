@@ -0,0 +1,29 @@
+package middlewares
+
+import (
+	"mas-diq/go-graphql/ratelimit"
+	"mas-diq/go-graphql/schemas"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimit charges each request to the bucket of its client (see
+// ratelimit.Client) and rejects those over budget with 429. Responses carry
+// the RateLimit headers either way.
+func RateLimit(limiter *ratelimit.Limiter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := ratelimit.WithClientIP(c.Request.Context(), c.ClientIP())
+		result := limiter.Take(ctx, ratelimit.Client(ctx), 1)
+		result.WriteHeaders(c.Writer.Header())
+		if !result.Allowed {
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, schemas.Response{
+				Code:      http.StatusTooManyRequests,
+				Info:      "Too many requests",
+				ErrorCode: "RATE_LIMITED",
+			})
+			return
+		}
+		c.Next()
+	}
+}
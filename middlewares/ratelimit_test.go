@@ -0,0 +1,72 @@
+package middlewares
+
+import (
+	"mas-diq/go-graphql/ratelimit"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// rateLimitedRouter allows one request per client, and trusts X-Forwarded-For
+// from proxies like SetupRouter does.
+func rateLimitedRouter(t *testing.T, trustedProxies []string) *gin.Engine {
+	t.Helper()
+	r := gin.New()
+	if err := r.SetTrustedProxies(trustedProxies); err != nil {
+		t.Fatal(err)
+	}
+	limiter := ratelimit.NewLimiter(ratelimit.NewMemoryStore(), "rest", ratelimit.Limit{Rate: 0.001, Burst: 1})
+	r.Use(RateLimit(limiter))
+	r.GET("/", func(c *gin.Context) { c.Status(http.StatusOK) })
+	return r
+}
+
+func get(r http.Handler, remoteAddr, forwardedFor string) int {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = remoteAddr
+	if forwardedFor != "" {
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w.Code
+}
+
+func TestRateLimitIgnoresSpoofedForwardedFor(t *testing.T) {
+	r := rateLimitedRouter(t, nil)
+	if code := get(r, "203.0.113.7:1234", "198.51.100.1"); code != http.StatusOK {
+		t.Fatalf("first request: status = %d", code)
+	}
+	if code := get(r, "203.0.113.7:1234", "198.51.100.2"); code != http.StatusTooManyRequests {
+		t.Fatalf("spoofed X-Forwarded-For got a new bucket: status = %d", code)
+	}
+	if code := get(r, "203.0.113.8:1234", ""); code != http.StatusOK {
+		t.Fatalf("other client: status = %d", code)
+	}
+}
+
+func TestRateLimitUsesForwardedForOfTrustedProxies(t *testing.T) {
+	r := rateLimitedRouter(t, []string{"10.0.0.0/8"})
+	if code := get(r, "10.0.0.1:1234", "198.51.100.1"); code != http.StatusOK {
+		t.Fatalf("first client: status = %d", code)
+	}
+	if code := get(r, "10.0.0.1:1234", "198.51.100.2"); code != http.StatusOK {
+		t.Fatalf("second client behind the proxy: status = %d", code)
+	}
+	if code := get(r, "10.0.0.2:1234", "198.51.100.1"); code != http.StatusTooManyRequests {
+		t.Fatalf("first client again: status = %d", code)
+	}
+	// Outside of the trusted range the header is ignored
+	if code := get(r, "203.0.113.7:1234", "198.51.100.3"); code != http.StatusOK {
+		t.Fatalf("untrusted proxy: status = %d", code)
+	}
+	if code := get(r, "203.0.113.7:1234", "198.51.100.4"); code != http.StatusTooManyRequests {
+		t.Fatalf("untrusted proxy again: status = %d", code)
+	}
+}
@@ -0,0 +1,43 @@
+package middlewares
+
+import (
+	"mas-diq/go-graphql/config"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SecurityHeaders sets the headers that keep browsers from sniffing, framing
+// or running what the API returns, and Strict-Transport-Security on HTTPS.
+// Routes serving pages, such as the GraphQL playground, set a policy of their own.
+func SecurityHeaders(cfg config.SecurityConfig) gin.HandlerFunc {
+	hsts := ""
+	if cfg.HSTSMaxAge > 0 {
+		hsts = "max-age=" + strconv.Itoa(int(cfg.HSTSMaxAge.Seconds()))
+		if cfg.HSTSIncludeSubdomains {
+			hsts += "; includeSubDomains"
+		}
+		if cfg.HSTSPreload {
+			hsts += "; preload"
+		}
+	}
+
+	return func(c *gin.Context) {
+		header := c.Writer.Header()
+		header.Set("X-Content-Type-Options", "nosniff")
+		if cfg.FrameOptions != "" {
+			header.Set("X-Frame-Options", cfg.FrameOptions)
+		}
+		if cfg.ReferrerPolicy != "" {
+			header.Set("Referrer-Policy", cfg.ReferrerPolicy)
+		}
+		if cfg.ContentSecurityPolicy != "" {
+			header.Set("Content-Security-Policy", cfg.ContentSecurityPolicy)
+		}
+		if hsts != "" && (c.Request.TLS != nil || strings.EqualFold(c.GetHeader("X-Forwarded-Proto"), "https")) {
+			header.Set("Strict-Transport-Security", hsts)
+		}
+		c.Next()
+	}
+}
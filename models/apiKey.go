@@ -0,0 +1,128 @@
+package models
+
+import (
+	"errors"
+	"mas-diq/go-graphql/config"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Scopes an API key can be granted. Reads are queries and GET requests, writes
+// are mutations and the other methods.
+const (
+	ScopeUsersRead  = "users:read"
+	ScopeUsersWrite = "users:write"
+	ScopePostsRead  = "posts:read"
+	ScopePostsWrite = "posts:write"
+)
+
+// APIKey lets a service call the API as its owner, without signing in, but
+// only for the resources its scopes cover. Only the SHA-256 of the key is
+// stored; the key itself is shown once, when it is created.
+type APIKey struct {
+	ID   uint   `json:"id" gorm:"primarykey"`
+	Name string `json:"name" gorm:"size:100;not null"`
+	// Prefix is the start of the key, to recognise it in lists and logs.
+	Prefix     string `json:"prefix" gorm:"size:16;not null"`
+	SecretHash string `json:"-" gorm:"size:64;not null;uniqueIndex"`
+	UserID     uint   `json:"userId" gorm:"not null;index"`
+	// Scopes is a comma separated list of scopes.
+	Scopes     string     `json:"scopes" gorm:"size:255;not null"`
+	ExpiresAt  *time.Time `json:"expiresAt"` // nil for keys that don't expire
+	LastUsedAt *time.Time `json:"lastUsedAt"`
+	RevokedAt  *time.Time `json:"revokedAt"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	UpdatedAt  time.Time  `json:"updatedAt"`
+}
+
+const apiKeyTable = "api_keys"
+
+func (k *APIKey) TableName() string {
+	return apiKeyTable
+}
+
+var (
+	// ErrAPIKeyRevoked is returned when a revoked key is used.
+	ErrAPIKeyRevoked = errors.New("API key was revoked")
+	// ErrAPIKeyExpired is returned when a key is used after its expiry.
+	ErrAPIKeyExpired = errors.New("API key expired")
+)
+
+// lastUsedPrecision is how stale LastUsedAt may get, so a busy key doesn't
+// write on every request.
+const lastUsedPrecision = time.Minute
+
+// ScopeList returns the scopes of the key.
+func (k *APIKey) ScopeList() []string {
+	return strings.Split(k.Scopes, ",")
+}
+
+// HasScope reports whether the key was granted scope.
+func (k *APIKey) HasScope(scope string) bool {
+	return slices.Contains(k.ScopeList(), scope)
+}
+
+// Valid returns ErrAPIKeyRevoked or ErrAPIKeyExpired when the key can't be
+// used at now.
+func (k *APIKey) Valid(now time.Time) error {
+	if k.RevokedAt != nil {
+		return ErrAPIKeyRevoked
+	}
+	if k.ExpiresAt != nil && !now.Before(*k.ExpiresAt) {
+		return ErrAPIKeyExpired
+	}
+	return nil
+}
+
+func CreateAPIKeyData(m *APIKey) (err error) {
+	query := config.DB.Table(apiKeyTable).Create(m)
+	return query.Error
+}
+
+// GetListAPIKey returns the keys of a user, revoked ones included, newest first.
+func GetListAPIKey(m *[]APIKey, userID uint) (err error) {
+	query := config.DB.Table(apiKeyTable).Where("user_id = ?", userID).Order("id DESC").Find(m)
+	return query.Error
+}
+
+func GetOneAPIKey(m *APIKey, id uint64) (err error) {
+	query := config.DB.
+		Table(apiKeyTable).
+		Where("id = ?", id).
+		First(m)
+	return query.Error
+}
+
+// GetAPIKeyByHash finds the key whose secret hashes to hash.
+func GetAPIKeyByHash(m *APIKey, hash string) (err error) {
+	query := config.DB.
+		Table(apiKeyTable).
+		Where("secret_hash = ?", hash).
+		First(m)
+	return query.Error
+}
+
+// RevokeAPIKey disables a key for good; allowed for its owner and for admins.
+// Revoking a revoked key keeps its first revocation time.
+func RevokeAPIKey(m *APIKey, actor *User) (err error) {
+	if actor == nil || (actor.ID != m.UserID && actor.Role != RoleAdmin) {
+		return ErrForbidden
+	}
+	if m.RevokedAt != nil {
+		return nil
+	}
+	now := time.Now()
+	m.RevokedAt = &now
+	return config.DB.Table(apiKeyTable).Where("id = ?", m.ID).Update("revoked_at", now).Error
+}
+
+// TouchAPIKey records that the key was used at now, unless it already was
+// within the last minute.
+func TouchAPIKey(m *APIKey, now time.Time) (err error) {
+	if m.LastUsedAt != nil && now.Sub(*m.LastUsedAt) < lastUsedPrecision {
+		return nil
+	}
+	m.LastUsedAt = &now
+	return config.DB.Table(apiKeyTable).Where("id = ?", m.ID).UpdateColumn("last_used_at", now).Error
+}
@@ -0,0 +1,131 @@
+package models
+
+import (
+	"errors"
+	"mas-diq/go-graphql/config"
+
+	"gorm.io/gorm"
+)
+
+type CommentStatus string
+
+const (
+	CommentApproved CommentStatus = "approved"
+	CommentPending  CommentStatus = "pending"
+	CommentRejected CommentStatus = "rejected"
+)
+
+type Comment struct {
+	gorm.Model
+	PostID   uint          `json:"postId" gorm:"not null;index"`
+	AuthorID uint          `json:"authorId" gorm:"not null;index"`
+	ParentID *uint         `json:"parentId" gorm:"index"` // nil for top level comments
+	Body     string        `json:"body" gorm:"type:text;not null"`
+	Status   CommentStatus `json:"status" gorm:"type:enum('approved','pending','rejected');default:'approved'"`
+}
+
+const commentTable = "comments"
+
+func (c *Comment) TableName() string {
+	return commentTable
+}
+
+// ErrInvalidParent is returned when a reply points to a comment of another post.
+var ErrInvalidParent = errors.New("parent comment belongs to another post")
+
+func CreateCommentData(m *Comment) (err error) {
+	if m.ParentID != nil {
+		var parent Comment
+		if err := GetOneComment(&parent, uint64(*m.ParentID)); err != nil {
+			return err
+		}
+		if parent.PostID != m.PostID {
+			return ErrInvalidParent
+		}
+	}
+	return config.DB.Create(m).Error
+}
+
+// UpdateCommentData saves an edited comment; only its author may edit it.
+func UpdateCommentData(m *Comment, actor *User) (err error) {
+	if actor == nil || actor.ID != m.AuthorID {
+		return ErrForbidden
+	}
+	return config.DB.Save(m).Error
+}
+
+// ModerateComment changes the moderation status; only moderators may do so.
+func ModerateComment(m *Comment, actor *User, status CommentStatus) (err error) {
+	if !actor.IsModerator() {
+		return ErrForbidden
+	}
+	m.Status = status
+	return config.DB.Save(m).Error
+}
+
+// DeleteComment removes a comment; allowed for its author and for moderators.
+// Replies stay in place.
+func DeleteComment(m *Comment, actor *User) (err error) {
+	if actor == nil || (actor.ID != m.AuthorID && !actor.IsModerator()) {
+		return ErrForbidden
+	}
+	return config.DB.Table(commentTable).Delete(m).Error
+}
+
+func GetOneComment(m *Comment, id uint64) (err error) {
+	query := config.DB.
+		Table(commentTable).
+		Where("id = ?", id).
+		First(m)
+	return query.Error
+}
+
+// VisibleComments is a query scope hiding comments the viewer may not see:
+// moderators see everything, others see approved comments plus their own.
+func VisibleComments(viewer *User) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		switch {
+		case viewer.IsModerator():
+			return db
+		case viewer != nil:
+			return db.Where("comments.status = ? OR comments.author_id = ?", CommentApproved, viewer.ID)
+		default:
+			return db.Where("comments.status = ?", CommentApproved)
+		}
+	}
+}
+
+// CommentPage is one page of a comment thread, ordered oldest first.
+type CommentPage struct {
+	Comments    []Comment
+	HasNextPage bool
+	TotalCount  int64
+}
+
+// GetCommentPage lists the comments of a post (parentID nil) or the replies
+// to a comment, visible to viewer, starting after the comment with ID after.
+func GetCommentPage(page *CommentPage, postID uint, parentID *uint, viewer *User, after uint, limit int) (err error) {
+	query := config.DB.Table(commentTable).Scopes(VisibleComments(viewer))
+	if parentID == nil {
+		query = query.Where("post_id = ? AND parent_id IS NULL", postID)
+	} else {
+		query = query.Where("parent_id = ?", *parentID)
+	}
+
+	if err := query.Session(&gorm.Session{}).Count(&page.TotalCount).Error; err != nil {
+		return err
+	}
+
+	if after > 0 {
+		query = query.Where("id > ?", after)
+	}
+	// Fetch one extra row to know whether there is a next page.
+	if err := query.Order("id ASC").Limit(limit + 1).Find(&page.Comments).Error; err != nil {
+		return err
+	}
+	if len(page.Comments) > limit {
+		page.HasNextPage = true
+		page.Comments = page.Comments[:limit]
+	}
+	return nil
+}
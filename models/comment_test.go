@@ -0,0 +1,112 @@
+package models
+
+import (
+	"errors"
+	"mas-diq/go-graphql/config"
+	"testing"
+)
+
+func TestIsModerator(t *testing.T) {
+	tests := []struct {
+		name string
+		user *User
+		want bool
+	}{
+		{name: "anonymous", user: nil},
+		{name: "user", user: author},
+		{name: "moderator", user: moderator, want: true},
+		{name: "admin", user: admin, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.user.IsModerator(); got != tt.want {
+				t.Fatalf("IsModerator = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVisibleComments(t *testing.T) {
+	db := dryRun(t)
+	tests := []struct {
+		name   string
+		viewer *User
+		want   string
+	}{
+		{name: "anonymous", viewer: nil, want: "SELECT * FROM `comments` WHERE comments.status = 'approved' AND `comments`.`deleted_at` IS NULL"},
+		{name: "user", viewer: other, want: "SELECT * FROM `comments` WHERE (comments.status = 'approved' OR comments.author_id = 2) AND `comments`.`deleted_at` IS NULL"},
+		{name: "moderator", viewer: moderator, want: "SELECT * FROM `comments` WHERE `comments`.`deleted_at` IS NULL"},
+		{name: "admin", viewer: admin, want: "SELECT * FROM `comments` WHERE `comments`.`deleted_at` IS NULL"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var comments []Comment
+			stmt := db.Scopes(VisibleComments(tt.viewer)).Find(&comments).Statement
+			if got := db.Dialector.Explain(stmt.SQL.String(), stmt.Vars...); got != tt.want {
+				t.Fatalf("query = %s\nwant    %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCommentPermissions(t *testing.T) {
+	previous := config.DB
+	config.DB = dryRun(t)
+	defer func() { config.DB = previous }()
+
+	tests := []struct {
+		name   string
+		action func(*Comment, *User) error
+		actor  *User
+		err    error
+	}{
+		{name: "edit, anonymous", action: UpdateCommentData, actor: nil, err: ErrForbidden},
+		{name: "edit, author", action: UpdateCommentData, actor: author},
+		{name: "edit, other user", action: UpdateCommentData, actor: other, err: ErrForbidden},
+		{name: "edit, moderator", action: UpdateCommentData, actor: moderator, err: ErrForbidden},
+		{name: "delete, anonymous", action: DeleteComment, actor: nil, err: ErrForbidden},
+		{name: "delete, author", action: DeleteComment, actor: author},
+		{name: "delete, other user", action: DeleteComment, actor: other, err: ErrForbidden},
+		{name: "delete, moderator", action: DeleteComment, actor: moderator},
+		{name: "delete, admin", action: DeleteComment, actor: admin},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			comment := &Comment{AuthorID: author.ID, Body: "hello", Status: CommentApproved}
+			comment.ID = 1
+			if err := tt.action(comment, tt.actor); !errors.Is(err, tt.err) {
+				t.Fatalf("err = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestModerateComment(t *testing.T) {
+	previous := config.DB
+	config.DB = dryRun(t)
+	defer func() { config.DB = previous }()
+
+	tests := []struct {
+		name   string
+		actor  *User
+		err    error
+		status CommentStatus
+	}{
+		{name: "anonymous", actor: nil, err: ErrForbidden, status: CommentPending},
+		{name: "author", actor: author, err: ErrForbidden, status: CommentPending},
+		{name: "moderator", actor: moderator, status: CommentRejected},
+		{name: "admin", actor: admin, status: CommentRejected},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			comment := &Comment{AuthorID: author.ID, Body: "hello", Status: CommentPending}
+			comment.ID = 1
+			if err := ModerateComment(comment, tt.actor, CommentRejected); !errors.Is(err, tt.err) {
+				t.Fatalf("err = %v, want %v", err, tt.err)
+			}
+			if comment.Status != tt.status {
+				t.Fatalf("status = %s, want %s", comment.Status, tt.status)
+			}
+		})
+	}
+}
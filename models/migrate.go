@@ -0,0 +1,92 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// ErrPendingMigrations means the database schema is behind the models.
+var ErrPendingMigrations = errors.New("database migrations are pending")
+
+// migratedModels are the models Migrate creates tables for.
+var migratedModels = []interface{}{
+	&User{},
+	&Post{},
+	&PostSlug{},
+	&PostRevision{},
+	&Tag{},
+	&PostTag{},
+	&Comment{},
+	&Upload{},
+	&PersistedQuery{},
+	&PostChange{},
+	&postChangeSequence{},
+	&OutboxEvent{},
+	&Webhook{},
+	&WebhookDelivery{},
+	&APIKey{},
+}
+
+// Migrate brings the database schema up to date with the models.
+func Migrate(db *gorm.DB) error {
+	// posts.slug has a unique index, so existing posts need a slug before
+	// AutoMigrate can create it.
+	if db.Migrator().HasTable(&Post{}) && !db.Migrator().HasColumn(&Post{}, "Slug") {
+		if err := db.Migrator().AddColumn(&Post{}, "Slug"); err != nil {
+			return err
+		}
+		if err := backfillSlugs(db); err != nil {
+			return err
+		}
+	}
+
+	if err := db.SetupJoinTable(&Post{}, "Tags", &PostTag{}); err != nil {
+		return err
+	}
+	if err := migratePostChangeSeq(db); err != nil {
+		return err
+	}
+	if err := db.AutoMigrate(migratedModels...); err != nil {
+		return err
+	}
+
+	return reslugTags(db)
+}
+
+// CheckMigrations reports ErrPendingMigrations, with what is missing, when a
+// table or column of the models isn't in the database. It reads one table
+// at a time and doesn't compare types or indexes.
+func CheckMigrations(db *gorm.DB) error {
+	var missing []string
+	for _, model := range migratedModels {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(model); err != nil {
+			return err
+		}
+		table := stmt.Schema.Table
+		if !db.Migrator().HasTable(table) {
+			missing = append(missing, table)
+			continue
+		}
+		columnTypes, err := db.Migrator().ColumnTypes(table)
+		if err != nil {
+			return err
+		}
+		columns := make(map[string]bool, len(columnTypes))
+		for _, column := range columnTypes {
+			columns[column.Name()] = true
+		}
+		for _, name := range stmt.Schema.DBNames {
+			if !columns[name] {
+				missing = append(missing, table+"."+name)
+			}
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: %s", ErrPendingMigrations, strings.Join(missing, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+package models
+
+import (
+	"encoding/json"
+	"mas-diq/go-graphql/config"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type EventType string
+
+const (
+	EventUserCreated   EventType = "user.created"
+	EventUserDeleted   EventType = "user.deleted"
+	EventPostCreated   EventType = "post.created"
+	EventPostUpdated   EventType = "post.updated"
+	EventPostPublished EventType = "post.published"
+	EventPostDeleted   EventType = "post.deleted"
+)
+
+// EventTypes lists every domain event, in the order they are documented.
+var EventTypes = []EventType{
+	EventUserCreated, EventUserDeleted,
+	EventPostCreated, EventPostUpdated, EventPostPublished, EventPostDeleted,
+}
+
+// OutboxEvent is a domain event waiting to be handed to webhooks. Events are
+// written in the transaction of the change they describe, so an event exists
+// exactly when its change was committed; the webhook dispatcher picks them up
+// afterwards and marks them dispatched.
+type OutboxEvent struct {
+	ID           uint64     `json:"id" gorm:"primarykey"`
+	Type         EventType  `json:"type" gorm:"size:50;not null"`
+	Payload      string     `json:"payload" gorm:"type:text;not null"` // JSON of the UserEventData or PostEventData
+	CreatedAt    time.Time  `json:"createdAt"`
+	DispatchedAt *time.Time `json:"dispatchedAt" gorm:"index"`
+}
+
+const outboxEventTable = "outbox_events"
+
+func (e *OutboxEvent) TableName() string {
+	return outboxEventTable
+}
+
+// UserEventData is the payload of user events.
+type UserEventData struct {
+	ID    uint     `json:"id"`
+	Name  string   `json:"name"`
+	Email string   `json:"email"`
+	Role  UserRole `json:"role"`
+}
+
+// PostEventData is the payload of post events, the post after the change.
+type PostEventData struct {
+	ID           uint       `json:"id"`
+	Title        string     `json:"title"`
+	Slug         string     `json:"slug"`
+	Subtitle     string     `json:"subtitle"`
+	Image        string     `json:"image"`
+	Content      string     `json:"content"`
+	Status       PostStatus `json:"status"`
+	CreatedBy    uint       `json:"createdBy"`
+	PublishedAt  *time.Time `json:"publishedAt"`
+	ScheduledFor *time.Time `json:"scheduledFor"`
+}
+
+func newUserEventData(m *User) UserEventData {
+	return UserEventData{ID: m.ID, Name: m.Name, Email: m.Email, Role: m.Role}
+}
+
+func newPostEventData(m *Post) PostEventData {
+	return PostEventData{
+		ID:           m.ID,
+		Title:        m.Title,
+		Slug:         m.Slug,
+		Subtitle:     m.Subtitle,
+		Image:        m.Image,
+		Content:      m.Content,
+		Status:       m.Status,
+		CreatedBy:    m.CreatedBy,
+		PublishedAt:  m.PublishedAt,
+		ScheduledFor: m.ScheduledFor,
+	}
+}
+
+// recordEvent writes a domain event to the outbox.
+func recordEvent(tx *gorm.DB, eventType EventType, data interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	return tx.Create(&OutboxEvent{Type: eventType, Payload: string(payload)}).Error
+}
+
+// GetPendingOutboxEvents returns up to limit events not dispatched yet, oldest first.
+func GetPendingOutboxEvents(m *[]OutboxEvent, limit int) (err error) {
+	query := config.DB.
+		Table(outboxEventTable).
+		Where("dispatched_at IS NULL").
+		Order("id").
+		Limit(limit).
+		Find(m)
+	return query.Error
+}
+
+// DispatchOutboxEvent marks the event dispatched and creates a pending
+// delivery for each of the webhooks, in one transaction. It reports false
+// when another dispatcher got to the event first.
+func DispatchOutboxEvent(m *OutboxEvent, webhooks []Webhook, now time.Time) (dispatched bool, err error) {
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		query := tx.Table(outboxEventTable).
+			Where("id = ? AND dispatched_at IS NULL", m.ID).
+			Update("dispatched_at", now)
+		if query.Error != nil || query.RowsAffected != 1 {
+			return query.Error
+		}
+		m.DispatchedAt = &now
+
+		for _, webhook := range webhooks {
+			delivery := WebhookDelivery{
+				WebhookID:     webhook.ID,
+				EventID:       m.ID,
+				Status:        DeliveryPending,
+				NextAttemptAt: &now,
+			}
+			if err := tx.Create(&delivery).Error; err != nil {
+				return err
+			}
+		}
+		dispatched = true
+		return nil
+	})
+	return dispatched, err
+}
+
+func GetOneOutboxEvent(m *OutboxEvent, id uint64) (err error) {
+	query := config.DB.
+		Table(outboxEventTable).
+		Where("id = ?", id).
+		First(m)
+	return query.Error
+}
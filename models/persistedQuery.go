@@ -0,0 +1,55 @@
+package models
+
+import (
+	"mas-diq/go-graphql/config"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// PersistedQuery is a GraphQL document stored under the SHA-256 of its text.
+// Registered queries come from the client manifests; the others were sent by
+// clients through automatic persisted queries.
+type PersistedQuery struct {
+	Hash          string    `json:"hash" gorm:"primaryKey;size:64"`
+	Query         string    `json:"query" gorm:"type:text;not null"`
+	OperationName string    `json:"operationName" gorm:"size:255"`
+	Registered    bool      `json:"registered" gorm:"not null;default:false"`
+	CreatedAt     time.Time `json:"createdAt"`
+}
+
+const persistedQueryTable = "persisted_queries"
+
+func (q *PersistedQuery) TableName() string {
+	return persistedQueryTable
+}
+
+func GetOnePersistedQuery(m *PersistedQuery, hash string) (err error) {
+	query := config.DB.
+		Table(persistedQueryTable).
+		Where("hash = ?", hash).
+		First(m)
+	return query.Error
+}
+
+// SavePersistedQuery stores a query unless it exists. Registering a query
+// that was only stored automatically marks it as registered.
+func SavePersistedQuery(m *PersistedQuery) (err error) {
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		var existing PersistedQuery
+		err := tx.Table(persistedQueryTable).Where("hash = ?", m.Hash).Limit(1).Find(&existing).Error
+		if err != nil {
+			return err
+		}
+		if existing.Hash == "" {
+			return tx.Table(persistedQueryTable).Create(m).Error
+		}
+		if m.Registered && !existing.Registered {
+			return tx.Table(persistedQueryTable).Where("hash = ?", m.Hash).Updates(map[string]interface{}{
+				"registered":     true,
+				"operation_name": m.OperationName,
+			}).Error
+		}
+		return nil
+	})
+}
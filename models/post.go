@@ -1,7 +1,9 @@
 package models
 
 import (
+	"errors"
 	"mas-diq/go-graphql/config"
+	"time"
 
 	"gorm.io/gorm"
 )
@@ -10,6 +12,7 @@ type PostStatus string
 
 const (
 	Draft     PostStatus = "draft"
+	Scheduled PostStatus = "scheduled"
 	Published PostStatus = "published"
 	Archived  PostStatus = "archived"
 )
@@ -17,12 +20,18 @@ const (
 type Post struct {
 	gorm.Model
 	Title     string     `json:"title" gorm:"size:255;not null"`
+	Slug      string     `json:"slug" gorm:"size:191;uniqueIndex"`
 	Subtitle  string     `json:"subtitle" gorm:"size:255"`
 	Image     string     `json:"image" gorm:"size:255"` // Store image URL or path
 	Content   string     `json:"content" gorm:"type:text;not null"`
-	Status    PostStatus `json:"status" gorm:"type:enum('draft','published','archived');default:'draft'"`
+	Status    PostStatus `json:"status" gorm:"type:enum('draft','scheduled','published','archived');default:'draft'"`
 	CreatedBy uint       `json:"createdBy" gorm:"not null"`        // Foreign key to User
 	User      User       `json:"user" gorm:"foreignKey:CreatedBy"` // Relationship
+
+	PublishedAt  *time.Time `json:"publishedAt"`
+	ScheduledFor *time.Time `json:"scheduledFor" gorm:"index"`
+
+	Tags []Tag `json:"tags" gorm:"many2many:post_tags"`
 }
 
 const postTable = "posts"
@@ -31,18 +40,86 @@ func (p *Post) TableName() string {
 	return postTable
 }
 
-func CreatePostData(m *Post) (err error) {
-	if err = config.DB.Create(m).Error; err != nil {
-		return err
+// CreatePostData inserts the post and records it as its first revision, edited
+// by its author. Unless tags is nil, the post gets those tags (see SetPostTags)
+// in the same transaction.
+func CreatePostData(m *Post, tags []string) (err error) {
+	if m.Status == Published && m.PublishedAt == nil {
+		now := time.Now()
+		m.PublishedAt = &now
 	}
-	return nil
+
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := assignSlug(tx, m); err != nil {
+			return err
+		}
+		if err := translateSlugError(tx.Omit("Tags").Create(m).Error); err != nil {
+			return err
+		}
+		if tags != nil {
+			if _, err := setPostTags(tx, m, tags); err != nil {
+				return err
+			}
+		}
+		if err := recordChange(tx, m, PostChangeCreated); err != nil {
+			return err
+		}
+		return recordRevision(tx, m, &User{Model: gorm.Model{ID: m.CreatedBy}})
+	})
 }
 
-func UpdatePostData(m *Post) (err error) {
-	if err = config.DB.Save(m).Error; err != nil {
-		return err
+// translateSlugError turns a unique index violation into ErrSlugTaken;
+// the slug is the only unique column on posts.
+func translateSlugError(err error) error {
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return ErrSlugTaken
 	}
-	return nil
+	return err
+}
+
+// UpdatePostData saves the post and records a revision attributed to editor
+// (nil for changes made by the system). Unless tags is nil, the tags of the
+// post are replaced too (see SetPostTags): the edit and the tags are saved
+// together or not at all.
+func UpdatePostData(m *Post, editor *User, tags []string) (err error) {
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		if tags != nil {
+			if _, err := setPostTags(tx, m, tags); err != nil {
+				return err
+			}
+		}
+
+		var previous struct {
+			Slug   string
+			Status PostStatus
+		}
+		if err := tx.Table(postTable).Select("slug", "status").Where("id = ?", m.ID).Scan(&previous).Error; err != nil {
+			return err
+		}
+		if m.Slug == "" {
+			m.Slug = previous.Slug
+		}
+		if m.Slug == "" {
+			if err := assignSlug(tx, m); err != nil {
+				return err
+			}
+		}
+		if err := changeSlug(tx, m, previous.Slug); err != nil {
+			return err
+		}
+		if err := translateSlugError(tx.Omit("Tags").Save(m).Error); err != nil {
+			return err
+		}
+
+		change := PostChangeUpdated
+		if m.Status == Published && previous.Status != Published {
+			change = PostChangePublished
+		}
+		if err := recordChange(tx, m, change); err != nil {
+			return err
+		}
+		return recordRevision(tx, m, editor)
+	})
 }
 
 func GetListPost(m *[]Post, filter Post) (err error) {
@@ -67,12 +144,27 @@ func GetListPost(m *[]Post, filter Post) (err error) {
 func GetOnePost(m *Post, id uint64) (err error) {
 	query := config.DB.
 		Table(postTable).
+		Preload("Tags").
 		Where("id = ?", id).
 		First(m)
 	return query.Error
 }
 
-func DeletePost(m *Post) (err error) {
-	query := config.DB.Table(postTable).Delete(m)
+// GetPostsByIDs loads the posts with the given IDs and their tags; deleted posts are left out.
+func GetPostsByIDs(m *[]Post, ids []uint) (err error) {
+	query := config.DB.
+		Table(postTable).
+		Preload("Tags").
+		Where("id IN ?", ids).
+		Find(m)
 	return query.Error
 }
+
+func DeletePost(m *Post) (err error) {
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Table(postTable).Delete(m).Error; err != nil {
+			return err
+		}
+		return recordChange(tx, m, PostChangeDeleted)
+	})
+}
@@ -0,0 +1,142 @@
+package models
+
+import (
+	"errors"
+	"mas-diq/go-graphql/config"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type PostChangeType string
+
+const (
+	PostChangeCreated   PostChangeType = "created"
+	PostChangeUpdated   PostChangeType = "updated"
+	PostChangeDeleted   PostChangeType = "deleted"
+	PostChangePublished PostChangeType = "published"
+)
+
+// PostChange is an entry of the change log of posts. It is written in the
+// transaction of the change itself, so the log has every committed change and
+// nothing else. Readers resume after the last Seq they saw; IDs can't serve
+// that purpose, as concurrent transactions commit out of ID order.
+type PostChange struct {
+	ID uint64 `json:"id" gorm:"primarykey"`
+	// Seq is the position of the change in commit order, see nextPostChangeSeq.
+	Seq       uint64         `json:"seq" gorm:"uniqueIndex"`
+	PostID    uint           `json:"postId" gorm:"not null;index"`
+	AuthorID  uint           `json:"authorId" gorm:"not null;index"`
+	Type      PostChangeType `json:"type" gorm:"size:20;not null"`
+	Status    PostStatus     `json:"status" gorm:"size:20"` // Status of the post after the change
+	CreatedAt time.Time      `json:"createdAt"`
+}
+
+const postChangeTable = "post_changes"
+
+func (c *PostChange) TableName() string {
+	return postChangeTable
+}
+
+// postChangeSequence is the counter of PostChange.Seq, a single row.
+type postChangeSequence struct {
+	ID    uint   `gorm:"primarykey"`
+	Value uint64 `gorm:"not null"`
+}
+
+const postChangeSequenceTable = "post_change_sequence"
+
+func (s *postChangeSequence) TableName() string {
+	return postChangeSequenceTable
+}
+
+// errNoPostChangeSequence means the counter row is missing, which Migrate creates.
+var errNoPostChangeSequence = errors.New("post change sequence is missing, run the migrations")
+
+// PostChangeFilter narrows down the changes GetPostChanges returns; zero values match everything.
+type PostChangeFilter struct {
+	AuthorID uint
+	Status   PostStatus
+}
+
+// recordChange appends a change of the post to the log and writes the
+// matching event to the outbox.
+func recordChange(tx *gorm.DB, m *Post, changeType PostChangeType) error {
+	seq, err := nextPostChangeSeq(tx)
+	if err != nil {
+		return err
+	}
+	if err := tx.Create(&PostChange{Seq: seq, PostID: m.ID, AuthorID: m.CreatedBy, Type: changeType, Status: m.Status}).Error; err != nil {
+		return err
+	}
+	return recordEvent(tx, EventType("post."+string(changeType)), newPostEventData(m))
+}
+
+// nextPostChangeSeq takes the next number of the change log. The update
+// locks the counter row until tx ends, so changes commit in the order of
+// their numbers: once a reader sees a number, it has seen every smaller one.
+func nextPostChangeSeq(tx *gorm.DB) (uint64, error) {
+	query := tx.Table(postChangeSequenceTable).Where("id = ?", 1).UpdateColumn("value", gorm.Expr("value + 1"))
+	if query.Error != nil {
+		return 0, query.Error
+	}
+	if query.RowsAffected == 0 {
+		return 0, errNoPostChangeSequence
+	}
+	var seq uint64
+	err := tx.Table(postChangeSequenceTable).Select("value").Where("id = ?", 1).Scan(&seq).Error
+	return seq, err
+}
+
+// migratePostChangeSeq numbers the changes logged before PostChange.Seq
+// existed by their ID, which readers resumed from until then, and creates
+// the counter row after the last number.
+func migratePostChangeSeq(db *gorm.DB) error {
+	if db.Migrator().HasTable(&PostChange{}) && !db.Migrator().HasColumn(&PostChange{}, "Seq") {
+		if err := db.Migrator().AddColumn(&PostChange{}, "Seq"); err != nil {
+			return err
+		}
+		if err := db.Table(postChangeTable).Where("seq IS NULL OR seq = 0").UpdateColumn("seq", gorm.Expr("id")).Error; err != nil {
+			return err
+		}
+	}
+	if err := db.AutoMigrate(&postChangeSequence{}, &PostChange{}); err != nil {
+		return err
+	}
+
+	var count int64
+	if err := db.Table(postChangeSequenceTable).Count(&count).Error; err != nil || count > 0 {
+		return err
+	}
+	last, err := lastPostChangeSeq(db)
+	if err != nil {
+		return err
+	}
+	return db.Create(&postChangeSequence{ID: 1, Value: last}).Error
+}
+
+// GetPostChanges returns up to limit changes logged after the change afterSeq, oldest first.
+func GetPostChanges(m *[]PostChange, afterSeq uint64, filter PostChangeFilter, limit int) (err error) {
+	query := config.DB.Table(postChangeTable).Where("seq > ?", afterSeq)
+
+	if filter.AuthorID != 0 {
+		query = query.Where("author_id = ?", filter.AuthorID)
+	}
+
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+
+	query = query.Order("seq").Limit(limit).Find(m)
+	return query.Error
+}
+
+// LastPostChangeSeq returns the Seq of the latest change, 0 when none was logged.
+func LastPostChangeSeq() (seq uint64, err error) {
+	return lastPostChangeSeq(config.DB)
+}
+
+func lastPostChangeSeq(db *gorm.DB) (seq uint64, err error) {
+	query := db.Table(postChangeTable).Select("COALESCE(MAX(seq), 0)").Scan(&seq)
+	return seq, query.Error
+}
@@ -0,0 +1,133 @@
+package models
+
+import (
+	"fmt"
+	"mas-diq/go-graphql/config"
+	"mas-diq/go-graphql/textdiff"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// PostRevision is a full snapshot of a post, taken every time it changes.
+type PostRevision struct {
+	ID        uint       `json:"id" gorm:"primarykey"`
+	PostID    uint       `json:"postId" gorm:"not null;uniqueIndex:idx_post_revision"`
+	Revision  int        `json:"revision" gorm:"not null;uniqueIndex:idx_post_revision"`
+	EditorID  *uint      `json:"editorId"` // nil for changes made by the system, e.g. scheduled publication
+	Title     string     `json:"title" gorm:"size:255;not null"`
+	Slug      string     `json:"slug" gorm:"size:191"`
+	Subtitle  string     `json:"subtitle" gorm:"size:255"`
+	Image     string     `json:"image" gorm:"size:255"`
+	Content   string     `json:"content" gorm:"type:text;not null"`
+	Status    PostStatus `json:"status" gorm:"size:20"`
+	CreatedAt time.Time  `json:"createdAt"`
+}
+
+const postRevisionTable = "post_revisions"
+
+func (r *PostRevision) TableName() string {
+	return postRevisionTable
+}
+
+func (r *PostRevision) sameContent(m *Post) bool {
+	return r.Title == m.Title && r.Slug == m.Slug && r.Subtitle == m.Subtitle &&
+		r.Image == m.Image && r.Content == m.Content && r.Status == m.Status
+}
+
+// CanViewRevisions reports whether viewer may read the revisions of post.
+// They hold its drafts, so like unpublished posts (see CanViewPost) they are
+// only shown to its author and to moderators.
+func CanViewRevisions(viewer *User, post *Post) bool {
+	return viewer.IsModerator() || (viewer != nil && viewer.ID == post.CreatedBy)
+}
+
+// recordRevision stores a snapshot of the post unless nothing changed since the last one.
+// The post row is locked first, so that concurrent writers number their
+// revisions one after the other rather than both taking the last one plus one.
+func recordRevision(tx *gorm.DB, m *Post, editor *User) error {
+	var locked []uint
+	if err := tx.Table(postTable).Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", m.ID).Pluck("id", &locked).Error; err != nil {
+		return err
+	}
+
+	var last PostRevision
+	err := tx.Table(postRevisionTable).Where("post_id = ?", m.ID).Order("revision DESC").Limit(1).Find(&last).Error
+	if err != nil {
+		return err
+	}
+	if last.ID != 0 && last.sameContent(m) {
+		return nil
+	}
+
+	revision := PostRevision{
+		PostID:   m.ID,
+		Revision: last.Revision + 1,
+		Title:    m.Title,
+		Slug:     m.Slug,
+		Subtitle: m.Subtitle,
+		Image:    m.Image,
+		Content:  m.Content,
+		Status:   m.Status,
+	}
+	if editor != nil {
+		revision.EditorID = &editor.ID
+	}
+	return tx.Create(&revision).Error
+}
+
+func GetPostRevisions(m *[]PostRevision, postID uint64) (err error) {
+	query := config.DB.
+		Table(postRevisionTable).
+		Where("post_id = ?", postID).
+		Order("revision DESC").
+		Find(m)
+	return query.Error
+}
+
+func GetOnePostRevision(m *PostRevision, postID uint64, revision int) (err error) {
+	query := config.DB.
+		Table(postRevisionTable).
+		Where("post_id = ? AND revision = ?", postID, revision).
+		First(m)
+	return query.Error
+}
+
+// render turns a revision into the plain text that DiffRevisions compares.
+func (r *PostRevision) render() string {
+	return fmt.Sprintf("title: %s\nslug: %s\nsubtitle: %s\nimage: %s\nstatus: %s\n\n%s",
+		r.Title, r.Slug, r.Subtitle, r.Image, r.Status, r.Content)
+}
+
+// DiffRevisions returns a unified line diff between two revisions of a post.
+func DiffRevisions(postID uint64, from, to int) (string, error) {
+	var a, b PostRevision
+	if err := GetOnePostRevision(&a, postID, from); err != nil {
+		return "", err
+	}
+	if err := GetOnePostRevision(&b, postID, to); err != nil {
+		return "", err
+	}
+	return textdiff.Unified(a.render(), b.render(), fmt.Sprintf("revision %d", from), fmt.Sprintf("revision %d", to), 3), nil
+}
+
+// RestoreRevision copies the title, subtitle, image and content of an old
+// revision back onto the post, which creates a new revision. Status and slug
+// are left alone; they have their own workflows.
+func RestoreRevision(m *Post, revision int, editor *User) error {
+	if editor == nil || (editor.ID != m.CreatedBy && editor.Role != RoleAdmin) {
+		return ErrForbidden
+	}
+
+	var old PostRevision
+	if err := GetOnePostRevision(&old, uint64(m.ID), revision); err != nil {
+		return err
+	}
+
+	m.Title = old.Title
+	m.Subtitle = old.Subtitle
+	m.Image = old.Image
+	m.Content = old.Content
+	return UpdatePostData(m, editor, nil)
+}
@@ -0,0 +1,204 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"mas-diq/go-graphql/config"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+	"gorm.io/gorm"
+)
+
+// PostSlug keeps the slugs a post used before, so old links keep working.
+type PostSlug struct {
+	ID        uint      `json:"id" gorm:"primarykey"`
+	PostID    uint      `json:"postId" gorm:"not null;index"`
+	Slug      string    `json:"slug" gorm:"size:191;not null;uniqueIndex"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+const postSlugTable = "post_slugs"
+
+func (s *PostSlug) TableName() string {
+	return postSlugTable
+}
+
+// ErrSlugTaken is returned when a slug is used by another post, now or in the past.
+var ErrSlugTaken = errors.New("slug is already taken")
+
+// maxSlugLength leaves room for a "-N" suffix within the 191 char index limit.
+const maxSlugLength = 180
+
+var slugPattern = regexp.MustCompile(`^[a-z0-9]+(?:-[a-z0-9]+)*$`)
+
+// Letters that don't decompose into an ASCII base letter plus accents.
+// Keys are lowercase; Slugify lowercases before the lookup.
+var transliterations = map[rune]string{
+	'ß': "ss", 'æ': "ae", 'ø': "o", 'œ': "oe", 'ł': "l", 'đ': "d", 'ð': "d", 'þ': "th",
+	'&': " and ",
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "e", 'ж': "zh",
+	'з': "z", 'и': "i", 'й': "y", 'к': "k", 'л': "l", 'м': "m", 'н': "n", 'о': "o",
+	'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u", 'ф': "f", 'х': "h", 'ц': "ts",
+	'ч': "ch", 'ш': "sh", 'щ': "sch", 'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "yu",
+	'я': "ya",
+}
+
+// IsValidSlug reports whether s is a lowercase, dash separated slug.
+func IsValidSlug(s string) bool {
+	return len(s) <= maxSlugLength && slugPattern.MatchString(s)
+}
+
+// Slugify turns a title into a URL friendly slug, e.g. "Crème Brûlée!" -> "creme-brulee".
+func Slugify(title string) string {
+	var expanded strings.Builder
+	for _, r := range title {
+		r = unicode.ToLower(r)
+		if t, ok := transliterations[r]; ok {
+			expanded.WriteString(t)
+		} else {
+			expanded.WriteRune(r)
+		}
+	}
+
+	var b strings.Builder
+	dash := false
+	// NFD splits "é" into "e" plus a combining accent, which we then drop.
+	for _, r := range norm.NFD.String(expanded.String()) {
+		switch {
+		case unicode.Is(unicode.Mn, r):
+			continue
+		case r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			dash = false
+			b.WriteRune(r)
+		default:
+			dash = true
+		}
+	}
+
+	slug := b.String()
+	if len(slug) > maxSlugLength {
+		slug = strings.TrimRight(slug[:maxSlugLength], "-")
+	}
+	if slug == "" {
+		slug = "post"
+	}
+	return slug
+}
+
+// slugInUse reports whether a slug belongs to a post other than postID,
+// either as its current slug or in its history. Deleted posts count, as the
+// unique index still holds their slugs.
+func slugInUse(db *gorm.DB, slug string, postID uint) (bool, error) {
+	var count int64
+	if err := db.Unscoped().Model(&Post{}).Where("slug = ? AND id <> ?", slug, postID).Count(&count).Error; err != nil {
+		return false, err
+	}
+	if count > 0 {
+		return true, nil
+	}
+	if err := db.Model(&PostSlug{}).Where("slug = ? AND post_id <> ?", slug, postID).Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// uniqueSlug returns base, or base-2, base-3... whichever is free first.
+func uniqueSlug(db *gorm.DB, base string, postID uint) (string, error) {
+	slug := base
+	for i := 2; ; i++ {
+		taken, err := slugInUse(db, slug, postID)
+		if err != nil {
+			return "", err
+		}
+		if !taken {
+			return slug, nil
+		}
+		slug = fmt.Sprintf("%s-%d", base, i)
+	}
+}
+
+// assignSlug fills in the slug of a new post, generating it from the title
+// unless the author picked one.
+func assignSlug(db *gorm.DB, m *Post) error {
+	if m.Slug != "" {
+		taken, err := slugInUse(db, m.Slug, m.ID)
+		if err != nil {
+			return err
+		}
+		if taken {
+			return ErrSlugTaken
+		}
+		return nil
+	}
+
+	slug, err := uniqueSlug(db, Slugify(m.Title), m.ID)
+	if err != nil {
+		return err
+	}
+	m.Slug = slug
+	return nil
+}
+
+// changeSlug records the previous slug of a post when the author edits it.
+func changeSlug(tx *gorm.DB, m *Post, previous string) error {
+	if m.Slug == previous || previous == "" {
+		return nil
+	}
+
+	taken, err := slugInUse(tx, m.Slug, m.ID)
+	if err != nil {
+		return err
+	}
+	if taken {
+		return ErrSlugTaken
+	}
+
+	// Reclaiming one of the post's own old slugs removes it from the history.
+	if err := tx.Where("post_id = ? AND slug = ?", m.ID, m.Slug).Delete(&PostSlug{}).Error; err != nil {
+		return err
+	}
+	return tx.Create(&PostSlug{PostID: m.ID, Slug: previous}).Error
+}
+
+// GetPostBySlug loads a post by its current slug or, failing that, by one of
+// its old slugs. moved is true in the latter case so callers can redirect.
+func GetPostBySlug(m *Post, slug string) (moved bool, err error) {
+	err = config.DB.Table(postTable).Preload("Tags").Where("slug = ?", slug).First(m).Error
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, err
+	}
+
+	var old PostSlug
+	if err := config.DB.Table(postSlugTable).Where("slug = ?", slug).First(&old).Error; err != nil {
+		return false, err
+	}
+	if err := config.DB.Table(postTable).Preload("Tags").Where("id = ?", old.PostID).First(m).Error; err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// backfillSlugs gives every post without a slug one generated from its title.
+func backfillSlugs(db *gorm.DB) error {
+	var posts []Post
+	if err := db.Table(postTable).Where("slug IS NULL OR slug = ''").Find(&posts).Error; err != nil {
+		return err
+	}
+	for i := range posts {
+		slug, err := uniqueSlug(db, Slugify(posts[i].Title), posts[i].ID)
+		if err != nil {
+			return err
+		}
+		if err := db.Model(&posts[i]).UpdateColumn("slug", slug).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,275 @@
+package models
+
+import (
+	"errors"
+	"mas-diq/go-graphql/config"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+	"gorm.io/gorm"
+)
+
+type Tag struct {
+	ID        uint      `json:"id" gorm:"primarykey"`
+	Name      string    `json:"name" gorm:"size:50;not null;uniqueIndex"`
+	Slug      string    `json:"slug" gorm:"size:64;not null;uniqueIndex"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// PostTag is the join table between posts and tags.
+type PostTag struct {
+	PostID    uint      `json:"postId" gorm:"primaryKey"`
+	TagID     uint      `json:"tagId" gorm:"primaryKey;index"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// TagCount is a tag with the number of published posts using it, for tag clouds.
+type TagCount struct {
+	Tag
+	PostCount int64 `json:"postCount"`
+}
+
+const (
+	tagTable     = "tags"
+	postTagTable = "post_tags"
+)
+
+func (t *Tag) TableName() string {
+	return tagTable
+}
+
+func (pt *PostTag) TableName() string {
+	return postTagTable
+}
+
+var (
+	// ErrTagTaken is returned when another tag already has the same name or slug.
+	ErrTagTaken = errors.New("tag already exists")
+	// ErrInvalidTagName is returned for tag names without a letter or a digit.
+	ErrInvalidTagName = errors.New("tag name must contain a letter or a number")
+)
+
+// maxTagSlugLength is the size of the tags.slug column.
+const maxTagSlugLength = 64
+
+// TagSlug normalises a tag name or slug the way tags are matched, e.g.
+// "Go Lang" -> "go-lang". Unlike Slugify it keeps letters of any script and
+// accents, and the "+" and "#" of names like "C++" and "C#", which would
+// otherwise all end up as "c". Names without a letter or a digit give "".
+func TagSlug(name string) string {
+	var b strings.Builder
+	dash, meaningful := false, false
+	for _, r := range norm.NFC.String(strings.ToLower(name)) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsMark(r) || r == '+' || r == '#':
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			dash = false
+			meaningful = meaningful || r != '+' && r != '#'
+			b.WriteRune(r)
+		default:
+			dash = true
+		}
+	}
+	if !meaningful {
+		return ""
+	}
+
+	slug := b.String()
+	for len(slug) > maxTagSlugLength {
+		_, size := utf8.DecodeLastRuneInString(slug)
+		slug = slug[:len(slug)-size]
+	}
+	return strings.TrimRight(slug, "-")
+}
+
+// reslugTags updates tags whose slug was made by an older TagSlug. A tag
+// whose new slug is taken by another one keeps its old slug.
+func reslugTags(db *gorm.DB) error {
+	var tags []Tag
+	if err := db.Table(tagTable).Find(&tags).Error; err != nil {
+		return err
+	}
+	taken := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		taken[tag.Slug] = true
+	}
+	for _, tag := range tags {
+		slug := TagSlug(tag.Name)
+		if slug == "" || slug == tag.Slug || taken[slug] {
+			continue
+		}
+		if err := db.Model(&tag).UpdateColumn("slug", slug).Error; err != nil {
+			return err
+		}
+		taken[slug] = true
+	}
+	return nil
+}
+
+func translateTagError(err error) error {
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return ErrTagTaken
+	}
+	return err
+}
+
+func CreateTagData(m *Tag) (err error) {
+	m.Name = strings.TrimSpace(m.Name)
+	m.Slug = TagSlug(m.Name)
+	if m.Slug == "" {
+		return ErrInvalidTagName
+	}
+	return translateTagError(config.DB.Create(m).Error)
+}
+
+func UpdateTagData(m *Tag) (err error) {
+	m.Name = strings.TrimSpace(m.Name)
+	m.Slug = TagSlug(m.Name)
+	if m.Slug == "" {
+		return ErrInvalidTagName
+	}
+	return translateTagError(config.DB.Save(m).Error)
+}
+
+func GetOneTag(m *Tag, id uint64) (err error) {
+	query := config.DB.
+		Table(tagTable).
+		Where("id = ?", id).
+		First(m)
+	return query.Error
+}
+
+// DeleteTag removes the tag and detaches it from all posts.
+func DeleteTag(m *Tag) (err error) {
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("tag_id = ?", m.ID).Delete(&PostTag{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(m).Error
+	})
+}
+
+// GetTagCounts lists tags with their number of published posts, most used first.
+// A limit of 0 returns all tags.
+func GetTagCounts(m *[]TagCount, limit int) (err error) {
+	query := config.DB.
+		Table(tagTable).
+		Select("tags.*, COUNT(posts.id) AS post_count").
+		Joins("LEFT JOIN post_tags ON post_tags.tag_id = tags.id").
+		Joins("LEFT JOIN posts ON posts.id = post_tags.post_id AND posts.status = ? AND posts.deleted_at IS NULL", Published).
+		Group("tags.id").
+		Order("post_count DESC, tags.name ASC")
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	return query.Find(m).Error
+}
+
+// SetPostTags replaces the tags of a post, creating tags that don't exist yet.
+func SetPostTags(m *Post, names []string) (err error) {
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		changed, err := setPostTags(tx, m, names)
+		if err != nil || !changed {
+			return err
+		}
+		return recordChange(tx, m, PostChangeUpdated)
+	})
+}
+
+// setPostTags replaces the tags of a post within tx, and reports whether they changed.
+func setPostTags(tx *gorm.DB, m *Post, names []string) (changed bool, err error) {
+	tags := make([]Tag, 0, len(names))
+	seen := map[string]bool{}
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		// Names without a letter or a digit are skipped like blank ones.
+		slug := TagSlug(name)
+		if slug == "" || seen[slug] {
+			continue
+		}
+		seen[slug] = true
+
+		var tag Tag
+		if err := tx.Where(Tag{Slug: slug}).Attrs(Tag{Name: name}).FirstOrCreate(&tag).Error; err != nil {
+			return false, translateTagError(err)
+		}
+		tags = append(tags, tag)
+	}
+
+	var previous []uint
+	if err := tx.Table(postTagTable).Where("post_id = ?", m.ID).Pluck("tag_id", &previous).Error; err != nil {
+		return false, err
+	}
+	if err := tx.Model(m).Association("Tags").Replace(tags); err != nil {
+		return false, err
+	}
+	m.Tags = tags
+	return !sameTags(previous, tags), nil
+}
+
+// sameTags reports whether tags are exactly the tags with the given IDs.
+func sameTags(ids []uint, tags []Tag) bool {
+	if len(ids) != len(tags) {
+		return false
+	}
+	current := make(map[uint]bool, len(tags))
+	for _, tag := range tags {
+		current[tag.ID] = true
+	}
+	for _, id := range ids {
+		if !current[id] {
+			return false
+		}
+	}
+	return true
+}
+
+// WithAnyTag is a query scope keeping posts that have at least one of the tags (by slug).
+func WithAnyTag(slugs []string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		normalised := normaliseTagSlugs(slugs)
+		if len(normalised) == 0 {
+			return db
+		}
+		sub := config.DB.Table(postTagTable).
+			Select("post_tags.post_id").
+			Joins("JOIN tags ON tags.id = post_tags.tag_id").
+			Where("tags.slug IN ?", normalised)
+		return db.Where("posts.id IN (?)", sub)
+	}
+}
+
+// WithAllTags is a query scope keeping posts that have every one of the tags (by slug).
+func WithAllTags(slugs []string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		normalised := normaliseTagSlugs(slugs)
+		if len(normalised) == 0 {
+			return db
+		}
+		sub := config.DB.Table(postTagTable).
+			Select("post_tags.post_id").
+			Joins("JOIN tags ON tags.id = post_tags.tag_id").
+			Where("tags.slug IN ?", normalised).
+			Group("post_tags.post_id").
+			Having("COUNT(DISTINCT tags.id) = ?", len(normalised))
+		return db.Where("posts.id IN (?)", sub)
+	}
+}
+
+func normaliseTagSlugs(values []string) []string {
+	seen := map[string]bool{}
+	slugs := make([]string, 0, len(values))
+	for _, v := range values {
+		if slug := TagSlug(v); slug != "" && !seen[slug] {
+			seen[slug] = true
+			slugs = append(slugs, slug)
+		}
+	}
+	return slugs
+}
@@ -0,0 +1,63 @@
+package models
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestTagSlug(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{name: "Go", want: "go"},
+		{name: "  Go Lang ", want: "go-lang"},
+		{name: "web/dev", want: "web-dev"},
+		{name: "C++", want: "c++"},
+		{name: "C#", want: "c#"},
+		{name: "C", want: "c"},
+		{name: "F# & .NET", want: "f#-net"},
+		{name: "Crème Brûlée", want: "crème-brûlée"},
+		{name: "Crème", want: "crème"},
+		{name: "Москва", want: "москва"},
+		{name: "日本語", want: "日本語"},
+		{name: "Web 3.0", want: "web-3-0"},
+		{name: "!!!", want: ""},
+		{name: "++", want: ""},
+		{name: "", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := TagSlug(tt.name); got != tt.want {
+				t.Fatalf("TagSlug(%q) = %q, want %q", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTagSlugLength(t *testing.T) {
+	slug := TagSlug(strings.Repeat("é", 40))
+	if len(slug) > maxTagSlugLength || slug != strings.Repeat("é", maxTagSlugLength/2) {
+		t.Fatalf("TagSlug = %q (%d bytes)", slug, len(slug))
+	}
+	if slug := TagSlug(strings.Repeat("a", 63) + " b"); slug != strings.Repeat("a", 63) {
+		t.Fatalf("TagSlug = %q, want no trailing dash", slug)
+	}
+}
+
+func TestNormaliseTagSlugs(t *testing.T) {
+	got := normaliseTagSlugs([]string{"Go", " go ", "C++", "c#", "", "!!"})
+	want := []string{"go", "c++", "c#"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("normaliseTagSlugs = %q, want %q", got, want)
+	}
+}
+
+func TestCreateTagDataRejectsNamesWithoutLetters(t *testing.T) {
+	for _, name := range []string{"!!!", "+#", "  "} {
+		if err := CreateTagData(&Tag{Name: name}); !errors.Is(err, ErrInvalidTagName) {
+			t.Errorf("CreateTagData(%q) = %v, want %v", name, err, ErrInvalidTagName)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"mas-diq/go-graphql/config"
+	"time"
+)
+
+// Upload is an image stored through the storage backend, with its thumbnail.
+// Posts refer to it by putting its Key in Post.Image.
+type Upload struct {
+	ID              uint      `json:"id" gorm:"primarykey"`
+	Key             string    `json:"key" gorm:"size:191;not null;uniqueIndex"`
+	ContentType     string    `json:"contentType" gorm:"size:50;not null"`
+	Size            int64     `json:"size"`
+	Width           int       `json:"width"`
+	Height          int       `json:"height"`
+	ThumbnailKey    string    `json:"thumbnailKey" gorm:"size:191"`
+	ThumbnailWidth  int       `json:"thumbnailWidth"`
+	ThumbnailHeight int       `json:"thumbnailHeight"`
+	UploadedBy      uint      `json:"uploadedBy" gorm:"not null;index"`
+	CreatedAt       time.Time `json:"createdAt"`
+}
+
+const uploadTable = "uploads"
+
+func (u *Upload) TableName() string {
+	return uploadTable
+}
+
+func CreateUploadData(m *Upload) (err error) {
+	return config.DB.Table(uploadTable).Create(m).Error
+}
+
+func GetOneUpload(m *Upload, id uint64) (err error) {
+	query := config.DB.
+		Table(uploadTable).
+		Where("id = ?", id).
+		First(m)
+	return query.Error
+}
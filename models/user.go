@@ -1,16 +1,27 @@
 package models
 
 import (
+	"errors"
 	"mas-diq/go-graphql/config"
+	"strings"
 
 	"gorm.io/gorm"
 )
 
+type UserRole string
+
+const (
+	RoleUser      UserRole = "user"
+	RoleModerator UserRole = "moderator"
+	RoleAdmin     UserRole = "admin"
+)
+
 type User struct {
 	gorm.Model
-	Name  string `json:"name" gorm:"size:100;not null"`
-	Email string `json:"email" gorm:"size:255;unique;not null"`
-	Posts []Post `json:"posts" gorm:"foreignKey:CreatedBy"`
+	Name  string   `json:"name" gorm:"size:100;not null"`
+	Email string   `json:"email" gorm:"size:255;unique;not null"`
+	Role  UserRole `json:"role" gorm:"type:enum('user','moderator','admin');default:'user'"`
+	Posts []Post   `json:"posts" gorm:"foreignKey:CreatedBy"`
 }
 
 const userTable = "users"
@@ -19,20 +30,87 @@ func (u *User) TableName() string {
 	return userTable
 }
 
-func CreateUserData(m *User) (err error) {
-	if err = config.DB.Create(m).Error; err != nil {
-		return err
+// IsModerator reports whether the user may moderate content of others.
+func (u *User) IsModerator() bool {
+	return u != nil && (u.Role == RoleModerator || u.Role == RoleAdmin)
+}
+
+// ErrEmailTaken is returned when another user already registered the email.
+var ErrEmailTaken = errors.New("email is already taken")
+
+// NormalizeEmail trims and lowercases an email so lookups and the unique
+// index are case-insensitive. With config.App.Users.StripEmailPlusTag it also
+// drops the "+tag" part of the local name.
+func NormalizeEmail(email string) string {
+	email = strings.ToLower(strings.TrimSpace(email))
+
+	if config.App.Users.StripEmailPlusTag {
+		at := strings.LastIndex(email, "@")
+		if at > 0 {
+			local, domain := email[:at], email[at:]
+			if plus := strings.Index(local, "+"); plus > 0 {
+				local = local[:plus]
+			}
+			email = local + domain
+		}
 	}
-	return nil
+	return email
 }
 
-func UpdateUserData(m *User) (err error) {
-	if err = config.DB.Save(m).Error; err != nil {
+// IsEmailAvailable reports whether no user other than excludeID uses the
+// email. Deleted users count, as the unique index still holds their emails.
+func IsEmailAvailable(email string, excludeID uint) (bool, error) {
+	var count int64
+	query := config.DB.Unscoped().Model(&User{}).Where("email = ?", NormalizeEmail(email))
+	if excludeID != 0 {
+		query = query.Where("id <> ?", excludeID)
+	}
+	if err := query.Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count == 0, nil
+}
+
+// saveUser normalises the email, checks it is free and runs the write.
+// The pre-check gives a friendly error in the common case; the unique index
+// still catches concurrent signups, which is translated to ErrEmailTaken too.
+func saveUser(m *User, write func(*gorm.DB) error) error {
+	m.Email = NormalizeEmail(m.Email)
+
+	available, err := IsEmailAvailable(m.Email, m.ID)
+	if err != nil {
+		return err
+	}
+	if !available {
+		return ErrEmailTaken
+	}
+
+	if err := write(config.DB); err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return ErrEmailTaken
+		}
 		return err
 	}
 	return nil
 }
 
+func CreateUserData(m *User) (err error) {
+	return saveUser(m, func(db *gorm.DB) error {
+		return db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Create(m).Error; err != nil {
+				return err
+			}
+			return recordEvent(tx, EventUserCreated, newUserEventData(m))
+		})
+	})
+}
+
+func UpdateUserData(m *User) (err error) {
+	return saveUser(m, func(db *gorm.DB) error {
+		return db.Save(m).Error
+	})
+}
+
 func GetListUser(m *[]User, filter User) (err error) {
 	query := config.DB.Table(userTable)
 
@@ -45,7 +123,7 @@ func GetListUser(m *[]User, filter User) (err error) {
 	}
 
 	if filter.Email != "" {
-		query = query.Where("email = ?", filter.Email)
+		query = query.Where("email = ?", NormalizeEmail(filter.Email))
 	}
 
 	query = query.Find(m)
@@ -61,6 +139,10 @@ func GetOneUser(m *User, id uint64) (err error) {
 }
 
 func DeleteUser(m *User) (err error) {
-	query := config.DB.Table(userTable).Delete(m)
-	return query.Error
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Table(userTable).Delete(m).Error; err != nil {
+			return err
+		}
+		return recordEvent(tx, EventUserDeleted, newUserEventData(m))
+	})
 }
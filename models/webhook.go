@@ -0,0 +1,206 @@
+package models
+
+import (
+	"mas-diq/go-graphql/config"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Webhook is an endpoint that receives domain events. Deliveries are signed
+// with its secret (see the webhooks package).
+type Webhook struct {
+	gorm.Model
+	URL         string `json:"url" gorm:"size:2048;not null"`
+	Description string `json:"description" gorm:"size:255"`
+	Secret      string `json:"-" gorm:"size:64;not null"`
+	// Events is a comma separated list of event types, "*" for all of them.
+	Events    string `json:"events" gorm:"size:255;not null"`
+	Active    bool   `json:"active" gorm:"not null"`
+	CreatedBy uint   `json:"createdBy" gorm:"not null"`
+}
+
+const webhookTable = "webhooks"
+
+func (w *Webhook) TableName() string {
+	return webhookTable
+}
+
+// EventList returns the event types the webhook subscribed to.
+func (w *Webhook) EventList() []string {
+	return strings.Split(w.Events, ",")
+}
+
+// Wants reports whether the webhook subscribed to the event type.
+func (w *Webhook) Wants(eventType EventType) bool {
+	for _, name := range w.EventList() {
+		if name == "*" || name == string(eventType) {
+			return true
+		}
+	}
+	return false
+}
+
+type DeliveryStatus string
+
+const (
+	// DeliveryPending deliveries are waiting for their next attempt.
+	DeliveryPending DeliveryStatus = "pending"
+	// DeliveryDelivered deliveries were accepted with a 2xx response.
+	DeliveryDelivered DeliveryStatus = "delivered"
+	// DeliveryDead deliveries failed every attempt; they stay until replayed.
+	DeliveryDead DeliveryStatus = "dead"
+)
+
+// WebhookDelivery is the delivery of one event to one webhook.
+type WebhookDelivery struct {
+	ID            uint64         `json:"id" gorm:"primarykey"`
+	WebhookID     uint           `json:"webhookId" gorm:"not null;index"`
+	EventID       uint64         `json:"eventId" gorm:"not null;index"`
+	Status        DeliveryStatus `json:"status" gorm:"size:20;not null;index:idx_delivery_due"`
+	Attempts      int            `json:"attempts" gorm:"not null;default:0"`
+	NextAttemptAt *time.Time     `json:"nextAttemptAt" gorm:"index:idx_delivery_due"`
+	LastStatus    int            `json:"lastStatus"` // HTTP status of the last attempt, 0 when there was no response
+	LastError     string         `json:"lastError" gorm:"size:1024"`
+	DeliveredAt   *time.Time     `json:"deliveredAt"`
+	CreatedAt     time.Time      `json:"createdAt"`
+	UpdatedAt     time.Time      `json:"updatedAt"`
+
+	Event   OutboxEvent `json:"-" gorm:"foreignKey:EventID"`
+	Webhook Webhook     `json:"-" gorm:"foreignKey:WebhookID"`
+}
+
+const webhookDeliveryTable = "webhook_deliveries"
+
+func (d *WebhookDelivery) TableName() string {
+	return webhookDeliveryTable
+}
+
+func CreateWebhookData(m *Webhook) (err error) {
+	query := config.DB.Table(webhookTable).Create(m)
+	return query.Error
+}
+
+func UpdateWebhookData(m *Webhook) (err error) {
+	query := config.DB.Table(webhookTable).Save(m)
+	return query.Error
+}
+
+func GetListWebhook(m *[]Webhook) (err error) {
+	query := config.DB.Table(webhookTable).Order("id").Find(m)
+	return query.Error
+}
+
+// GetActiveWebhooks returns the webhooks that receive events.
+func GetActiveWebhooks(m *[]Webhook) (err error) {
+	query := config.DB.Table(webhookTable).Where("active = ?", true).Find(m)
+	return query.Error
+}
+
+func GetOneWebhook(m *Webhook, id uint64) (err error) {
+	query := config.DB.
+		Table(webhookTable).
+		Where("id = ?", id).
+		First(m)
+	return query.Error
+}
+
+// DeleteWebhook removes the webhook and its deliveries.
+func DeleteWebhook(m *Webhook) (err error) {
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Table(webhookDeliveryTable).Where("webhook_id = ?", m.ID).Delete(&WebhookDelivery{}).Error; err != nil {
+			return err
+		}
+		return tx.Table(webhookTable).Delete(m).Error
+	})
+}
+
+// GetWebhookDeliveries returns the deliveries of a webhook, newest first,
+// optionally only those with a status.
+func GetWebhookDeliveries(m *[]WebhookDelivery, webhookID uint64, status DeliveryStatus, limit int) (err error) {
+	query := config.DB.Table(webhookDeliveryTable).Where("webhook_id = ?", webhookID)
+
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	query = query.Preload("Event").Order("id DESC").Limit(limit).Find(m)
+	return query.Error
+}
+
+func GetOneWebhookDelivery(m *WebhookDelivery, webhookID, id uint64) (err error) {
+	query := config.DB.
+		Table(webhookDeliveryTable).
+		Preload("Event").
+		Where("webhook_id = ? AND id = ?", webhookID, id).
+		First(m)
+	return query.Error
+}
+
+// GetDueDeliveries returns up to limit pending deliveries of active webhooks
+// whose next attempt is due. Deliveries of inactive webhooks wait until the
+// webhook is activated again.
+func GetDueDeliveries(m *[]WebhookDelivery, now time.Time, limit int) (err error) {
+	query := config.DB.
+		Table(webhookDeliveryTable).
+		Select(webhookDeliveryTable+".*").
+		Joins("JOIN "+webhookTable+" ON "+webhookTable+".id = "+webhookDeliveryTable+".webhook_id").
+		Where(webhookTable+".active = ? AND "+webhookTable+".deleted_at IS NULL", true).
+		Where(webhookDeliveryTable+".status = ? AND "+webhookDeliveryTable+".next_attempt_at <= ?", DeliveryPending, now).
+		Preload("Event").
+		Preload("Webhook").
+		Order(webhookDeliveryTable + ".next_attempt_at").
+		Limit(limit).
+		Find(m)
+	return query.Error
+}
+
+// ClaimDelivery pushes the next attempt of a due delivery to lease, so other
+// dispatchers leave it alone while it is being sent. It reports false when
+// another dispatcher claimed it first.
+func ClaimDelivery(m *WebhookDelivery, lease time.Time) (bool, error) {
+	query := config.DB.Table(webhookDeliveryTable).
+		Where("id = ? AND status = ? AND next_attempt_at = ?", m.ID, DeliveryPending, m.NextAttemptAt).
+		Update("next_attempt_at", lease)
+	if query.Error != nil || query.RowsAffected != 1 {
+		return false, query.Error
+	}
+	m.NextAttemptAt = &lease
+	return true, nil
+}
+
+// SaveDeliveryAttempt stores the outcome of an attempt.
+func SaveDeliveryAttempt(m *WebhookDelivery) (err error) {
+	query := config.DB.Table(webhookDeliveryTable).Select(
+		"status", "attempts", "next_attempt_at", "last_status", "last_error", "delivered_at", "updated_at",
+	).Save(m)
+	return query.Error
+}
+
+// ReplayDelivery queues a delivery again, as new, whatever its status.
+func ReplayDelivery(m *WebhookDelivery, now time.Time) (err error) {
+	m.Status = DeliveryPending
+	m.Attempts = 0
+	m.NextAttemptAt = &now
+	m.LastStatus = 0
+	m.LastError = ""
+	m.DeliveredAt = nil
+	return SaveDeliveryAttempt(m)
+}
+
+// ReplayDeadDeliveries queues every dead delivery of a webhook again and
+// returns how many there were.
+func ReplayDeadDeliveries(webhookID uint64, now time.Time) (int64, error) {
+	query := config.DB.Table(webhookDeliveryTable).
+		Where("webhook_id = ? AND status = ?", webhookID, DeliveryDead).
+		Updates(map[string]interface{}{
+			"status":          DeliveryPending,
+			"attempts":        0,
+			"next_attempt_at": now,
+			"last_status":     0,
+			"last_error":      "",
+			"updated_at":      now,
+		})
+	return query.RowsAffected, query.Error
+}
@@ -0,0 +1,178 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"mas-diq/go-graphql/config"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrInvalidTransition is returned when a post can't move from its current status to the requested one.
+	ErrInvalidTransition = errors.New("invalid status transition")
+	// ErrForbidden is returned when the acting user may not change the post.
+	ErrForbidden = errors.New("not allowed to perform this action")
+)
+
+// transitions lists, for each status, the statuses a post may move to.
+var transitions = map[PostStatus][]PostStatus{
+	Draft:     {Scheduled, Published, Archived},
+	Scheduled: {Draft, Published, Archived},
+	Published: {Draft, Archived},
+	Archived:  {Draft},
+}
+
+// CanTransition reports whether a post may move from one status to another.
+func CanTransition(from, to PostStatus) bool {
+	for _, allowed := range transitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// CanChangeStatus reports whether actor may move post to the given status.
+// Authors manage their own posts, moderators may archive any post and admins may do anything.
+func CanChangeStatus(actor *User, post *Post, to PostStatus) bool {
+	switch {
+	case actor == nil:
+		return false
+	case actor.Role == RoleAdmin:
+		return true
+	case actor.ID == post.CreatedBy:
+		return true
+	case actor.Role == RoleModerator:
+		return to == Archived
+	default:
+		return false
+	}
+}
+
+// CanEditPost reports whether actor may change the content or the tags of
+// post, or delete it: its author and moderators may.
+func CanEditPost(actor *User, post *Post) bool {
+	return actor.IsModerator() || (actor != nil && actor.ID == post.CreatedBy)
+}
+
+// CanViewPost reports whether viewer may read post: published posts are
+// public, the others are only shown to their author and to moderators.
+func CanViewPost(viewer *User, post *Post) bool {
+	return post.Status == Published || viewer.IsModerator() || (viewer != nil && viewer.ID == post.CreatedBy)
+}
+
+// VisiblePosts is a query scope hiding the posts viewer may not read, see CanViewPost.
+func VisiblePosts(viewer *User) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		switch {
+		case viewer.IsModerator():
+			return db
+		case viewer != nil:
+			return db.Where("posts.status = ? OR posts.created_by = ?", Published, viewer.ID)
+		default:
+			return db.Where("posts.status = ?", Published)
+		}
+	}
+}
+
+// TransitionPost validates and applies a status change on the post in memory.
+// scheduledFor is only used when moving to Scheduled. The caller saves the post.
+func TransitionPost(m *Post, actor *User, to PostStatus, scheduledFor *time.Time) error {
+	from := m.Status
+	if from == "" {
+		from = Draft
+	}
+	if from == to && to != Scheduled {
+		return nil
+	}
+	if from != to && !CanTransition(from, to) {
+		return fmt.Errorf("%w: %s to %s", ErrInvalidTransition, from, to)
+	}
+	if !CanChangeStatus(actor, m, to) {
+		return ErrForbidden
+	}
+
+	m.Status = to
+	switch to {
+	case Scheduled:
+		if scheduledFor == nil {
+			return fmt.Errorf("%w: a publication time is required to schedule a post", ErrInvalidTransition)
+		}
+		m.ScheduledFor = scheduledFor
+	case Published:
+		now := time.Now()
+		m.PublishedAt = &now
+		m.ScheduledFor = nil
+	default:
+		m.ScheduledFor = nil
+	}
+	return nil
+}
+
+// PublishPost publishes the post now, or schedules it when at lies in the future.
+func PublishPost(m *Post, actor *User, at *time.Time) error {
+	to := Published
+	if at != nil && at.After(time.Now()) {
+		to = Scheduled
+	}
+	if err := TransitionPost(m, actor, to, at); err != nil {
+		return err
+	}
+	return UpdatePostData(m, actor, nil)
+}
+
+// ArchivePost moves the post to the archive.
+func ArchivePost(m *Post, actor *User) error {
+	if err := TransitionPost(m, actor, Archived, nil); err != nil {
+		return err
+	}
+	return UpdatePostData(m, actor, nil)
+}
+
+// PublishDuePosts publishes every scheduled post whose time has come and
+// returns the posts it published. Each post is flipped with a conditional
+// update, so running it concurrently (several instances, or right after a
+// restart) never publishes a post twice.
+func PublishDuePosts(now time.Time) ([]Post, error) {
+	var due []Post
+	if err := config.DB.Table(postTable).
+		Where("status = ? AND scheduled_for <= ?", Scheduled, now).
+		Find(&due).Error; err != nil {
+		return nil, err
+	}
+
+	published := make([]Post, 0, len(due))
+	for _, post := range due {
+		err := config.DB.Transaction(func(tx *gorm.DB) error {
+			query := tx.Table(postTable).
+				Where("id = ? AND status = ?", post.ID, Scheduled).
+				Updates(map[string]interface{}{
+					"status":        Published,
+					"published_at":  post.ScheduledFor,
+					"scheduled_for": gorm.Expr("NULL"),
+					"updated_at":    now,
+				})
+			if query.Error != nil || query.RowsAffected != 1 {
+				return query.Error
+			}
+
+			post.Status = Published
+			post.PublishedAt = post.ScheduledFor
+			post.ScheduledFor = nil
+			if err := recordChange(tx, &post, PostChangePublished); err != nil {
+				return err
+			}
+			if err := recordRevision(tx, &post, nil); err != nil {
+				return err
+			}
+			published = append(published, post)
+			return nil
+		})
+		if err != nil {
+			return published, err
+		}
+	}
+	return published, nil
+}
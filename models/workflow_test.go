@@ -0,0 +1,185 @@
+package models
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+var (
+	author    = &User{Model: gorm.Model{ID: 1}, Role: RoleUser}
+	other     = &User{Model: gorm.Model{ID: 2}, Role: RoleUser}
+	moderator = &User{Model: gorm.Model{ID: 3}, Role: RoleModerator}
+	admin     = &User{Model: gorm.Model{ID: 4}, Role: RoleAdmin}
+)
+
+// dryRun returns a database that builds statements without running them.
+func dryRun(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(mysql.New(mysql.Config{DSN: "user:password@tcp(127.0.0.1:3306)/db", SkipInitializeWithVersion: true}),
+		&gorm.Config{DryRun: true, DisableAutomaticPing: true, SkipDefaultTransaction: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestCanTransition(t *testing.T) {
+	tests := []struct {
+		from, to PostStatus
+		want     bool
+	}{
+		{from: Draft, to: Scheduled, want: true},
+		{from: Draft, to: Published, want: true},
+		{from: Draft, to: Archived, want: true},
+		{from: Scheduled, to: Draft, want: true},
+		{from: Scheduled, to: Published, want: true},
+		{from: Published, to: Draft, want: true},
+		{from: Published, to: Archived, want: true},
+		{from: Published, to: Scheduled},
+		{from: Archived, to: Draft, want: true},
+		{from: Archived, to: Published},
+		{from: Archived, to: Scheduled},
+	}
+	for _, tt := range tests {
+		if got := CanTransition(tt.from, tt.to); got != tt.want {
+			t.Errorf("CanTransition(%s, %s) = %v, want %v", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
+
+func TestCanChangeStatus(t *testing.T) {
+	post := &Post{CreatedBy: author.ID, Status: Published}
+	tests := []struct {
+		name  string
+		actor *User
+		to    PostStatus
+		want  bool
+	}{
+		{name: "anonymous", actor: nil, to: Archived},
+		{name: "author", actor: author, to: Draft, want: true},
+		{name: "other user", actor: other, to: Archived},
+		{name: "moderator archives", actor: moderator, to: Archived, want: true},
+		{name: "moderator unpublishes", actor: moderator, to: Draft},
+		{name: "admin", actor: admin, to: Draft, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CanChangeStatus(tt.actor, post, tt.to); got != tt.want {
+				t.Fatalf("CanChangeStatus = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTransitionPost(t *testing.T) {
+	later := time.Now().Add(time.Hour)
+	tests := []struct {
+		name         string
+		from         PostStatus
+		actor        *User
+		to           PostStatus
+		scheduledFor *time.Time
+		err          error
+	}{
+		{name: "publish", from: Draft, actor: author, to: Published},
+		{name: "new post", from: "", actor: author, to: Published},
+		{name: "schedule", from: Draft, actor: author, to: Scheduled, scheduledFor: &later},
+		{name: "schedule without a time", from: Draft, actor: author, to: Scheduled, err: ErrInvalidTransition},
+		{name: "not allowed", from: Archived, actor: author, to: Published, err: ErrInvalidTransition},
+		{name: "someone else's post", from: Draft, actor: other, to: Published, err: ErrForbidden},
+		{name: "anonymous", from: Draft, actor: nil, to: Published, err: ErrForbidden},
+		{name: "moderator archives", from: Published, actor: moderator, to: Archived},
+		{name: "same status", from: Published, actor: other, to: Published},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			post := &Post{CreatedBy: author.ID, Status: tt.from}
+			err := TransitionPost(post, tt.actor, tt.to, tt.scheduledFor)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("err = %v, want %v", err, tt.err)
+			}
+			if err != nil {
+				return
+			}
+			if post.Status != tt.to {
+				t.Errorf("status = %s, want %s", post.Status, tt.to)
+			}
+			if (post.ScheduledFor != nil) != (tt.to == Scheduled) {
+				t.Errorf("scheduled for %v", post.ScheduledFor)
+			}
+			if tt.to == Published && tt.from != Published && post.PublishedAt == nil {
+				t.Error("publication time not set")
+			}
+		})
+	}
+}
+
+func TestCanEditPost(t *testing.T) {
+	post := &Post{CreatedBy: author.ID, Status: Published}
+	for _, tt := range []struct {
+		name  string
+		actor *User
+		want  bool
+	}{
+		{name: "anonymous"},
+		{name: "author", actor: author, want: true},
+		{name: "other user", actor: other},
+		{name: "moderator", actor: moderator, want: true},
+		{name: "admin", actor: admin, want: true},
+	} {
+		if got := CanEditPost(tt.actor, post); got != tt.want {
+			t.Errorf("%s: CanEditPost = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCanViewPost(t *testing.T) {
+	tests := []struct {
+		name   string
+		viewer *User
+		status PostStatus
+		want   bool
+	}{
+		{name: "published, anonymous", viewer: nil, status: Published, want: true},
+		{name: "draft, anonymous", viewer: nil, status: Draft},
+		{name: "draft, author", viewer: author, status: Draft, want: true},
+		{name: "scheduled, other user", viewer: other, status: Scheduled},
+		{name: "archived, other user", viewer: other, status: Archived},
+		{name: "draft, moderator", viewer: moderator, status: Draft, want: true},
+		{name: "archived, admin", viewer: admin, status: Archived, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			post := &Post{CreatedBy: author.ID, Status: tt.status}
+			if got := CanViewPost(tt.viewer, post); got != tt.want {
+				t.Fatalf("CanViewPost = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVisiblePosts(t *testing.T) {
+	db := dryRun(t)
+	tests := []struct {
+		name   string
+		viewer *User
+		want   string
+	}{
+		{name: "anonymous", viewer: nil, want: "SELECT * FROM `posts` WHERE posts.status = 'published' AND `posts`.`deleted_at` IS NULL"},
+		{name: "user", viewer: other, want: "SELECT * FROM `posts` WHERE (posts.status = 'published' OR posts.created_by = 2) AND `posts`.`deleted_at` IS NULL"},
+		{name: "moderator", viewer: moderator, want: "SELECT * FROM `posts` WHERE `posts`.`deleted_at` IS NULL"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var posts []Post
+			stmt := db.Scopes(VisiblePosts(tt.viewer)).Find(&posts).Statement
+			if got := db.Dialector.Explain(stmt.SQL.String(), stmt.Vars...); got != tt.want {
+				t.Fatalf("query = %s\nwant    %s", got, tt.want)
+			}
+		})
+	}
+}
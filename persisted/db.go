@@ -0,0 +1,32 @@
+package persisted
+
+import (
+	"context"
+	"errors"
+	"mas-diq/go-graphql/models"
+
+	"gorm.io/gorm"
+)
+
+// DBStore keeps persisted queries in the persisted_queries table.
+type DBStore struct{}
+
+func (DBStore) Get(ctx context.Context, hash string) (*Query, error) {
+	var m models.PersistedQuery
+	if err := models.GetOnePersistedQuery(&m, hash); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &Query{Hash: m.Hash, Body: m.Query, OperationName: m.OperationName, Registered: m.Registered}, nil
+}
+
+func (DBStore) Save(ctx context.Context, query Query) error {
+	return models.SavePersistedQuery(&models.PersistedQuery{
+		Hash:          query.Hash,
+		Query:         query.Body,
+		OperationName: query.OperationName,
+		Registered:    query.Registered,
+	})
+}
@@ -0,0 +1,81 @@
+package persisted
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+// DiskStore keeps persisted queries as files named after their hash:
+// registered ones in Dir, automatic ones in Dir/apq. Registered queries can
+// be committed and shipped with the server.
+type DiskStore struct {
+	Dir string
+}
+
+func NewDiskStore(dir string) *DiskStore {
+	return &DiskStore{Dir: dir}
+}
+
+var hashPattern = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+func (s *DiskStore) path(hash string, registered bool) string {
+	if registered {
+		return filepath.Join(s.Dir, hash+".graphql")
+	}
+	return filepath.Join(s.Dir, "apq", hash+".graphql")
+}
+
+func (s *DiskStore) Get(ctx context.Context, hash string) (*Query, error) {
+	// The hash comes from clients; anything else could point outside Dir.
+	if !hashPattern.MatchString(hash) {
+		return nil, ErrNotFound
+	}
+	for _, registered := range []bool{true, false} {
+		body, err := os.ReadFile(s.path(hash, registered))
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return &Query{Hash: hash, Body: string(body), Registered: registered}, nil
+	}
+	return nil, ErrNotFound
+}
+
+func (s *DiskStore) Save(ctx context.Context, query Query) error {
+	if !hashPattern.MatchString(query.Hash) {
+		return errors.New("invalid persisted query hash")
+	}
+	name := s.path(query.Hash, query.Registered)
+	if _, err := os.Stat(name); err == nil {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(name), ".query-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.WriteString(query.Body); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), name); err != nil {
+		return err
+	}
+	if query.Registered {
+		os.Remove(s.path(query.Hash, false))
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+package persisted
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/printer"
+	"github.com/graphql-go/graphql/language/source"
+)
+
+// Manifest lists the operations of a client, in the format of Apollo's
+// persisted query manifests, so clients can send the ids instead of the bodies.
+type Manifest struct {
+	Format     string      `json:"format"`
+	Version    int         `json:"version"`
+	Operations []Operation `json:"operations"`
+}
+
+// Operation is one operation of a manifest. Body is the operation followed by
+// the fragments it uses, and ID the hash of Body.
+type Operation struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	Type string `json:"type"`
+	Body string `json:"body"`
+}
+
+// Extract splits GraphQL documents, keyed by file name, into operations.
+// Fragments may be defined in any of the documents.
+func Extract(documents map[string]string) (*Manifest, error) {
+	names := make([]string, 0, len(documents))
+	for name := range documents {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fragments := map[string]*ast.FragmentDefinition{}
+	var operations []*ast.OperationDefinition
+	for _, name := range names {
+		doc, err := parser.Parse(parser.ParseParams{
+			Source: source.NewSource(&source.Source{Body: []byte(documents[name]), Name: name}),
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, definition := range doc.Definitions {
+			switch definition := definition.(type) {
+			case *ast.FragmentDefinition:
+				if _, ok := fragments[definition.Name.Value]; ok {
+					return nil, fmt.Errorf("%s: fragment %s is defined twice", name, definition.Name.Value)
+				}
+				fragments[definition.Name.Value] = definition
+			case *ast.OperationDefinition:
+				if definition.Name == nil {
+					return nil, fmt.Errorf("%s: operations must be named", name)
+				}
+				operations = append(operations, definition)
+			}
+		}
+	}
+
+	manifest := &Manifest{Format: "apollo-persisted-query-manifest", Version: 1, Operations: []Operation{}}
+	seen := map[string]bool{}
+	for _, operation := range operations {
+		name := operation.Name.Value
+		if seen[name] {
+			return nil, fmt.Errorf("operation %s is defined twice", name)
+		}
+		seen[name] = true
+
+		used := map[string]bool{}
+		if err := collectFragments(operation.SelectionSet, fragments, used); err != nil {
+			return nil, fmt.Errorf("operation %s: %w", name, err)
+		}
+		definitions := []ast.Node{operation}
+		fragmentNames := make([]string, 0, len(used))
+		for fragment := range used {
+			fragmentNames = append(fragmentNames, fragment)
+		}
+		sort.Strings(fragmentNames)
+		for _, fragment := range fragmentNames {
+			definitions = append(definitions, fragments[fragment])
+		}
+
+		printed := make([]string, len(definitions))
+		for i, definition := range definitions {
+			printed[i], _ = printer.Print(definition).(string)
+		}
+		body := strings.Join(printed, "\n\n")
+		manifest.Operations = append(manifest.Operations, Operation{
+			ID:   Hash(body),
+			Name: name,
+			Type: operation.Operation,
+			Body: body,
+		})
+	}
+	return manifest, nil
+}
+
+// collectFragments adds the fragments a selection set uses, directly or through other fragments.
+func collectFragments(set *ast.SelectionSet, fragments map[string]*ast.FragmentDefinition, used map[string]bool) error {
+	if set == nil {
+		return nil
+	}
+	for _, selection := range set.Selections {
+		switch selection := selection.(type) {
+		case *ast.Field:
+			if err := collectFragments(selection.SelectionSet, fragments, used); err != nil {
+				return err
+			}
+		case *ast.InlineFragment:
+			if err := collectFragments(selection.SelectionSet, fragments, used); err != nil {
+				return err
+			}
+		case *ast.FragmentSpread:
+			name := selection.Name.Value
+			if used[name] {
+				continue
+			}
+			fragment, ok := fragments[name]
+			if !ok {
+				return fmt.Errorf("unknown fragment %s", name)
+			}
+			used[name] = true
+			if err := collectFragments(fragment.SelectionSet, fragments, used); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+// Package persisted stores GraphQL documents by the SHA-256 hash of their
+// text, for automatic persisted queries and operation allowlists.
+package persisted
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"mas-diq/go-graphql/config"
+	"sync"
+)
+
+// Query is a stored GraphQL document.
+type Query struct {
+	Hash          string
+	Body          string
+	OperationName string
+	// Registered queries were added from a client manifest; others were
+	// stored automatically when a client first sent them.
+	Registered bool
+}
+
+// Store keeps persisted queries.
+type Store interface {
+	// Get returns the query stored under hash, or ErrNotFound.
+	Get(ctx context.Context, hash string) (*Query, error)
+	// Save stores a query. Saving a registered query registers an existing
+	// automatic one; nothing is ever unregistered.
+	Save(ctx context.Context, query Query) error
+}
+
+// ErrNotFound is returned by Get for unknown hashes.
+var ErrNotFound = errors.New("persisted query not found")
+
+// Hash returns the hex encoded SHA-256 of a query, as Apollo clients compute it.
+func Hash(body string) string {
+	sum := sha256.Sum256([]byte(body))
+	return hex.EncodeToString(sum[:])
+}
+
+// NewStore opens the store selected by the configuration: "db" or "disk".
+func NewStore(cfg config.GraphQLConfig) (Store, error) {
+	switch cfg.PersistedStore {
+	case "db":
+		return &DBStore{}, nil
+	case "disk":
+		return NewDiskStore(cfg.PersistedDir), nil
+	default:
+		return nil, fmt.Errorf("unknown persisted query store %q", cfg.PersistedStore)
+	}
+}
+
+// Cache keeps the most recently found queries of a store in memory.
+type Cache struct {
+	Store
+	size int
+
+	mu      sync.Mutex
+	queries map[string]*Query
+}
+
+// NewCache caches up to size queries of store.
+func NewCache(store Store, size int) *Cache {
+	return &Cache{Store: store, size: size, queries: map[string]*Query{}}
+}
+
+func (c *Cache) Get(ctx context.Context, hash string) (*Query, error) {
+	c.mu.Lock()
+	query, ok := c.queries[hash]
+	c.mu.Unlock()
+	if ok {
+		return query, nil
+	}
+
+	query, err := c.Store.Get(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	if len(c.queries) >= c.size {
+		// Start over rather than track recency; the hot queries return quickly.
+		c.queries = map[string]*Query{}
+	}
+	c.queries[hash] = query
+	c.mu.Unlock()
+	return query, nil
+}
+
+func (c *Cache) Save(ctx context.Context, query Query) error {
+	c.mu.Lock()
+	delete(c.queries, query.Hash)
+	c.mu.Unlock()
+	return c.Store.Save(ctx, query)
+}
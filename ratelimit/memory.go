@@ -0,0 +1,60 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// pruneInterval is how often the memory store forgets the buckets that
+// filled up again.
+const pruneInterval = time.Minute
+
+// MemoryStore keeps the buckets in memory. Each instance meters its own
+// requests, so behind a load balancer clients get a budget per instance.
+type MemoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	pruned  time.Time
+}
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	// full is when the bucket will be full, and equivalent to a new one.
+	full time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: map[string]*bucket{}, pruned: time.Now()}
+}
+
+func (s *MemoryStore) Take(_ context.Context, key string, limit Limit, cost float64) (Result, error) {
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.prune(now)
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: limit.Burst, updated: now}
+		s.buckets[key] = b
+	}
+	tokens, result := take(b.tokens, now.Sub(b.updated), limit, cost)
+	b.tokens, b.updated, b.full = tokens, now, now.Add(result.Reset)
+	return result, nil
+}
+
+// prune drops the buckets that are full, as missing buckets start full, so
+// clients that went away don't hold memory.
+func (s *MemoryStore) prune(now time.Time) {
+	if now.Sub(s.pruned) < pruneInterval {
+		return
+	}
+	s.pruned = now
+	for key, b := range s.buckets {
+		if !now.Before(b.full) {
+			delete(s.buckets, key)
+		}
+	}
+}
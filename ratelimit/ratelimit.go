@@ -0,0 +1,144 @@
+// Package ratelimit meters clients with token buckets. A client has a bucket
+// per budget holding up to Burst tokens, refilled at Rate tokens per second;
+// each request takes its cost from the bucket, or is refused when the bucket
+// doesn't hold that much.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"mas-diq/go-graphql/auth"
+	"mas-diq/go-graphql/logging"
+	"mas-diq/go-graphql/metrics"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+var logger = logging.For("ratelimit")
+
+// Limit is the size of a budget.
+type Limit struct {
+	// Rate is how many tokens are added to a bucket per second. It must be positive.
+	Rate float64
+	// Burst is the capacity of a bucket: the most a client can spend at once.
+	Burst float64
+}
+
+// Result is the state of a bucket after a request took from it.
+type Result struct {
+	Allowed bool
+	Limit   Limit
+	// Remaining is what is left in the bucket.
+	Remaining float64
+	// Reset is how long the bucket takes to fill up again.
+	Reset time.Duration
+	// RetryAfter is how long a refused request must wait for enough tokens.
+	RetryAfter time.Duration
+}
+
+// Store keeps the buckets. Take must be atomic per key, as concurrent
+// requests of a client share its bucket. The in-memory store locks; a store
+// shared by instances, such as Redis, would keep the tokens and update time
+// of a bucket in a hash, refill and take in a Lua script using the server's
+// clock, and expire the hash after Reset, since a missing bucket is a full one.
+type Store interface {
+	// Take refills the bucket of key, created full, for the time elapsed
+	// since its last update, then takes cost from it if it holds that much.
+	Take(ctx context.Context, key string, limit Limit, cost float64) (Result, error)
+}
+
+// take is the token bucket algorithm Store implementations share: tokens
+// are what the bucket held after its last update, elapsed the time since.
+func take(tokens float64, elapsed time.Duration, limit Limit, cost float64) (float64, Result) {
+	tokens = math.Min(limit.Burst, tokens+elapsed.Seconds()*limit.Rate)
+	result := Result{Allowed: tokens >= cost, Limit: limit}
+	if result.Allowed {
+		tokens -= cost
+	} else {
+		result.RetryAfter = refill(cost-tokens, limit.Rate)
+	}
+	result.Remaining = tokens
+	result.Reset = refill(limit.Burst-tokens, limit.Rate)
+	return tokens, result
+}
+
+// refill is how long adding tokens takes at rate.
+func refill(tokens, rate float64) time.Duration {
+	if tokens <= 0 || rate <= 0 {
+		return 0
+	}
+	return time.Duration(tokens / rate * float64(time.Second))
+}
+
+// Limiter charges requests to one budget, such as the REST routes or GraphQL.
+type Limiter struct {
+	Store Store
+	// Name keeps the buckets of the budgets sharing a store apart, e.g. "rest".
+	Name  string
+	Limit Limit
+}
+
+// NewLimiter returns a limiter of the budget name.
+func NewLimiter(store Store, name string, limit Limit) *Limiter {
+	return &Limiter{Store: store, Name: name, Limit: limit}
+}
+
+// Take charges cost to the bucket of client. Costs above the Burst are
+// charged as the Burst, so that any request can run on a full bucket. When
+// the store fails the request is let through, rather than failing them all.
+func (l *Limiter) Take(ctx context.Context, client string, cost float64) Result {
+	cost = math.Min(cost, l.Limit.Burst)
+	result, err := l.Store.Take(ctx, l.Name+":"+client, l.Limit, cost)
+	if err != nil {
+		logger.WarnContext(ctx, "Rate limit store failed, request not limited", "budget", l.Name, "error", err)
+		return Result{Allowed: true, Limit: l.Limit, Remaining: l.Limit.Burst}
+	}
+	if !result.Allowed {
+		metrics.RateLimited.WithLabelValues(l.Name).Inc()
+		logger.DebugContext(ctx, "Rate limited", "budget", l.Name, "client", client, "cost", cost)
+	}
+	return result
+}
+
+// WriteHeaders sets the headers of the IETF RateLimit header fields draft:
+// the capacity of the bucket, what is left of it, the seconds until it is
+// full again and the policy, "<burst>;w=<seconds to fill an empty bucket>".
+// Refused requests also get Retry-After.
+func (r Result) WriteHeaders(header http.Header) {
+	header.Set("RateLimit-Limit", strconv.FormatFloat(math.Floor(r.Limit.Burst), 'f', -1, 64))
+	header.Set("RateLimit-Remaining", strconv.FormatFloat(math.Floor(r.Remaining), 'f', -1, 64))
+	header.Set("RateLimit-Reset", strconv.Itoa(Seconds(r.Reset)))
+	header.Set("RateLimit-Policy", fmt.Sprintf("%.0f;w=%d", math.Floor(r.Limit.Burst), Seconds(refill(r.Limit.Burst, r.Limit.Rate))))
+	if !r.Allowed {
+		header.Set("Retry-After", strconv.Itoa(max(Seconds(r.RetryAfter), 1)))
+	}
+}
+
+// Seconds rounds a duration up to whole seconds, as the headers count them.
+func Seconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+type clientIPKey struct{}
+
+// WithClientIP returns a copy of ctx carrying the address of the client,
+// which anonymous callers are metered by.
+func WithClientIP(ctx context.Context, ip string) context.Context {
+	return context.WithValue(ctx, clientIPKey{}, ip)
+}
+
+// Client names the bucket of the caller of ctx: "apikey:<id>" for services
+// using an API key, so each key has a budget apart from its owner's,
+// "user:<id>" for signed in users and "ip:<address>" for anonymous ones.
+func Client(ctx context.Context) string {
+	if key := auth.APIKeyFromContext(ctx); key != nil {
+		return "apikey:" + strconv.FormatUint(uint64(key.ID), 10)
+	}
+	if user := auth.UserFromContext(ctx); user != nil {
+		return "user:" + strconv.FormatUint(uint64(user.ID), 10)
+	}
+	ip, _ := ctx.Value(clientIPKey{}).(string)
+	return "ip:" + ip
+}
@@ -0,0 +1,108 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestTake(t *testing.T) {
+	limit := Limit{Rate: 2, Burst: 10}
+	tests := []struct {
+		name       string
+		tokens     float64
+		elapsed    time.Duration
+		cost       float64
+		want       float64
+		allowed    bool
+		reset      time.Duration
+		retryAfter time.Duration
+	}{
+		{name: "full bucket", tokens: 10, cost: 1, want: 9, allowed: true, reset: 500 * time.Millisecond},
+		{name: "refill caps at burst", tokens: 5, elapsed: time.Hour, cost: 4, want: 6, allowed: true, reset: 2 * time.Second},
+		{name: "refilled enough", tokens: 0, elapsed: time.Second, cost: 2, want: 0, allowed: true, reset: 5 * time.Second},
+		{name: "refused", tokens: 1, elapsed: 500 * time.Millisecond, cost: 5, want: 2, reset: 4 * time.Second, retryAfter: 1500 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens, result := take(tt.tokens, tt.elapsed, limit, tt.cost)
+			if tokens != tt.want || result.Remaining != tt.want {
+				t.Errorf("tokens = %v, remaining = %v, want %v", tokens, result.Remaining, tt.want)
+			}
+			if result.Allowed != tt.allowed {
+				t.Errorf("allowed = %v, want %v", result.Allowed, tt.allowed)
+			}
+			if result.Reset != tt.reset || result.RetryAfter != tt.retryAfter {
+				t.Errorf("reset = %v, retry after = %v, want %v and %v", result.Reset, result.RetryAfter, tt.reset, tt.retryAfter)
+			}
+		})
+	}
+}
+
+func TestMemoryStoreKeepsBucketsApart(t *testing.T) {
+	store := NewMemoryStore()
+	limit := Limit{Rate: 0.001, Burst: 2}
+	ctx := context.Background()
+
+	for i, want := range []bool{true, true, false} {
+		result, err := store.Take(ctx, "a", limit, 1)
+		if err != nil || result.Allowed != want {
+			t.Fatalf("take %d from a: allowed = %v, err = %v, want %v", i, result.Allowed, err, want)
+		}
+	}
+	if result, _ := store.Take(ctx, "b", limit, 1); !result.Allowed || result.Remaining != 1 {
+		t.Fatalf("take from b = %+v, want a full bucket", result)
+	}
+}
+
+func TestLimiterChargesAtMostTheBurst(t *testing.T) {
+	limiter := NewLimiter(NewMemoryStore(), "graphql", Limit{Rate: 1, Burst: 100})
+	if result := limiter.Take(context.Background(), "ip:1", 1000); !result.Allowed || result.Remaining != 0 {
+		t.Fatalf("result = %+v, want the full bucket spent", result)
+	}
+}
+
+type failingStore struct{}
+
+func (failingStore) Take(context.Context, string, Limit, float64) (Result, error) {
+	return Result{}, errors.New("unavailable")
+}
+
+func TestLimiterLetsThroughWhenTheStoreFails(t *testing.T) {
+	limiter := NewLimiter(failingStore{}, "rest", Limit{Rate: 1, Burst: 5})
+	if result := limiter.Take(context.Background(), "ip:1", 1); !result.Allowed {
+		t.Fatal("request refused")
+	}
+}
+
+func TestWriteHeaders(t *testing.T) {
+	header := http.Header{}
+	Result{
+		Limit:      Limit{Rate: 10, Burst: 60},
+		Remaining:  0.5,
+		Reset:      5950 * time.Millisecond,
+		RetryAfter: 50 * time.Millisecond,
+	}.WriteHeaders(header)
+
+	want := map[string]string{
+		"RateLimit-Limit":     "60",
+		"RateLimit-Remaining": "0",
+		"RateLimit-Reset":     "6",
+		"RateLimit-Policy":    "60;w=6",
+		"Retry-After":         "1",
+	}
+	for name, value := range want {
+		if got := header.Get(name); got != value {
+			t.Errorf("%s = %q, want %q", name, got, value)
+		}
+	}
+}
+
+func TestClient(t *testing.T) {
+	ctx := WithClientIP(context.Background(), "203.0.113.7")
+	if got := Client(ctx); got != "ip:203.0.113.7" {
+		t.Fatalf("Client = %q", got)
+	}
+}
@@ -2,56 +2,204 @@ package routes
 
 import (
 	"context"
+	"fmt"
 	"mas-diq/go-graphql/config"
 	"mas-diq/go-graphql/controllers"
 	"mas-diq/go-graphql/graphql"
 	"mas-diq/go-graphql/loaders"
+	"mas-diq/go-graphql/logging"
+	"mas-diq/go-graphql/metrics"
+	"mas-diq/go-graphql/middlewares"
+	"mas-diq/go-graphql/models"
+	"mas-diq/go-graphql/persisted"
+	"mas-diq/go-graphql/ratelimit"
+	"mas-diq/go-graphql/storage"
+	"mas-diq/go-graphql/validation"
+	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
-	"github.com/graphql-go/handler"
 )
 
-// Define a custom type for context keys
-type contextKey string
+var logger = logging.For("routes")
 
-const userLoaderKey contextKey = "userLoader"
+// SetupRouter returns the routes of the API, and the GraphQL handler, whose
+// WebSocket connections are drained apart on shutdown (see Server).
+func SetupRouter() (*gin.Engine, *graphql.Handler, error) {
+	r := gin.New()
+	// Client IPs, which anonymous clients are rate limited by, only come from
+	// X-Forwarded-For when a trusted proxy sent it
+	if err := r.SetTrustedProxies(config.App.Server.TrustedProxies); err != nil {
+		return nil, nil, fmt.Errorf("invalid trusted proxies: %w", err)
+	}
+	// Tracing before AccessLog, so the access log line has the trace ID
+	r.Use(middlewares.RequestID(), middlewares.Tracing(), middlewares.AccessLog())
+	if config.App.Metrics.Enabled {
+		// Outside of Recover, so requests that panicked are counted as 500s
+		r.Use(middlewares.Metrics())
+	}
+	// CORS before Authenticate, so preflights pass and errors can be read by scripts
+	r.Use(middlewares.Recover(), middlewares.SecurityHeaders(config.App.Security), middlewares.CORS(config.App.CORS), middlewares.Authenticate())
+
+	// Liveness and readiness probes
+	r.GET("/healthz", controllers.Healthz)
+	r.GET("/readyz", controllers.Readyz)
+
+	// Prometheus metrics
+	if config.App.Metrics.Enabled {
+		r.GET("/metrics", gin.WrapH(metrics.Handler()))
+	}
 
-func SetupRouter() *gin.Engine {
-	r := gin.Default()
+	// REST routes are rate limited per client, GraphQL has a budget of its own
+	api := r.Group("")
+	var graphQLLimiter *ratelimit.Limiter
+	if cfg := config.App.RateLimit; cfg.Enabled {
+		store := ratelimit.NewMemoryStore()
+		api.Use(middlewares.RateLimit(ratelimit.NewLimiter(store, "rest", ratelimit.Limit{Rate: cfg.RESTRate, Burst: cfg.RESTBurst})))
+		graphQLLimiter = ratelimit.NewLimiter(store, "graphql", ratelimit.Limit{Rate: cfg.GraphQLRate, Burst: cfg.GraphQLBurst})
+	}
 
-	// REST routes for users
-	users := r.Group("users")
+	// REST routes for users; API keys need the users scopes
+	users := api.Group("users", middlewares.RequireScope(models.ScopeUsersRead, models.ScopeUsersWrite))
 	{
 		users.GET("", controllers.GetListUser)
 		users.POST("", controllers.CreateUser)
-		users.GET("/:id", controllers.GetUser)
-		users.PUT("/:id", controllers.UpdateUser)
-		users.DELETE("/:id", controllers.DeleteUser)
+		users.GET("/email-available", controllers.CheckEmailAvailability)
+		users.GET("/:id", middlewares.ParseID(), controllers.GetUser)
+		users.PUT("/:id", middlewares.ParseID(), controllers.UpdateUser)
+		users.DELETE("/:id", middlewares.ParseID(), controllers.DeleteUser)
+	}
+
+	// REST routes for posts; API keys need the posts scopes
+	posts := api.Group("posts", middlewares.RequireScope(models.ScopePostsRead, models.ScopePostsWrite))
+	{
+		posts.POST("", middlewares.RequireUser(), controllers.CreatePost)
+		posts.GET("/by-slug/:slug", controllers.GetPostBySlug)
+		posts.GET("/stream", controllers.StreamPostChanges)
+		posts.PUT("/:id", middlewares.ParseID(), middlewares.RequireUser(), controllers.UpdatePost)
+		posts.POST("/:id/publish", middlewares.ParseID(), middlewares.RequireUser(), controllers.PublishPost)
+		posts.POST("/:id/archive", middlewares.ParseID(), middlewares.RequireUser(), controllers.ArchivePost)
+		posts.DELETE("/:id", middlewares.ParseID(), middlewares.RequireUser(), controllers.DeletePost)
+		posts.PUT("/:id/tags", middlewares.ParseID(), middlewares.RequireUser(), controllers.SetPostTags)
+		posts.GET("/:id/comments", middlewares.ParseID(), controllers.GetPostComments)
+		posts.POST("/:id/comments", middlewares.ParseID(), middlewares.RequireUser(), controllers.CreateComment)
+		posts.GET("/:id/revisions", middlewares.ParseID(), controllers.GetPostRevisions)
+		posts.GET("/:id/revisions/diff", middlewares.ParseID(), controllers.DiffPostRevisions)
+		posts.POST("/:id/revisions/:revision/restore", middlewares.ParseID(), middlewares.ParseUintParam("revision"), middlewares.RequireUser(), controllers.RestorePostRevision)
+	}
+
+	// REST routes for tags; API keys need the posts scopes
+	tags := api.Group("tags", middlewares.RequireScope(models.ScopePostsRead, models.ScopePostsWrite))
+	{
+		tags.GET("", controllers.GetListTag)
+		tags.POST("", middlewares.RequireUser(), controllers.CreateTag)
+		tags.PUT("/:id", middlewares.ParseID(), middlewares.RequireUser(), controllers.UpdateTag)
+		tags.DELETE("/:id", middlewares.ParseID(), middlewares.RequireUser(), controllers.DeleteTag)
+	}
+
+	// REST routes for comments; API keys need the posts scopes
+	comments := api.Group("comments", middlewares.RequireScope(models.ScopePostsRead, models.ScopePostsWrite))
+	{
+		comments.GET("/:id/replies", middlewares.ParseID(), controllers.GetCommentReplies)
+		comments.PUT("/:id", middlewares.ParseID(), middlewares.RequireUser(), controllers.UpdateComment)
+		comments.PUT("/:id/status", middlewares.ParseID(), middlewares.RequireUser(), controllers.ModerateComment)
+		comments.DELETE("/:id", middlewares.ParseID(), middlewares.RequireUser(), controllers.DeleteComment)
 	}
 
-	// REST routes for posts
-	posts := r.Group("posts")
+	// REST routes for webhooks, admin only
+	hooks := api.Group("webhooks", middlewares.RequireRole(models.RoleAdmin))
 	{
-		posts.POST("", controllers.CreatePost)
-		posts.PUT("/:id", controllers.UpdatePost)
-		posts.DELETE("/:id", controllers.DeletePost)
+		hooks.GET("", controllers.GetListWebhook)
+		hooks.POST("", controllers.CreateWebhook)
+		hooks.GET("/:id", middlewares.ParseID(), controllers.GetWebhook)
+		hooks.PUT("/:id", middlewares.ParseID(), controllers.UpdateWebhook)
+		hooks.DELETE("/:id", middlewares.ParseID(), controllers.DeleteWebhook)
+		hooks.GET("/:id/deliveries", middlewares.ParseID(), controllers.GetWebhookDeliveries)
+		hooks.POST("/:id/deliveries/replay", middlewares.ParseID(), controllers.ReplayDeadWebhookDeliveries)
+		hooks.POST("/:id/deliveries/:delivery/replay", middlewares.ParseID(), middlewares.ParseUintParam("delivery"), controllers.ReplayWebhookDelivery)
+	}
+
+	// REST routes for the API keys of the current user, who can't use one here
+	apiKeys := api.Group("api-keys", middlewares.RequireUser(), middlewares.RejectAPIKeys())
+	{
+		apiKeys.GET("", controllers.GetListAPIKey)
+		apiKeys.POST("", controllers.CreateAPIKey)
+		apiKeys.DELETE("/:id", middlewares.ParseID(), controllers.RevokeAPIKey)
+	}
+
+	// REST route for full-text search
+	api.GET("/search", middlewares.RequireScope(models.ScopePostsRead, models.ScopePostsWrite), controllers.SearchPosts)
+
+	// REST route for image uploads; API keys need posts:write. The local storage backend is served from its public path
+	api.POST("/uploads", middlewares.RequireScope(models.ScopePostsRead, models.ScopePostsWrite), middlewares.RequireUser(), controllers.CreateUpload)
+	if local, ok := storage.Default.(*storage.Local); ok && strings.HasPrefix(local.BaseURL, "/") {
+		r.Static(local.BaseURL, local.Dir)
 	}
 
 	// GraphQL route
-	schema, _ := graphql.NewSchema(config.DB)
-	h := handler.New(&handler.Config{
-		Schema: &schema,
-		Pretty: true,
-	})
+	schema, err := graphql.NewSchema(config.DB)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid GraphQL schema: %w", err)
+	}
+	h := graphql.NewHandler(schema, config.App.Uploads.MaxBytes+controllers.MultipartOverhead)
+	h.Limits = graphql.Limits{
+		MaxDepth:        config.App.GraphQL.MaxDepth,
+		MaxComplexity:   config.App.GraphQL.MaxComplexity,
+		DefaultListSize: config.App.GraphQL.DefaultListSize,
+	}
+	if store, err := persisted.NewStore(config.App.GraphQL); err != nil {
+		logger.Warn("Persisted queries disabled", "error", err)
+	} else {
+		h.Persisted = graphql.PersistedQueries{
+			Store:     persisted.NewCache(store, 1000),
+			Automatic: config.App.GraphQL.AutomaticPersistedQueries,
+			Only:      config.App.GraphQL.PersistedQueriesOnly,
+		}
+	}
+
+	h.Cache = graphql.CacheControl{MaxAge: config.App.GraphQL.CacheMaxAge}
+	h.RateLimit = graphql.RateLimit{Limiter: graphQLLimiter, Weighted: config.App.RateLimit.GraphQLWeighted}
+	// WebSockets are accepted from the CORS origins too
+	h.CheckOrigin = middlewares.CheckOrigin(config.App.CORS)
+	// Subscriptions authenticate with their connection_init payload, and get new loaders for every event
+	h.Authenticate = middlewares.AuthenticatePayload
+	h.OperationContext = func(ctx context.Context) context.Context {
+		return loaders.WithLoaders(ctx, loaders.New(config.DB))
+	}
+	// Queries may read from replicas; mutations read their own writes from the primary
+	h.QueryContext = config.WithReplicaReads
 
 	// In routes/routes.go
-	r.POST("/graphql", func(c *gin.Context) {
-		// Create new loader for each request
-		loader := loaders.NewUserLoader(config.DB)
-		ctx := context.WithValue(c.Request.Context(), userLoaderKey, loader)
+	serveGraphQL := func(c *gin.Context) {
+		// Create new loaders for each request
+		ctx := loaders.WithLoaders(c.Request.Context(), loaders.New(config.DB))
+		ctx = validation.WithLocale(ctx, validation.ParseLocale(c.GetHeader("Accept-Language")))
+		ctx = ratelimit.WithClientIP(ctx, c.ClientIP())
 		c.Request = c.Request.WithContext(ctx)
 		h.ServeHTTP(c.Writer, c.Request)
+	}
+	r.POST("/graphql", serveGraphQL)
+	// Queries, whose responses are cacheable (see graphql.CacheControl), and WebSocket connections
+	if config.App.GraphQL.Playground {
+		// Browsers opening /graphql get GraphiQL, which needs a policy allowing its scripts
+		playground := graphql.Playground("/graphql")
+		servePlayground := func(c *gin.Context) {
+			if !graphql.IsPlaygroundRequest(c.Request) {
+				return
+			}
+			c.Header("Content-Security-Policy", config.App.Security.PlaygroundContentSecurityPolicy)
+			playground.ServeHTTP(c.Writer, c.Request)
+			c.Abort()
+		}
+		r.GET("/graphql", servePlayground, serveGraphQL)
+	} else {
+		r.GET("/graphql", serveGraphQL)
+	}
+	// The schema as SDL, for client teams and code generators
+	sdl := []byte(graphql.PrintSchema(schema))
+	r.GET("/graphql/schema.graphql", func(c *gin.Context) {
+		c.Data(http.StatusOK, "text/plain; charset=utf-8", sdl)
 	})
 
-	return r
+	return r, h, nil
 }
@@ -0,0 +1,77 @@
+package routes
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"mas-diq/go-graphql/config"
+	"mas-diq/go-graphql/events"
+	"mas-diq/go-graphql/graphql"
+	"mas-diq/go-graphql/health"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Server serves the API over HTTP and drains it on shutdown.
+type Server struct {
+	http       *http.Server
+	graphql    *graphql.Handler
+	drainDelay time.Duration
+}
+
+func NewServer(cfg config.ServerConfig) (*Server, error) {
+	r, h, err := SetupRouter()
+	if err != nil {
+		return nil, err
+	}
+	return &Server{
+		http: &http.Server{
+			Addr:              cfg.Addr,
+			Handler:           r,
+			ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+			ReadTimeout:       cfg.ReadTimeout,
+			WriteTimeout:      cfg.WriteTimeout,
+			IdleTimeout:       cfg.IdleTimeout,
+			ErrorLog:          slog.NewLogLogger(logger.Handler(), slog.LevelWarn),
+		},
+		graphql:    h,
+		drainDelay: cfg.DrainDelay,
+	}, nil
+}
+
+// ListenAndServe serves requests until Shutdown is called, when it returns nil.
+func (s *Server) ListenAndServe() error {
+	logger.Info("Listening", "addr", s.http.Addr)
+	if err := s.http.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// Shutdown stops accepting requests and waits for the running ones, until ctx
+// ends. /readyz reports the instance as draining for the drain delay first,
+// while requests are still served, then the change streams and subscriptions
+// are completed, and GraphQL operations running over WebSocket are waited for
+// before their connections are closed.
+func (s *Server) Shutdown(ctx context.Context) error {
+	health.Drain()
+	// Give load balancers time to see /readyz fail and stop sending requests
+	select {
+	case <-time.After(s.drainDelay):
+	case <-ctx.Done():
+	}
+	// Ends the streams fed by the bus: the SSE change streams and subscriptions
+	events.Default.Close()
+
+	var wg sync.WaitGroup
+	var wsErr error
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		wsErr = s.graphql.Shutdown(ctx)
+	}()
+	httpErr := s.http.Shutdown(ctx)
+	wg.Wait()
+	return errors.Join(httpErr, wsErr)
+}
@@ -0,0 +1,46 @@
+package scheduler
+
+import (
+	"context"
+	"mas-diq/go-graphql/events"
+	"mas-diq/go-graphql/logging"
+	"mas-diq/go-graphql/models"
+	"time"
+)
+
+var logger = logging.For("scheduler")
+
+// Start publishes scheduled posts once they are due, checking every interval
+// until ctx is cancelled. The schedule lives in the database, so posts that
+// came due while the server was down are published on the first run. The
+// returned channel is closed once it stopped.
+func Start(ctx context.Context, interval time.Duration) <-chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			publishDue()
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return done
+}
+
+func publishDue() {
+	posts, err := models.PublishDuePosts(time.Now())
+	if err != nil {
+		logger.Error("Failed to publish scheduled posts", "error", err)
+	}
+	for _, post := range posts {
+		logger.Info("Published scheduled post", "post_id", post.ID, "slug", post.Slug)
+		events.Publish(events.Event{Type: events.PostPublished, Post: post})
+	}
+}
@@ -0,0 +1,488 @@
+// Package schemadiff compares two versions of a GraphQL schema, as SDL, and
+// classifies every change by what it does to clients written against the
+// old one: breaking changes fail their operations, dangerous ones may change
+// what they get back, safe ones go unnoticed.
+package schemadiff
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/printer"
+	"github.com/graphql-go/graphql/language/source"
+)
+
+// Level is how a change affects existing clients.
+type Level int
+
+const (
+	Safe Level = iota
+	Dangerous
+	Breaking
+)
+
+func (l Level) String() string {
+	switch l {
+	case Breaking:
+		return "breaking"
+	case Dangerous:
+		return "dangerous"
+	}
+	return "safe"
+}
+
+// Change is one difference between two schemas.
+type Change struct {
+	Level Level
+	// Path is what changed: "Type", "Type.field", "Type.field(arg:)",
+	// "Enum.VALUE", "@directive" or "schema".
+	Path    string
+	Message string
+}
+
+func (c Change) String() string {
+	return c.Path + " " + c.Message
+}
+
+// Compare parses the SDL of two versions of a schema and returns what changed
+// from oldSDL to newSDL, ordered by type, then by field.
+func Compare(oldSDL, newSDL string) ([]Change, error) {
+	oldSchema, err := parse("old schema", oldSDL)
+	if err != nil {
+		return nil, err
+	}
+	newSchema, err := parse("new schema", newSDL)
+	if err != nil {
+		return nil, err
+	}
+	d := &differ{}
+	d.compare(oldSchema, newSchema)
+	return d.changes, nil
+}
+
+// HasBreaking reports whether any of changes is breaking.
+func HasBreaking(changes []Change) bool {
+	for _, change := range changes {
+		if change.Level == Breaking {
+			return true
+		}
+	}
+	return false
+}
+
+// schema is what the comparison needs of a parsed schema.
+type schema struct {
+	// roots maps operations to the name of their root type.
+	roots      map[string]string
+	types      map[string]*typeDef
+	directives map[string]*directiveDef
+}
+
+type typeDef struct {
+	// kind is "scalar", "type", "interface", "union", "enum" or "input".
+	kind        string
+	fields      map[string]*field
+	inputFields map[string]*inputValue
+	interfaces  map[string]bool
+	members     map[string]bool
+	// values maps enum values to whether they are deprecated.
+	values map[string]bool
+}
+
+type field struct {
+	typ        ast.Type
+	args       map[string]*inputValue
+	deprecated bool
+}
+
+// inputValue is an argument or an input object field.
+type inputValue struct {
+	typ ast.Type
+	// defaultValue is the printed default, empty without one.
+	defaultValue string
+}
+
+type directiveDef struct {
+	args      map[string]*inputValue
+	locations map[string]bool
+}
+
+var kinds = map[string]string{
+	"scalar":    "a scalar",
+	"type":      "an object type",
+	"interface": "an interface",
+	"union":     "a union",
+	"enum":      "an enum",
+	"input":     "an input type",
+}
+
+func parse(name, sdl string) (*schema, error) {
+	doc, err := parser.Parse(parser.ParseParams{
+		Source: source.NewSource(&source.Source{Body: []byte(sdl), Name: name}),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s := &schema{roots: map[string]string{}, types: map[string]*typeDef{}, directives: map[string]*directiveDef{}}
+	for _, definition := range doc.Definitions {
+		switch definition := definition.(type) {
+		case *ast.SchemaDefinition:
+			for _, operationType := range definition.OperationTypes {
+				s.roots[operationType.Operation] = operationType.Type.Name.Value
+			}
+		case *ast.ScalarDefinition:
+			s.types[definition.Name.Value] = &typeDef{kind: "scalar"}
+		case *ast.ObjectDefinition:
+			t := &typeDef{kind: "type", fields: fields(definition.Fields), interfaces: map[string]bool{}}
+			for _, iface := range definition.Interfaces {
+				t.interfaces[iface.Name.Value] = true
+			}
+			s.types[definition.Name.Value] = t
+		case *ast.TypeExtensionDefinition:
+			if t, ok := s.types[definition.Definition.Name.Value]; ok && t.kind == "type" {
+				for name, f := range fields(definition.Definition.Fields) {
+					t.fields[name] = f
+				}
+				for _, iface := range definition.Definition.Interfaces {
+					t.interfaces[iface.Name.Value] = true
+				}
+			}
+		case *ast.InterfaceDefinition:
+			s.types[definition.Name.Value] = &typeDef{kind: "interface", fields: fields(definition.Fields)}
+		case *ast.UnionDefinition:
+			t := &typeDef{kind: "union", members: map[string]bool{}}
+			for _, member := range definition.Types {
+				t.members[member.Name.Value] = true
+			}
+			s.types[definition.Name.Value] = t
+		case *ast.EnumDefinition:
+			t := &typeDef{kind: "enum", values: map[string]bool{}}
+			for _, value := range definition.Values {
+				t.values[value.Name.Value] = deprecated(value.Directives)
+			}
+			s.types[definition.Name.Value] = t
+		case *ast.InputObjectDefinition:
+			s.types[definition.Name.Value] = &typeDef{kind: "input", inputFields: inputValues(definition.Fields)}
+		case *ast.DirectiveDefinition:
+			d := &directiveDef{args: inputValues(definition.Arguments), locations: map[string]bool{}}
+			for _, location := range definition.Locations {
+				d.locations[location.Value] = true
+			}
+			s.directives[definition.Name.Value] = d
+		}
+	}
+
+	// Without a schema definition the root types go by their usual names.
+	if len(s.roots) == 0 {
+		for operation, name := range map[string]string{"query": "Query", "mutation": "Mutation", "subscription": "Subscription"} {
+			if _, ok := s.types[name]; ok {
+				s.roots[operation] = name
+			}
+		}
+	}
+	return s, nil
+}
+
+func fields(definitions []*ast.FieldDefinition) map[string]*field {
+	fields := make(map[string]*field, len(definitions))
+	for _, definition := range definitions {
+		fields[definition.Name.Value] = &field{
+			typ:        definition.Type,
+			args:       inputValues(definition.Arguments),
+			deprecated: deprecated(definition.Directives),
+		}
+	}
+	return fields
+}
+
+func inputValues(definitions []*ast.InputValueDefinition) map[string]*inputValue {
+	values := make(map[string]*inputValue, len(definitions))
+	for _, definition := range definitions {
+		value := &inputValue{typ: definition.Type}
+		if definition.DefaultValue != nil {
+			value.defaultValue = fmt.Sprint(printer.Print(definition.DefaultValue))
+		}
+		values[definition.Name.Value] = value
+	}
+	return values
+}
+
+func deprecated(directives []*ast.Directive) bool {
+	for _, directive := range directives {
+		if directive.Name.Value == "deprecated" {
+			return true
+		}
+	}
+	return false
+}
+
+type differ struct {
+	changes []Change
+}
+
+func (d *differ) add(level Level, path, format string, args ...interface{}) {
+	d.changes = append(d.changes, Change{Level: level, Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+func (d *differ) compare(oldSchema, newSchema *schema) {
+	for _, operation := range []string{"query", "mutation", "subscription"} {
+		oldRoot, newRoot := oldSchema.roots[operation], newSchema.roots[operation]
+		switch {
+		case oldRoot == newRoot:
+		case newRoot == "":
+			d.add(Breaking, "schema", "no longer has a %s root", operation)
+		case oldRoot == "":
+			d.add(Safe, "schema", "has a %s root, %s", operation, newRoot)
+		default:
+			d.add(Breaking, "schema", "%s root changed from %s to %s", operation, oldRoot, newRoot)
+		}
+	}
+
+	for _, name := range keys(oldSchema.directives) {
+		newDirective, ok := newSchema.directives[name]
+		if !ok {
+			d.add(Breaking, "@"+name, "was removed")
+			continue
+		}
+		oldDirective := oldSchema.directives[name]
+		d.compareArgs("@"+name, oldDirective.args, newDirective.args)
+		for _, location := range keys(oldDirective.locations) {
+			if !newDirective.locations[location] {
+				d.add(Breaking, "@"+name, "can no longer be used on %s", location)
+			}
+		}
+	}
+	for _, name := range keys(newSchema.directives) {
+		if _, ok := oldSchema.directives[name]; !ok {
+			d.add(Safe, "@"+name, "was added")
+		}
+	}
+
+	for _, name := range keys(oldSchema.types) {
+		newType, ok := newSchema.types[name]
+		if !ok {
+			d.add(Breaking, name, "was removed")
+			continue
+		}
+		oldType := oldSchema.types[name]
+		if oldType.kind != newType.kind {
+			d.add(Breaking, name, "changed from %s to %s", kinds[oldType.kind], kinds[newType.kind])
+			continue
+		}
+		switch oldType.kind {
+		case "type", "interface":
+			d.compareFields(name, oldType.fields, newType.fields)
+			d.compareSet(name, oldType.interfaces, newType.interfaces, "no longer implements %s", "now implements %s")
+		case "union":
+			d.compareSet(name, oldType.members, newType.members, "no longer includes %s", "now includes %s")
+		case "enum":
+			d.compareEnum(name, oldType.values, newType.values)
+		case "input":
+			d.compareInputFields(name, oldType.inputFields, newType.inputFields)
+		}
+	}
+	for _, name := range keys(newSchema.types) {
+		if _, ok := oldSchema.types[name]; !ok {
+			d.add(Safe, name, "was added")
+		}
+	}
+}
+
+// compareFields compares the fields of an object type or interface, which
+// clients read: they may become more precise, e.g. non-null, but not less.
+func (d *differ) compareFields(typeName string, oldFields, newFields map[string]*field) {
+	for _, name := range keys(oldFields) {
+		path := typeName + "." + name
+		newField, ok := newFields[name]
+		if !ok {
+			d.add(Breaking, path, "was removed")
+			continue
+		}
+		oldField := oldFields[name]
+		d.compareType(path, oldField.typ, newField.typ, safeOutput)
+		d.compareArgs(path, oldField.args, newField.args)
+		if !oldField.deprecated && newField.deprecated {
+			d.add(Safe, path, "was deprecated")
+		}
+	}
+	for _, name := range keys(newFields) {
+		if _, ok := oldFields[name]; !ok {
+			d.add(Safe, typeName+"."+name, "was added")
+		}
+	}
+}
+
+// compareArgs compares the arguments of a field or directive, which clients
+// send: they may become optional, but not required.
+func (d *differ) compareArgs(fieldPath string, oldArgs, newArgs map[string]*inputValue) {
+	for _, name := range keys(oldArgs) {
+		path := fieldPath + "(" + name + ":)"
+		newArg, ok := newArgs[name]
+		if !ok {
+			d.add(Breaking, path, "was removed")
+			continue
+		}
+		d.compareInputValue(path, oldArgs[name], newArg)
+	}
+	for _, name := range keys(newArgs) {
+		if _, ok := oldArgs[name]; !ok {
+			d.addInputValue(fieldPath+"("+name+":)", "argument", newArgs[name])
+		}
+	}
+}
+
+// compareInputFields compares the fields of an input type, which clients
+// send, like arguments.
+func (d *differ) compareInputFields(typeName string, oldFields, newFields map[string]*inputValue) {
+	for _, name := range keys(oldFields) {
+		path := typeName + "." + name
+		newField, ok := newFields[name]
+		if !ok {
+			d.add(Breaking, path, "was removed")
+			continue
+		}
+		d.compareInputValue(path, oldFields[name], newField)
+	}
+	for _, name := range keys(newFields) {
+		if _, ok := oldFields[name]; !ok {
+			d.addInputValue(typeName+"."+name, "input field", newFields[name])
+		}
+	}
+}
+
+func (d *differ) compareInputValue(path string, oldValue, newValue *inputValue) {
+	d.compareType(path, oldValue.typ, newValue.typ, safeInput)
+	switch {
+	case oldValue.defaultValue == newValue.defaultValue:
+	case newValue.defaultValue == "":
+		d.add(Dangerous, path, "no longer defaults to %s", oldValue.defaultValue)
+	case oldValue.defaultValue == "":
+		d.add(Dangerous, path, "now defaults to %s", newValue.defaultValue)
+	default:
+		d.add(Dangerous, path, "default changed from %s to %s", oldValue.defaultValue, newValue.defaultValue)
+	}
+}
+
+// addInputValue records a new argument or input field: operations that
+// don't send a required one fail, and an optional one may change results.
+func (d *differ) addInputValue(path, what string, value *inputValue) {
+	if _, required := value.typ.(*ast.NonNull); required && value.defaultValue == "" {
+		d.add(Breaking, path, "was added as a required %s", what)
+	} else {
+		d.add(Dangerous, path, "was added as an optional %s", what)
+	}
+}
+
+func (d *differ) compareType(path string, oldType, newType ast.Type, safe func(oldType, newType ast.Type) bool) {
+	oldName, newName := typeString(oldType), typeString(newType)
+	if oldName == newName {
+		return
+	}
+	level := Breaking
+	if safe(oldType, newType) {
+		level = Safe
+	}
+	d.add(level, path, "changed type from %s to %s", oldName, newName)
+}
+
+// compareSet compares the interfaces of a type or the members of a union:
+// removing one breaks the fragments on it, adding one may return results
+// that clients don't expect.
+func (d *differ) compareSet(path string, oldSet, newSet map[string]bool, removed, added string) {
+	for _, name := range keys(oldSet) {
+		if !newSet[name] {
+			d.add(Breaking, path, removed, name)
+		}
+	}
+	for _, name := range keys(newSet) {
+		if !oldSet[name] {
+			d.add(Dangerous, path, added, name)
+		}
+	}
+}
+
+// compareEnum compares the values of an enum: a removed value breaks the
+// operations sending it, a new one may reach clients that can't handle it.
+func (d *differ) compareEnum(typeName string, oldValues, newValues map[string]bool) {
+	for _, name := range keys(oldValues) {
+		path := typeName + "." + name
+		newDeprecated, ok := newValues[name]
+		switch {
+		case !ok:
+			d.add(Breaking, path, "was removed")
+		case !oldValues[name] && newDeprecated:
+			d.add(Safe, path, "was deprecated")
+		}
+	}
+	for _, name := range keys(newValues) {
+		if _, ok := oldValues[name]; !ok {
+			d.add(Dangerous, typeName+"."+name, "was added")
+		}
+	}
+}
+
+// safeOutput reports whether clients reading values of oldType can read
+// values of newType: the same type, or one that became non-null.
+func safeOutput(oldType, newType ast.Type) bool {
+	if newNonNull, ok := newType.(*ast.NonNull); ok {
+		if oldNonNull, ok := oldType.(*ast.NonNull); ok {
+			return safeOutput(oldNonNull.Type, newNonNull.Type)
+		}
+		return safeOutput(oldType, newNonNull.Type)
+	}
+	switch oldType := oldType.(type) {
+	case *ast.Named:
+		newNamed, ok := newType.(*ast.Named)
+		return ok && newNamed.Name.Value == oldType.Name.Value
+	case *ast.List:
+		newList, ok := newType.(*ast.List)
+		return ok && safeOutput(oldType.Type, newList.Type)
+	}
+	// A non-null type became nullable.
+	return false
+}
+
+// safeInput reports whether values that clients sent as oldType are still
+// valid as newType: the same type, or one that became nullable.
+func safeInput(oldType, newType ast.Type) bool {
+	if oldNonNull, ok := oldType.(*ast.NonNull); ok {
+		if newNonNull, ok := newType.(*ast.NonNull); ok {
+			return safeInput(oldNonNull.Type, newNonNull.Type)
+		}
+		return safeInput(oldNonNull.Type, newType)
+	}
+	switch oldType := oldType.(type) {
+	case *ast.Named:
+		newNamed, ok := newType.(*ast.Named)
+		return ok && newNamed.Name.Value == oldType.Name.Value
+	case *ast.List:
+		newList, ok := newType.(*ast.List)
+		return ok && safeInput(oldType.Type, newList.Type)
+	}
+	// A nullable type became non-null.
+	return false
+}
+
+func typeString(t ast.Type) string {
+	switch t := t.(type) {
+	case *ast.Named:
+		return t.Name.Value
+	case *ast.List:
+		return "[" + typeString(t.Type) + "]"
+	case *ast.NonNull:
+		return typeString(t.Type) + "!"
+	}
+	return ""
+}
+
+func keys[V any](m map[string]V) []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,190 @@
+package schemadiff
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		name     string
+		old, new string
+		want     []Change
+	}{
+		{
+			name: "identical",
+			old:  `type Query { post(id: ID!): String }`,
+			new:  `type Query { post(id: ID!): String }`,
+		},
+		{
+			name: "output becomes non-null",
+			old:  `type Query { title: String }`,
+			new:  `type Query { title: String! }`,
+			want: []Change{{Safe, "Query.title", "changed type from String to String!"}},
+		},
+		{
+			name: "output becomes nullable",
+			old:  `type Query { title: String! }`,
+			new:  `type Query { title: String }`,
+			want: []Change{{Breaking, "Query.title", "changed type from String! to String"}},
+		},
+		{
+			name: "output list items become non-null",
+			old:  `type Query { tags: [String] }`,
+			new:  `type Query { tags: [String!]! }`,
+			want: []Change{{Safe, "Query.tags", "changed type from [String] to [String!]!"}},
+		},
+		{
+			name: "input field becomes non-null",
+			old:  `input PostInput { title: String }`,
+			new:  `input PostInput { title: String! }`,
+			want: []Change{{Breaking, "PostInput.title", "changed type from String to String!"}},
+		},
+		{
+			name: "input field becomes nullable",
+			old:  `input PostInput { title: String! }`,
+			new:  `input PostInput { title: String }`,
+			want: []Change{{Safe, "PostInput.title", "changed type from String! to String"}},
+		},
+		{
+			name: "argument becomes non-null",
+			old:  `type Query { posts(tags: [String]): String }`,
+			new:  `type Query { posts(tags: [String!]): String }`,
+			want: []Change{{Breaking, "Query.posts(tags:)", "changed type from [String] to [String!]"}},
+		},
+		{
+			name: "argument becomes nullable",
+			old:  `type Query { post(id: ID!): String }`,
+			new:  `type Query { post(id: ID): String }`,
+			want: []Change{{Safe, "Query.post(id:)", "changed type from ID! to ID"}},
+		},
+		{
+			name: "field changes type",
+			old:  `type Query { id: Int }`,
+			new:  `type Query { id: ID }`,
+			want: []Change{{Breaking, "Query.id", "changed type from Int to ID"}},
+		},
+		{
+			name: "required argument added",
+			old:  `type Query { posts: String }`,
+			new:  `type Query { posts(limit: Int!): String }`,
+			want: []Change{{Breaking, "Query.posts(limit:)", "was added as a required argument"}},
+		},
+		{
+			name: "non-null argument with a default added",
+			old:  `type Query { posts: String }`,
+			new:  `type Query { posts(limit: Int! = 20): String }`,
+			want: []Change{{Dangerous, "Query.posts(limit:)", "was added as an optional argument"}},
+		},
+		{
+			name: "nullable argument added",
+			old:  `type Query { posts: String }`,
+			new:  `type Query { posts(limit: Int): String }`,
+			want: []Change{{Dangerous, "Query.posts(limit:)", "was added as an optional argument"}},
+		},
+		{
+			name: "argument removed",
+			old:  `type Query { posts(limit: Int): String }`,
+			new:  `type Query { posts: String }`,
+			want: []Change{{Breaking, "Query.posts(limit:)", "was removed"}},
+		},
+		{
+			name: "argument default changed",
+			old:  `type Query { posts(limit: Int = 20): String }`,
+			new:  `type Query { posts(limit: Int = 10): String }`,
+			want: []Change{{Dangerous, "Query.posts(limit:)", "default changed from 20 to 10"}},
+		},
+		{
+			name: "required input field added",
+			old:  `input PostInput { title: String }`,
+			new:  `input PostInput { title: String body: String! }`,
+			want: []Change{{Breaking, "PostInput.body", "was added as a required input field"}},
+		},
+		{
+			name: "enum value added",
+			old:  `enum PostStatus { DRAFT PUBLISHED }`,
+			new:  `enum PostStatus { DRAFT PUBLISHED ARCHIVED }`,
+			want: []Change{{Dangerous, "PostStatus.ARCHIVED", "was added"}},
+		},
+		{
+			name: "enum value removed",
+			old:  `enum PostStatus { DRAFT PUBLISHED ARCHIVED }`,
+			new:  `enum PostStatus { DRAFT PUBLISHED }`,
+			want: []Change{{Breaking, "PostStatus.ARCHIVED", "was removed"}},
+		},
+		{
+			name: "enum value deprecated",
+			old:  `enum PostStatus { DRAFT PUBLISHED }`,
+			new:  `enum PostStatus { DRAFT @deprecated PUBLISHED }`,
+			want: []Change{{Safe, "PostStatus.DRAFT", "was deprecated"}},
+		},
+		{
+			name: "union member added",
+			old:  `type Post { id: ID } type User { id: ID } union SearchResult = Post`,
+			new:  `type Post { id: ID } type User { id: ID } union SearchResult = Post | User`,
+			want: []Change{{Dangerous, "SearchResult", "now includes User"}},
+		},
+		{
+			name: "union member removed",
+			old:  `type Post { id: ID } type User { id: ID } union SearchResult = Post | User`,
+			new:  `type Post { id: ID } type User { id: ID } union SearchResult = Post`,
+			want: []Change{{Breaking, "SearchResult", "no longer includes User"}},
+		},
+		{
+			name: "interface implemented",
+			old:  `interface Node { id: ID } type Post { id: ID }`,
+			new:  `interface Node { id: ID } type Post implements Node { id: ID }`,
+			want: []Change{{Dangerous, "Post", "now implements Node"}},
+		},
+		{
+			name: "interface no longer implemented",
+			old:  `interface Node { id: ID } type Post implements Node { id: ID }`,
+			new:  `interface Node { id: ID } type Post { id: ID }`,
+			want: []Change{{Breaking, "Post", "no longer implements Node"}},
+		},
+		{
+			name: "type changes kind",
+			old:  `type Post { id: ID } type User { id: ID } union SearchResult = Post | User`,
+			new:  `type Post { id: ID } type User { id: ID } interface SearchResult { id: ID }`,
+			want: []Change{{Breaking, "SearchResult", "changed from a union to an interface"}},
+		},
+		{
+			name: "types added and removed",
+			old:  `type Query { id: ID } type Draft { id: ID }`,
+			new:  `type Query { id: ID } type Tag { id: ID }`,
+			want: []Change{{Breaking, "Draft", "was removed"}, {Safe, "Tag", "was added"}},
+		},
+		{
+			name: "root removed",
+			old:  `type Query { id: ID } type Mutation { id: ID }`,
+			new:  `type Query { id: ID }`,
+			want: []Change{{Breaking, "schema", "no longer has a mutation root"}, {Breaking, "Mutation", "was removed"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changes, err := Compare(tt.old, tt.new)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(changes, tt.want) {
+				t.Fatalf("changes = %v, want %v", changes, tt.want)
+			}
+		})
+	}
+}
+
+func TestHasBreaking(t *testing.T) {
+	if HasBreaking([]Change{{Level: Safe}, {Level: Dangerous}}) {
+		t.Error("safe and dangerous changes reported as breaking")
+	}
+	if !HasBreaking([]Change{{Level: Safe}, {Level: Breaking}}) {
+		t.Error("breaking change not reported")
+	}
+}
+
+func TestCompareRejectsInvalidSDL(t *testing.T) {
+	if _, err := Compare(`type Query { id: ID }`, `type Query {`); err == nil {
+		t.Fatal("invalid schema compared")
+	}
+}
@@ -1,7 +1,18 @@
 package schemas
 
 type Response struct {
-	Code int         `json:"code"`
-	Info string      `json:"info"`
-	Data interface{} `json:"data"`
+	Code      int          `json:"code"`
+	Info      string       `json:"info"`
+	Data      interface{}  `json:"data"`
+	ErrorCode string       `json:"errorCode,omitempty"`
+	Errors    []FieldError `json:"errors,omitempty"`
+}
+
+// FieldError describes a single input field that failed validation.
+// Field uses the JSON name of the input so clients can map it back to their form.
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Param   string `json:"param,omitempty"`
+	Message string `json:"message"`
 }
@@ -0,0 +1,223 @@
+package search
+
+import (
+	"context"
+	"mas-diq/go-graphql/models"
+	"math"
+	"reflect"
+	"sort"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+// Field weights: a word in the title says more about a post than one in its content.
+var fieldWeights = struct{ title, subtitle, content float64 }{3, 2, 1}
+
+// memoryIndex is an inverted index of the published posts, for databases
+// without full-text search. GORM callbacks mark the posts that were written
+// and the next search reloads them, so the index follows every change made
+// through GORM. It is meant for development and small sites.
+type memoryIndex struct {
+	db *gorm.DB
+
+	mu       sync.RWMutex
+	postings map[string]map[uint]float64 // word -> post -> weighted term frequency
+	words    map[uint][]string           // post -> its distinct words, to unindex it
+
+	// pending is guarded by its own lock, so callbacks running inside a
+	// transaction never wait on a search that is waiting for the database.
+	pendingMu sync.Mutex
+	pending   map[uint]struct{}
+	rebuild   bool
+}
+
+func newMemoryIndex(db *gorm.DB) *memoryIndex {
+	return &memoryIndex{
+		db:       db,
+		postings: map[string]map[uint]float64{},
+		words:    map[uint][]string{},
+		pending:  map[uint]struct{}{},
+		rebuild:  true,
+	}
+}
+
+func (m *memoryIndex) register() error {
+	callbacks := m.db.Callback()
+	if err := callbacks.Create().After("gorm:create").Register("search:index", m.track); err != nil {
+		return err
+	}
+	if err := callbacks.Update().After("gorm:update").Register("search:index", m.track); err != nil {
+		return err
+	}
+	return callbacks.Delete().After("gorm:delete").Register("search:index", m.track)
+}
+
+// track marks the posts written by a statement for reindexing. Statements
+// that don't name their posts, like updates by condition, trigger a rebuild.
+func (m *memoryIndex) track(tx *gorm.DB) {
+	if tx.Error != nil || tx.Statement.Schema == nil || tx.Statement.Schema.Table != (&models.Post{}).TableName() {
+		return
+	}
+
+	var ids []uint
+	value := reflect.Indirect(tx.Statement.ReflectValue)
+	switch value.Kind() {
+	case reflect.Struct:
+		if post, ok := value.Interface().(models.Post); ok {
+			ids = append(ids, post.ID)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			if post, ok := reflect.Indirect(value.Index(i)).Interface().(models.Post); ok {
+				ids = append(ids, post.ID)
+			}
+		}
+	}
+
+	m.pendingMu.Lock()
+	defer m.pendingMu.Unlock()
+	for _, id := range ids {
+		if id == 0 {
+			m.rebuild = true
+			continue
+		}
+		m.pending[id] = struct{}{}
+	}
+	if len(ids) == 0 {
+		m.rebuild = true
+	}
+}
+
+// refresh reloads the posts marked by track.
+func (m *memoryIndex) refresh(ctx context.Context) error {
+	m.pendingMu.Lock()
+	rebuild, pending := m.rebuild, m.pending
+	m.rebuild, m.pending = false, map[uint]struct{}{}
+	m.pendingMu.Unlock()
+	if !rebuild && len(pending) == 0 {
+		return nil
+	}
+
+	ids := make([]uint, 0, len(pending))
+	for id := range pending {
+		ids = append(ids, id)
+	}
+	query := m.db.Session(&gorm.Session{NewDB: true}).WithContext(ctx).
+		Model(&models.Post{}).
+		Select("id", "title", "subtitle", "content").
+		Where("status = ?", models.Published)
+	if !rebuild {
+		query = query.Where("id IN ?", ids)
+	}
+	var posts []models.Post
+	if err := query.Find(&posts).Error; err != nil {
+		// Try again on the next search.
+		m.pendingMu.Lock()
+		m.rebuild = m.rebuild || rebuild
+		for _, id := range ids {
+			m.pending[id] = struct{}{}
+		}
+		m.pendingMu.Unlock()
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if rebuild {
+		m.postings = map[string]map[uint]float64{}
+		m.words = map[uint][]string{}
+	} else {
+		for _, id := range ids {
+			m.remove(id)
+		}
+	}
+	for _, post := range posts {
+		m.add(post)
+	}
+	return nil
+}
+
+func (m *memoryIndex) add(post models.Post) {
+	frequencies := map[string]float64{}
+	for _, field := range []struct {
+		text   string
+		weight float64
+	}{
+		{post.Title, fieldWeights.title},
+		{post.Subtitle, fieldWeights.subtitle},
+		{post.Content, fieldWeights.content},
+	} {
+		for _, t := range tokenize(field.text) {
+			frequencies[t.word] += field.weight
+		}
+	}
+
+	words := make([]string, 0, len(frequencies))
+	for word, frequency := range frequencies {
+		if m.postings[word] == nil {
+			m.postings[word] = map[uint]float64{}
+		}
+		m.postings[word][post.ID] = frequency
+		words = append(words, word)
+	}
+	m.words[post.ID] = words
+}
+
+func (m *memoryIndex) remove(id uint) {
+	for _, word := range m.words[id] {
+		delete(m.postings[word], id)
+		if len(m.postings[word]) == 0 {
+			delete(m.postings, word)
+		}
+	}
+	delete(m.words, id)
+}
+
+// Search scores posts with tf-idf. Words that only start with a term score
+// half as much as the term itself. Finding them scans the whole vocabulary,
+// which is fine at the sizes this index is meant for.
+func (m *memoryIndex) Search(ctx context.Context, terms []string, limit, offset int) ([]Hit, error) {
+	if err := m.refresh(ctx); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	documents := float64(len(m.words))
+	scores := map[uint]float64{}
+	for _, term := range terms {
+		term = fold(term)
+		for word, postings := range m.postings {
+			weight := 1.0
+			switch {
+			case word == term:
+			case len(word) > len(term) && word[:len(term)] == term:
+				weight = 0.5
+			default:
+				continue
+			}
+			idf := math.Log(1 + documents/float64(len(postings)))
+			for id, frequency := range postings {
+				scores[id] += weight * math.Log(1+frequency) * idf
+			}
+		}
+	}
+
+	hits := make([]Hit, 0, len(scores))
+	for id, score := range scores {
+		hits = append(hits, Hit{PostID: id, Score: score})
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].PostID > hits[j].PostID
+	})
+
+	if offset >= len(hits) {
+		return []Hit{}, nil
+	}
+	return hits[offset:min(offset+limit, len(hits))], nil
+}
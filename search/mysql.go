@@ -0,0 +1,60 @@
+package search
+
+import (
+	"context"
+	"mas-diq/go-graphql/models"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// mysqlEngine uses InnoDB FULLTEXT indexes. Matches in the title count twice,
+// through a second index on the title alone.
+//
+// MySQL does not index words shorter than innodb_ft_min_token_size (3 by
+// default) nor its stopwords, so those never match.
+type mysqlEngine struct {
+	db *gorm.DB
+}
+
+const (
+	mysqlIndex      = "idx_posts_fulltext"
+	mysqlTitleIndex = "idx_posts_title_fulltext"
+)
+
+func (e *mysqlEngine) migrate() error {
+	indexes := map[string]string{
+		mysqlIndex:      "title, subtitle, content",
+		mysqlTitleIndex: "title",
+	}
+	for name, columns := range indexes {
+		if e.db.Migrator().HasIndex(&models.Post{}, name) {
+			continue
+		}
+		if err := e.db.Exec("CREATE FULLTEXT INDEX " + name + " ON posts (" + columns + ")").Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *mysqlEngine) Search(ctx context.Context, terms []string, limit, offset int) ([]Hit, error) {
+	// Boolean mode without operators matches any word; "*" makes each a prefix.
+	words := make([]string, len(terms))
+	for i, term := range terms {
+		words[i] = term + "*"
+	}
+	against := strings.Join(words, " ")
+
+	var hits []Hit
+	err := e.db.WithContext(ctx).
+		Model(&models.Post{}).
+		Select("id AS post_id, MATCH(title, subtitle, content) AGAINST (? IN BOOLEAN MODE) + 2 * MATCH(title) AGAINST (? IN BOOLEAN MODE) AS score", against, against).
+		Where("status = ?", models.Published).
+		Where("MATCH(title, subtitle, content) AGAINST (? IN BOOLEAN MODE)", against).
+		Order("score DESC, id DESC").
+		Limit(limit).
+		Offset(offset).
+		Scan(&hits).Error
+	return hits, err
+}
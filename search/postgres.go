@@ -0,0 +1,45 @@
+package search
+
+import (
+	"context"
+	"mas-diq/go-graphql/models"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// postgresDocument is the weighted tsvector of a post. The GIN index is built
+// on this exact expression, so queries must use it verbatim to hit the index.
+const postgresDocument = "setweight(to_tsvector('simple', coalesce(title, '')), 'A') || " +
+	"setweight(to_tsvector('simple', coalesce(subtitle, '')), 'B') || " +
+	"setweight(to_tsvector('simple', coalesce(content, '')), 'C')"
+
+// postgresEngine uses a tsvector expression index and ts_rank.
+type postgresEngine struct {
+	db *gorm.DB
+}
+
+func (e *postgresEngine) migrate() error {
+	return e.db.Exec("CREATE INDEX IF NOT EXISTS idx_posts_fulltext ON posts USING GIN ((" + postgresDocument + "))").Error
+}
+
+func (e *postgresEngine) Search(ctx context.Context, terms []string, limit, offset int) ([]Hit, error) {
+	// Terms only hold letters and digits, so they are safe in tsquery syntax.
+	words := make([]string, len(terms))
+	for i, term := range terms {
+		words[i] = term + ":*"
+	}
+	query := strings.Join(words, " | ")
+
+	var hits []Hit
+	err := e.db.WithContext(ctx).
+		Model(&models.Post{}).
+		Select("id AS post_id, ts_rank("+postgresDocument+", to_tsquery('simple', ?)) AS score", query).
+		Where("status = ?", models.Published).
+		Where(postgresDocument+" @@ to_tsquery('simple', ?)", query).
+		Order("score DESC, id DESC").
+		Limit(limit).
+		Offset(offset).
+		Scan(&hits).Error
+	return hits, err
+}
@@ -0,0 +1,131 @@
+// Package search finds published posts by the words in their title, subtitle
+// and content. It uses the full-text features of the database when it has
+// them and an in-memory inverted index otherwise.
+package search
+
+import (
+	"context"
+	"errors"
+	"mas-diq/go-graphql/models"
+
+	"gorm.io/gorm"
+)
+
+// Engine ranks published posts against a list of query terms.
+type Engine interface {
+	// Search returns the posts matching any of the terms, best match first.
+	// Terms are lowercase words; a term also matches words it is a prefix of.
+	Search(ctx context.Context, terms []string, limit, offset int) ([]Hit, error)
+}
+
+// Hit is a matching post and its relevance. Scores are only comparable
+// between hits of the same search.
+type Hit struct {
+	PostID uint
+	Score  float64
+}
+
+// Result is a search hit with its post and highlighted snippets.
+type Result struct {
+	Post       models.Post
+	Score      float64
+	Highlights Highlights
+}
+
+// Highlights hold HTML escaped text with matching words wrapped in <mark>.
+type Highlights struct {
+	Title    string `json:"title"`
+	Subtitle string `json:"subtitle"`
+	Content  string `json:"content"` // A snippet around the best matching passage
+}
+
+// ErrNotConfigured is returned when searching before Setup.
+var ErrNotConfigured = errors.New("search is not configured")
+
+// maxTerms bounds the work done for very long queries.
+const maxTerms = 10
+
+var (
+	database *gorm.DB
+	engine   Engine
+)
+
+// Setup picks the engine for the database dialect and prepares it: MySQL and
+// Postgres get a full-text index, other databases (SQLite) are indexed in
+// memory and kept up to date through GORM callbacks.
+func Setup(db *gorm.DB) error {
+	var e Engine
+	switch db.Dialector.Name() {
+	case "mysql":
+		m := &mysqlEngine{db: db}
+		if err := m.migrate(); err != nil {
+			return err
+		}
+		e = m
+	case "postgres":
+		p := &postgresEngine{db: db}
+		if err := p.migrate(); err != nil {
+			return err
+		}
+		e = p
+	default:
+		m := newMemoryIndex(db)
+		if err := m.register(); err != nil {
+			return err
+		}
+		e = m
+	}
+
+	database, engine = db, e
+	return nil
+}
+
+// Posts searches the published posts and returns one page of results.
+func Posts(ctx context.Context, query string, limit, offset int) ([]Result, error) {
+	if engine == nil {
+		return nil, ErrNotConfigured
+	}
+	terms := Terms(query)
+	if len(terms) == 0 {
+		return []Result{}, nil
+	}
+
+	hits, err := engine.Search(ctx, terms, limit, offset)
+	if err != nil || len(hits) == 0 {
+		return []Result{}, err
+	}
+
+	ids := make([]uint, 0, len(hits))
+	for _, hit := range hits {
+		ids = append(ids, hit.PostID)
+	}
+	var posts []models.Post
+	err = database.WithContext(ctx).
+		Where("id IN ? AND status = ?", ids, models.Published).
+		Find(&posts).Error
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[uint]models.Post, len(posts))
+	for _, post := range posts {
+		byID[post.ID] = post
+	}
+
+	results := make([]Result, 0, len(hits))
+	for _, hit := range hits {
+		post, ok := byID[hit.PostID]
+		if !ok {
+			continue // unpublished or deleted since it was indexed
+		}
+		results = append(results, Result{
+			Post:  post,
+			Score: hit.Score,
+			Highlights: Highlights{
+				Title:    Highlight(post.Title, terms),
+				Subtitle: Highlight(post.Subtitle, terms),
+				Content:  Snippet(post.Content, terms, snippetWords),
+			},
+		})
+	}
+	return results, nil
+}
@@ -0,0 +1,161 @@
+package search
+
+import (
+	"html"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// snippetWords is the length of content snippets, in words.
+const snippetWords = 30
+
+// token is a word of a text and its byte offsets.
+type token struct {
+	start, end int
+	word       string // lowercase, accents removed
+}
+
+// tokenize splits text into words of letters and digits.
+func tokenize(text string) []token {
+	var tokens []token
+	start := -1
+	for i, r := range text {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		if start >= 0 {
+			tokens = append(tokens, token{start: start, end: i, word: fold(text[start:i])})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		tokens = append(tokens, token{start: start, end: len(text), word: fold(text[start:])})
+	}
+	return tokens
+}
+
+// fold lowercases a word and drops its accents, so "Crème" matches "creme".
+func fold(word string) string {
+	var b strings.Builder
+	for _, r := range norm.NFD.String(strings.ToLower(word)) {
+		if !unicode.Is(unicode.Mn, r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// Terms extracts the distinct lowercase words of a query. Single characters are
+// dropped, and so is anything after the first maxTerms words.
+func Terms(query string) []string {
+	seen := map[string]bool{}
+	var terms []string
+	for _, field := range strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && !unicode.Is(unicode.Mn, r)
+	}) {
+		field = norm.NFC.String(field)
+		if len([]rune(field)) < 2 || seen[field] {
+			continue
+		}
+		seen[field] = true
+		terms = append(terms, field)
+		if len(terms) == maxTerms {
+			break
+		}
+	}
+	return terms
+}
+
+// matcher reports whether a folded word matches one of the query terms,
+// either exactly or as a prefix.
+func matcher(terms []string) func(word string) bool {
+	folded := make([]string, len(terms))
+	for i, term := range terms {
+		folded[i] = fold(term)
+	}
+	return func(word string) bool {
+		for _, term := range folded {
+			if strings.HasPrefix(word, term) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Highlight escapes text for HTML and wraps the words matching terms in <mark>.
+func Highlight(text string, terms []string) string {
+	return mark(text, tokenize(text), matcher(terms))
+}
+
+// Snippet returns about n words of text around its densest cluster of matches,
+// highlighted like Highlight. Text without matches yields its first n words.
+func Snippet(text string, terms []string, n int) string {
+	tokens := tokenize(text)
+	if len(tokens) <= n {
+		return Highlight(text, terms)
+	}
+
+	matches := matcher(terms)
+	hit := make([]int, len(tokens)+1) // hit[i] counts matches among tokens[:i]
+	for i, t := range tokens {
+		hit[i+1] = hit[i]
+		if matches(t.word) {
+			hit[i+1]++
+		}
+	}
+	best, bestCount := 0, -1
+	for i := 0; i+n <= len(tokens); i++ {
+		if count := hit[i+n] - hit[i]; count > bestCount {
+			best, bestCount = i, count
+		}
+	}
+	// Start a few words before the first match of the window, for context.
+	if bestCount > 0 {
+		first := best
+		for !matches(tokens[first].word) {
+			first++
+		}
+		best = max(first-3, 0)
+	}
+	last := min(best+n, len(tokens)) - 1
+
+	window := tokens[best : last+1]
+	from, to := window[0].start, window[len(window)-1].end
+	shifted := make([]token, len(window))
+	for i, t := range window {
+		shifted[i] = token{start: t.start - from, end: t.end - from, word: t.word}
+	}
+
+	snippet := mark(text[from:to], shifted, matches)
+	if best > 0 {
+		snippet = "…" + snippet
+	}
+	if last < len(tokens)-1 {
+		snippet += "…"
+	}
+	return snippet
+}
+
+// mark writes text with the matching tokens wrapped in <mark> tags.
+func mark(text string, tokens []token, matches func(string) bool) string {
+	var b strings.Builder
+	pos := 0
+	for _, t := range tokens {
+		if !matches(t.word) {
+			continue
+		}
+		b.WriteString(html.EscapeString(text[pos:t.start]))
+		b.WriteString("<mark>")
+		b.WriteString(html.EscapeString(text[t.start:t.end]))
+		b.WriteString("</mark>")
+		pos = t.end
+	}
+	b.WriteString(html.EscapeString(text[pos:]))
+	return b.String()
+}
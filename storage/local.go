@@ -0,0 +1,87 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Local stores files in a directory. The API serves them under BaseURL.
+type Local struct {
+	Dir     string
+	BaseURL string
+}
+
+func NewLocal(dir, baseURL string) *Local {
+	return &Local{Dir: dir, BaseURL: strings.TrimSuffix(baseURL, "/")}
+}
+
+// path maps a key to a file inside Dir, refusing keys that would leave it.
+func (l *Local) path(key string) (string, error) {
+	clean := path.Clean(key)
+	if key == "" || clean != key || path.IsAbs(key) || clean == ".." || strings.HasPrefix(clean, "../") || strings.Contains(key, "\\") {
+		return "", fmt.Errorf("invalid storage key %q", key)
+	}
+	return filepath.Join(l.Dir, filepath.FromSlash(key)), nil
+}
+
+func (l *Local) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	name, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so readers never see a partial file.
+	tmp, err := os.CreateTemp(filepath.Dir(name), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), name)
+}
+
+func (l *Local) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	name, err := l.path(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return f, err
+}
+
+func (l *Local) Delete(ctx context.Context, key string) error {
+	name, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(name); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (l *Local) URL(key string) string {
+	return l.BaseURL + "/" + key
+}
@@ -0,0 +1,247 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mas-diq/go-graphql/config"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// S3 stores files in a bucket of an S3 compatible object store, signing
+// requests with AWS Signature Version 4. Point it at a local MinIO
+// (with path style addressing) to develop without AWS.
+type S3 struct {
+	Endpoint        *url.URL
+	Region          string
+	Bucket          string
+	AccessKeyID     string
+	SecretAccessKey string
+	PathStyle       bool
+	// PublicURL replaces the bucket address in URL, e.g. for a CDN.
+	PublicURL string
+
+	Client *http.Client
+	now    func() time.Time
+}
+
+func NewS3(cfg config.StorageConfig) (*S3, error) {
+	endpoint, err := url.Parse(cfg.S3Endpoint)
+	if err != nil || endpoint.Host == "" {
+		return nil, fmt.Errorf("invalid S3 endpoint %q", cfg.S3Endpoint)
+	}
+	if cfg.S3Bucket == "" {
+		return nil, errors.New("S3 bucket is not set")
+	}
+
+	publicURL := cfg.PublicURL
+	if publicURL == "/uploads" {
+		// The default only makes sense for the local driver.
+		publicURL = ""
+	}
+	return &S3{
+		Endpoint:        endpoint,
+		Region:          cfg.S3Region,
+		Bucket:          cfg.S3Bucket,
+		AccessKeyID:     cfg.S3AccessKeyID,
+		SecretAccessKey: cfg.S3SecretAccessKey,
+		PathStyle:       cfg.S3PathStyle,
+		PublicURL:       strings.TrimSuffix(publicURL, "/"),
+		Client:          &http.Client{Timeout: time.Minute},
+		now:             time.Now,
+	}, nil
+}
+
+// objectURL is the address of the object under key.
+func (s *S3) objectURL(key string) *url.URL {
+	u := *s.Endpoint
+	escaped := escapePath(key)
+	if s.PathStyle {
+		u.Path = strings.TrimSuffix(s.Endpoint.Path, "/") + "/" + s.Bucket + "/" + key
+		u.RawPath = strings.TrimSuffix(s.Endpoint.EscapedPath(), "/") + "/" + escapePath(s.Bucket) + "/" + escaped
+	} else {
+		u.Host = s.Bucket + "." + u.Host
+		u.Path = "/" + key
+		u.RawPath = "/" + escaped
+	}
+	return &u
+}
+
+func (s *S3) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, s.objectURL(key).String(), r)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	req.Header.Set("Content-Type", contentType)
+	// The body is streamed, so it is left out of the signature (allowed by S3 and MinIO).
+	resp, err := s.do(req, "UNSIGNED-PAYLOAD")
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+func (s *S3) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.objectURL(key).String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.do(req, emptyPayloadHash)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+func (s *S3) Delete(ctx context.Context, key string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, s.objectURL(key).String(), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := s.do(req, emptyPayloadHash)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+func (s *S3) URL(key string) string {
+	if s.PublicURL != "" {
+		return s.PublicURL + "/" + escapePath(key)
+	}
+	return s.objectURL(key).String()
+}
+
+// do signs and sends a request. Error responses are turned into errors.
+func (s *S3) do(req *http.Request, payloadHash string) (*http.Response, error) {
+	s.sign(req, payloadHash, s.now())
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 300 {
+		return resp, nil
+	}
+
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrNotFound
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return nil, fmt.Errorf("s3: %s %s: %s: %s", req.Method, req.URL.Path, resp.Status, body)
+}
+
+// emptyPayloadHash is the SHA-256 of an empty body.
+const emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// sign adds the AWS Signature Version 4 headers to a request. Every header
+// already set on the request is signed, along with the host.
+// See https://docs.aws.amazon.com/AmazonS3/latest/API/sig-v4-header-based-auth.html
+func (s *S3) sign(req *http.Request, payloadHash string, now time.Time) {
+	now = now.UTC()
+	amzDate := now.Format("20060102T150405Z")
+	day := now.Format("20060102")
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := day + "/" + s.Region + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hexSHA256(canonicalRequest)
+
+	key := hmacSHA256([]byte("AWS4"+s.SecretAccessKey), day)
+	key = hmacSHA256(key, s.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+s.AccessKeyID+"/"+scope+
+		", SignedHeaders="+signedHeaders+", Signature="+signature)
+}
+
+func canonicalQuery(values url.Values) string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var parts []string
+	for _, key := range keys {
+		vs := append([]string(nil), values[key]...)
+		sort.Strings(vs)
+		for _, v := range vs {
+			parts = append(parts, escape(key)+"="+escape(v))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// escape percent-encodes everything but the unreserved characters of RFC 3986, as SigV4 requires.
+func escape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || strings.IndexByte("-_.~", c) >= 0 {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// escapePath escapes each segment of a slash separated key.
+func escapePath(key string) string {
+	segments := strings.Split(key, "/")
+	for i, segment := range segments {
+		segments[i] = escape(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+func hexSHA256(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
@@ -0,0 +1,49 @@
+// Package storage keeps uploaded files, on the local disk or in an S3
+// compatible object store.
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mas-diq/go-graphql/config"
+)
+
+// Backend stores files under slash separated keys such as "images/2024/05/ab12.jpg".
+type Backend interface {
+	// Put stores size bytes read from r under key, replacing any existing file.
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Get opens the file stored under key. It returns ErrNotFound if there is none.
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes the file stored under key. Missing files are not an error.
+	Delete(ctx context.Context, key string) error
+	// URL is the address clients download the file from.
+	URL(key string) string
+}
+
+// ErrNotFound is returned by Get for keys without a file.
+var ErrNotFound = errors.New("file not found")
+
+// Default is the backend configured at startup.
+var Default Backend
+
+// Setup creates the backend selected by the configuration and makes it the Default.
+func Setup(cfg config.StorageConfig) error {
+	var backend Backend
+	switch cfg.Driver {
+	case "local":
+		backend = NewLocal(cfg.LocalDir, cfg.PublicURL)
+	case "s3":
+		s3, err := NewS3(cfg)
+		if err != nil {
+			return err
+		}
+		backend = s3
+	default:
+		return fmt.Errorf("unknown storage driver %q", cfg.Driver)
+	}
+
+	Default = backend
+	return nil
+}
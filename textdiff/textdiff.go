@@ -0,0 +1,211 @@
+package textdiff
+
+import (
+	"fmt"
+	"strings"
+)
+
+type OpKind int
+
+const (
+	Equal OpKind = iota
+	Insert
+	Delete
+)
+
+// Edit is one line of a diff: kept, inserted into b, or deleted from a.
+type Edit struct {
+	Kind OpKind
+	Line string
+}
+
+// maxEdits bounds the edit distance Lines searches for, as the memory it
+// takes grows with the square of the distance. Texts further apart are
+// diffed as a whole: every line that differs is deleted, then replaced.
+const maxEdits = 1000
+
+// Lines computes the shortest edit script turning a into b using Myers' algorithm.
+func Lines(a, b []string) []Edit {
+	// Lines shared at the start and the end are kept as they are
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	var edits []Edit
+	for _, line := range a[:prefix] {
+		edits = append(edits, Edit{Kind: Equal, Line: line})
+	}
+	edits = append(edits, myers(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for _, line := range a[len(a)-suffix:] {
+		edits = append(edits, Edit{Kind: Equal, Line: line})
+	}
+	return edits
+}
+
+func myers(a, b []string) []Edit {
+	n, m := len(a), len(b)
+	limit := min(n+m, maxEdits)
+	offset := limit + 1
+	v := make([]int, 2*limit+3)
+
+	var trace [][]int
+	for d := 0; d <= limit; d++ {
+		// Step d only reads the diagonals -d-1 to d+1, so only they are kept.
+		trace = append(trace, append([]int(nil), v[offset-d-1:offset+d+2]...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				return backtrack(trace, a, b)
+			}
+		}
+	}
+	return replace(a, b)
+}
+
+// backtrack follows the trace back from the end of both texts. trace[d]
+// holds the furthest x of the diagonals -d-1 to d+1 before step d.
+func backtrack(trace [][]int, a, b []string) []Edit {
+	x, y := len(a), len(b)
+	var edits []Edit
+
+	for d := len(trace) - 1; d >= 0; d-- {
+		v := trace[d]
+		k := x - y
+
+		var prevK int
+		if k == -d || (k != d && v[k-1+d+1] < v[k+1+d+1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := v[prevK+d+1]
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			edits = append(edits, Edit{Kind: Equal, Line: a[x-1]})
+			x--
+			y--
+		}
+		if d > 0 {
+			if x == prevX {
+				edits = append(edits, Edit{Kind: Insert, Line: b[prevY]})
+			} else {
+				edits = append(edits, Edit{Kind: Delete, Line: a[prevX]})
+			}
+		}
+		x, y = prevX, prevY
+	}
+
+	// Edits were collected from the end of the texts.
+	for i, j := 0, len(edits)-1; i < j; i, j = i+1, j-1 {
+		edits[i], edits[j] = edits[j], edits[i]
+	}
+	return edits
+}
+
+// replace is the edit script deleting all of a, then inserting all of b.
+func replace(a, b []string) []Edit {
+	edits := make([]Edit, 0, len(a)+len(b))
+	for _, line := range a {
+		edits = append(edits, Edit{Kind: Delete, Line: line})
+	}
+	for _, line := range b {
+		edits = append(edits, Edit{Kind: Insert, Line: line})
+	}
+	return edits
+}
+
+// Unified renders the line diff between a and b in unified diff format,
+// with the given number of context lines around each change.
+func Unified(a, b, fromName, toName string, context int) string {
+	edits := Lines(splitLines(a), splitLines(b))
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", fromName, toName)
+
+	// Walk the edits, tracking line numbers in a and b, and emit one hunk per
+	// group of changes that are less than 2*context lines apart.
+	aLine, bLine := 1, 1
+	for i := 0; i < len(edits); {
+		if edits[i].Kind == Equal {
+			aLine++
+			bLine++
+			i++
+			continue
+		}
+
+		start := i - context
+		if start < 0 {
+			start = 0
+		}
+		end := i
+		for end < len(edits) {
+			if edits[end].Kind != Equal {
+				end++
+				continue
+			}
+			run := end
+			for run < len(edits) && edits[run].Kind == Equal {
+				run++
+			}
+			if run == len(edits) || run-end > 2*context {
+				end += min(context, run-end)
+				break
+			}
+			end = run
+		}
+
+		hunkA, hunkB := aLine-(i-start), bLine-(i-start)
+		var countA, countB int
+		var body strings.Builder
+		for _, e := range edits[start:end] {
+			switch e.Kind {
+			case Equal:
+				body.WriteString(" " + e.Line + "\n")
+				countA++
+				countB++
+			case Delete:
+				body.WriteString("-" + e.Line + "\n")
+				countA++
+			case Insert:
+				body.WriteString("+" + e.Line + "\n")
+				countB++
+			}
+		}
+		fmt.Fprintf(&out, "@@ -%d,%d +%d,%d @@\n%s", hunkA, countA, hunkB, countB, body.String())
+
+		for _, e := range edits[i:end] {
+			if e.Kind != Insert {
+				aLine++
+			}
+			if e.Kind != Delete {
+				bLine++
+			}
+		}
+		i = end
+	}
+	return out.String()
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
@@ -0,0 +1,129 @@
+package textdiff
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// apply rebuilds both texts from an edit script.
+func apply(edits []Edit) (a, b []string) {
+	for _, e := range edits {
+		if e.Kind != Insert {
+			a = append(a, e.Line)
+		}
+		if e.Kind != Delete {
+			b = append(b, e.Line)
+		}
+	}
+	return a, b
+}
+
+func changes(edits []Edit) int {
+	count := 0
+	for _, e := range edits {
+		if e.Kind != Equal {
+			count++
+		}
+	}
+	return count
+}
+
+func TestLines(t *testing.T) {
+	tests := []struct {
+		name    string
+		a, b    string
+		changes int
+	}{
+		{name: "empty", a: "", b: "", changes: 0},
+		{name: "identical", a: "a b c", b: "a b c", changes: 0},
+		{name: "from nothing", a: "", b: "a b", changes: 2},
+		{name: "to nothing", a: "a b", b: "", changes: 2},
+		{name: "insert", a: "a c", b: "a b c", changes: 1},
+		{name: "delete", a: "a b c", b: "a c", changes: 1},
+		{name: "replace", a: "a b c", b: "a x c", changes: 2},
+		{name: "move", a: "a b c d", b: "b c d a", changes: 2},
+		{name: "myers paper", a: "a b c a b b a", b: "c b a b a c", changes: 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := strings.Fields(tt.a), strings.Fields(tt.b)
+			edits := Lines(a, b)
+			gotA, gotB := apply(edits)
+			if strings.Join(gotA, " ") != tt.a || strings.Join(gotB, " ") != tt.b {
+				t.Fatalf("edits rebuild %q and %q", gotA, gotB)
+			}
+			if got := changes(edits); got != tt.changes {
+				t.Fatalf("%d changes, want %d", got, tt.changes)
+			}
+		})
+	}
+}
+
+func TestLinesReplacesTextsTooFarApart(t *testing.T) {
+	var a, b []string
+	a = append(a, "head")
+	b = append(b, "head")
+	for i := 0; i < maxEdits; i++ {
+		a = append(a, fmt.Sprintf("old %d", i))
+		b = append(b, fmt.Sprintf("new %d", i))
+	}
+	a = append(a, "tail")
+	b = append(b, "tail")
+
+	edits := Lines(a, b)
+	gotA, gotB := apply(edits)
+	if strings.Join(gotA, "\n") != strings.Join(a, "\n") || strings.Join(gotB, "\n") != strings.Join(b, "\n") {
+		t.Fatal("edits don't rebuild the texts")
+	}
+	if edits[0] != (Edit{Kind: Equal, Line: "head"}) || edits[len(edits)-1] != (Edit{Kind: Equal, Line: "tail"}) {
+		t.Fatal("shared lines aren't kept")
+	}
+	if edits[1].Kind != Delete || edits[maxEdits+1].Kind != Insert {
+		t.Fatal("differing lines aren't replaced as a whole")
+	}
+}
+
+func TestUnified(t *testing.T) {
+	a := "one\ntwo\nthree\nfour\nfive\nsix\nseven\neight\nnine\nten\n"
+	b := "one\n2\nthree\nfour\nfive\nsix\nseven\neight\nnine\nten\neleven\n"
+	want := `--- a
++++ b
+@@ -1,3 +1,3 @@
+ one
+-two
++2
+ three
+@@ -10,1 +10,2 @@
+ ten
++eleven
+`
+	if got := Unified(a, b, "a", "b", 1); got != want {
+		t.Fatalf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestUnifiedMergesCloseChanges(t *testing.T) {
+	a := "a\nb\nc\nd\ne\n"
+	b := "a\nB\nc\nD\ne\n"
+	want := `--- a
++++ b
+@@ -1,5 +1,5 @@
+ a
+-b
++B
+ c
+-d
++D
+ e
+`
+	if got := Unified(a, b, "a", "b", 1); got != want {
+		t.Fatalf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestUnifiedIdentical(t *testing.T) {
+	if got := Unified("same\n", "same\n", "a", "b", 3); got != "--- a\n+++ b\n" {
+		t.Fatalf("got %q", got)
+	}
+}
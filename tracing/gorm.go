@@ -0,0 +1,85 @@
+package tracing
+
+import (
+	"errors"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+var tracer = otel.Tracer("mas-diq/go-graphql/tracing")
+
+// spanKey is where the span of a statement waits for the after callback.
+const spanKey = "tracing:span"
+
+// RegisterGorm traces every statement GORM runs with a context that is already
+// traced (db.WithContext in a request). Statements run without one, such as
+// the model helpers using config.DB or the background workers, aren't traced
+// rather than each starting a trace of its own.
+func RegisterGorm(db *gorm.DB) error {
+	callbacks := db.Callback()
+	return errors.Join(
+		callbacks.Create().Before("gorm:create").Register("tracing:before_create", before),
+		callbacks.Create().After("gorm:create").Register("tracing:after_create", after),
+		callbacks.Query().Before("gorm:query").Register("tracing:before_query", before),
+		callbacks.Query().After("gorm:query").Register("tracing:after_query", after),
+		callbacks.Update().Before("gorm:update").Register("tracing:before_update", before),
+		callbacks.Update().After("gorm:update").Register("tracing:after_update", after),
+		callbacks.Delete().Before("gorm:delete").Register("tracing:before_delete", before),
+		callbacks.Delete().After("gorm:delete").Register("tracing:after_delete", after),
+		callbacks.Row().Before("gorm:row").Register("tracing:before_row", before),
+		callbacks.Row().After("gorm:row").Register("tracing:after_row", after),
+		callbacks.Raw().Before("gorm:raw").Register("tracing:before_raw", before),
+		callbacks.Raw().After("gorm:raw").Register("tracing:after_raw", after),
+	)
+}
+
+// before starts the span of a statement, named once its SQL is known.
+func before(db *gorm.DB) {
+	ctx := db.Statement.Context
+	if ctx == nil || !trace.SpanContextFromContext(ctx).IsValid() {
+		return
+	}
+	_, span := tracer.Start(ctx, "gorm", trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(semconv.DBSystemKey.String(db.Dialector.Name())))
+	db.InstanceSet(spanKey, span)
+}
+
+// after ends the span of a statement, failed when the statement failed.
+func after(db *gorm.DB) {
+	value, ok := db.InstanceGet(spanKey)
+	if !ok {
+		return
+	}
+	span := value.(trace.Span)
+	defer span.End()
+
+	sql := db.Statement.SQL.String()
+	span.SetName(spanName(sql, db.Statement.Table))
+	span.SetAttributes(semconv.DBQueryText(sql), semconv.DBCollectionName(db.Statement.Table))
+	if db.Statement.RowsAffected >= 0 { // Row and Raw don't count rows
+		span.SetAttributes(attribute.Int64("db.rows_affected", db.Statement.RowsAffected))
+	}
+	if err := db.Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+}
+
+// spanName is the SQL verb and the table, e.g. "SELECT posts".
+func spanName(sql, table string) string {
+	verb, _, _ := strings.Cut(strings.TrimSpace(sql), " ")
+	if verb == "" {
+		verb = "SQL"
+	}
+	verb = strings.ToUpper(verb)
+	if table == "" {
+		return verb
+	}
+	return verb + " " + table
+}
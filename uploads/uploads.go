@@ -0,0 +1,153 @@
+// Package uploads validates uploaded images, generates their thumbnails and
+// stores both through the storage backend.
+package uploads
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif" // registers the GIF decoder
+	"image/jpeg"
+	"image/png"
+	"io"
+	"mas-diq/go-graphql/config"
+	"mas-diq/go-graphql/models"
+	"mas-diq/go-graphql/storage"
+	"net/http"
+	"time"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp" // registers the WebP decoder
+)
+
+var (
+	// ErrUnsupportedType is returned for files that are not JPEG, PNG, GIF or WebP images.
+	ErrUnsupportedType = errors.New("file must be a JPEG, PNG, GIF or WebP image")
+	// ErrTooLarge is returned for files above the configured size or pixel limits.
+	ErrTooLarge = errors.New("file is too large")
+)
+
+// extensions of the accepted content types, as sniffed from the file itself.
+var extensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+}
+
+// SaveImage validates an image read from r, stores it with a thumbnail and
+// records it as an Upload of the given user. The content type is sniffed from
+// the data; whatever the client claimed is ignored.
+func SaveImage(ctx context.Context, backend storage.Backend, r io.Reader, uploadedBy uint) (*models.Upload, error) {
+	limits := config.App.Uploads
+
+	data, err := io.ReadAll(io.LimitReader(r, limits.MaxBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limits.MaxBytes {
+		return nil, ErrTooLarge
+	}
+
+	contentType := http.DetectContentType(data)
+	ext, ok := extensions[contentType]
+	if !ok {
+		return nil, ErrUnsupportedType
+	}
+
+	// Check the dimensions before decoding, so a small file can't expand into a huge bitmap.
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedType
+	}
+	if int64(cfg.Width)*int64(cfg.Height) > limits.MaxPixels {
+		return nil, ErrTooLarge
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedType
+	}
+
+	thumb, thumbType, err := thumbnail(img, contentType, limits.ThumbnailWidth)
+	if err != nil {
+		return nil, err
+	}
+
+	name, err := randomName()
+	if err != nil {
+		return nil, err
+	}
+	dir := "images/" + time.Now().UTC().Format("2006/01") + "/"
+	upload := models.Upload{
+		Key:             dir + name + ext,
+		ContentType:     contentType,
+		Size:            int64(len(data)),
+		Width:           cfg.Width,
+		Height:          cfg.Height,
+		ThumbnailKey:    dir + name + "_thumb" + extensions[thumbType],
+		ThumbnailWidth:  thumb.Width,
+		ThumbnailHeight: thumb.Height,
+		UploadedBy:      uploadedBy,
+	}
+
+	if err := backend.Put(ctx, upload.Key, bytes.NewReader(data), upload.Size, contentType); err != nil {
+		return nil, err
+	}
+	if err := backend.Put(ctx, upload.ThumbnailKey, bytes.NewReader(thumb.Data), int64(len(thumb.Data)), thumbType); err != nil {
+		backend.Delete(ctx, upload.Key)
+		return nil, err
+	}
+	if err := models.CreateUploadData(&upload); err != nil {
+		backend.Delete(ctx, upload.Key)
+		backend.Delete(ctx, upload.ThumbnailKey)
+		return nil, err
+	}
+	return &upload, nil
+}
+
+// encodedImage is an encoded thumbnail and its dimensions.
+type encodedImage struct {
+	Data          []byte
+	Width, Height int
+}
+
+// thumbnail scales img down to width, keeping its aspect ratio; smaller images
+// keep their size. Images that may be transparent become PNGs, others JPEGs.
+func thumbnail(img image.Image, contentType string, width int) (encodedImage, string, error) {
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	if w > width {
+		h = max(h*width/w, 1)
+		w = width
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, bounds, draw.Src, nil)
+
+	var buf bytes.Buffer
+	thumbType := "image/jpeg"
+	var err error
+	switch contentType {
+	case "image/png", "image/gif", "image/webp":
+		thumbType = "image/png"
+		err = png.Encode(&buf, dst)
+	default:
+		err = jpeg.Encode(&buf, dst, &jpeg.Options{Quality: 85})
+	}
+	if err != nil {
+		return encodedImage{}, "", fmt.Errorf("encoding thumbnail: %w", err)
+	}
+	return encodedImage{Data: buf.Bytes(), Width: w, Height: h}, thumbType, nil
+}
+
+func randomName() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
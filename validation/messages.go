@@ -0,0 +1,117 @@
+package validation
+
+import (
+	"strings"
+	"sync"
+)
+
+// DefaultLocale is used when the client asks for a locale we have no messages for.
+const DefaultLocale = "en"
+
+// Messages maps a validation rule (the validator tag, e.g. "required" or "min")
+// to a message template. Templates may use the {field} and {param} placeholders.
+type Messages map[string]string
+
+var (
+	localesMu sync.RWMutex
+	locales   = map[string]Messages{
+		"en": {
+			"required": "{field} is required",
+			"min":      "{field} must be at least {param} characters long",
+			"max":      "{field} must be at most {param} characters long",
+			"email":    "{field} must be a valid email address",
+			"url":      "{field} must be a valid URL",
+			"oneof":    "{field} must be one of: {param}",
+			"gt":       "{field} must be greater than {param}",
+			"gte":      "{field} must be greater than or equal to {param}",
+			"lt":       "{field} must be less than {param}",
+			"lte":      "{field} must be less than or equal to {param}",
+			"type":     "{field} must be of type {param}",
+			"json":     "request body is not valid JSON",
+			"numeric":  "{field} must be a number",
+			"unique":   "{field} is already taken",
+			"slug":     "{field} may only contain lowercase letters, numbers and dashes",
+			"datetime": "{field} must be a date in the format {param}",
+			"cursor":   "{field} is not a valid cursor",
+			"parent":   "{field} must be a comment on the same post",
+			"tag":      "{field} must contain a letter or a number",
+			"default":  "{field} is invalid",
+		},
+		"id": {
+			"required": "{field} wajib diisi",
+			"min":      "{field} minimal {param} karakter",
+			"max":      "{field} maksimal {param} karakter",
+			"email":    "{field} harus berupa alamat email yang valid",
+			"url":      "{field} harus berupa URL yang valid",
+			"oneof":    "{field} harus salah satu dari: {param}",
+			"gt":       "{field} harus lebih besar dari {param}",
+			"gte":      "{field} harus lebih besar atau sama dengan {param}",
+			"lt":       "{field} harus lebih kecil dari {param}",
+			"lte":      "{field} harus lebih kecil atau sama dengan {param}",
+			"type":     "{field} harus bertipe {param}",
+			"json":     "body request bukan JSON yang valid",
+			"numeric":  "{field} harus berupa angka",
+			"unique":   "{field} sudah digunakan",
+			"slug":     "{field} hanya boleh berisi huruf kecil, angka, dan tanda hubung",
+			"datetime": "{field} harus berupa tanggal dengan format {param}",
+			"cursor":   "{field} bukan cursor yang valid",
+			"parent":   "{field} harus komentar pada post yang sama",
+			"tag":      "{field} harus berisi huruf atau angka",
+			"default":  "{field} tidak valid",
+		},
+	}
+)
+
+// RegisterMessages adds or overrides the message templates for a locale.
+// Rules that are missing from a locale fall back to the DefaultLocale templates.
+func RegisterMessages(locale string, messages Messages) {
+	localesMu.Lock()
+	defer localesMu.Unlock()
+
+	locale = strings.ToLower(locale)
+	existing, ok := locales[locale]
+	if !ok {
+		existing = Messages{}
+		locales[locale] = existing
+	}
+	for rule, template := range messages {
+		existing[rule] = template
+	}
+}
+
+// Message renders the message for a rule in the given locale.
+func Message(locale, rule, field, param string) string {
+	localesMu.RLock()
+	defer localesMu.RUnlock()
+
+	template, ok := locales[locale][rule]
+	if !ok {
+		template, ok = locales[DefaultLocale][rule]
+	}
+	if !ok {
+		template = locales[DefaultLocale]["default"]
+	}
+
+	return strings.NewReplacer("{field}", field, "{param}", param).Replace(template)
+}
+
+// ParseLocale picks the first supported locale from an Accept-Language header value.
+func ParseLocale(acceptLanguage string) string {
+	localesMu.RLock()
+	defer localesMu.RUnlock()
+
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag := strings.TrimSpace(strings.SplitN(part, ";", 2)[0])
+		tag = strings.ToLower(tag)
+		if _, ok := locales[tag]; ok {
+			return tag
+		}
+		// "id-ID" -> "id"
+		if base := strings.SplitN(tag, "-", 2)[0]; base != tag {
+			if _, ok := locales[base]; ok {
+				return base
+			}
+		}
+	}
+	return DefaultLocale
+}
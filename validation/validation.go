@@ -0,0 +1,101 @@
+package validation
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"mas-diq/go-graphql/models"
+	"mas-diq/go-graphql/schemas"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+type contextKey string
+
+const localeKey contextKey = "locale"
+
+func init() {
+	// Report fields by their JSON (or form) name instead of the Go struct field name,
+	// so "CreatedBy" shows up as "createdBy" just like in the request body.
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(fieldName)
+		v.RegisterValidation("slug", func(fl validator.FieldLevel) bool {
+			return models.IsValidSlug(fl.Field().String())
+		})
+	}
+}
+
+func fieldName(field reflect.StructField) string {
+	for _, tag := range []string{"json", "form"} {
+		name := strings.SplitN(field.Tag.Get(tag), ",", 2)[0]
+		if name == "-" {
+			return ""
+		}
+		if name != "" {
+			return name
+		}
+	}
+	return field.Name
+}
+
+// WithLocale stores the locale used to render validation messages in the context.
+func WithLocale(ctx context.Context, locale string) context.Context {
+	return context.WithValue(ctx, localeKey, locale)
+}
+
+// LocaleFromContext returns the locale stored by WithLocale, or DefaultLocale.
+func LocaleFromContext(ctx context.Context) string {
+	if locale, ok := ctx.Value(localeKey).(string); ok && locale != "" {
+		return locale
+	}
+	return DefaultLocale
+}
+
+// Validate runs the `binding` tag rules of a struct, the same rules gin applies
+// on ShouldBindJSON. It lets non-HTTP inputs (e.g. GraphQL arguments) share them.
+func Validate(obj interface{}) error {
+	return binding.Validator.ValidateStruct(obj)
+}
+
+// Translate converts a binding or validation error into field level errors.
+// It returns nil when err is not something the client can fix field by field.
+func Translate(err error, locale string) []schemas.FieldError {
+	var validationErrs validator.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		fields := make([]schemas.FieldError, 0, len(validationErrs))
+		for _, fe := range validationErrs {
+			fields = append(fields, schemas.FieldError{
+				Field:   fe.Field(),
+				Rule:    fe.Tag(),
+				Param:   fe.Param(),
+				Message: Message(locale, fe.Tag(), fe.Field(), fe.Param()),
+			})
+		}
+		return fields
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return []schemas.FieldError{NewFieldError(locale, typeErr.Field, "type", typeErr.Type.String())}
+	}
+
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		return []schemas.FieldError{NewFieldError(locale, "", "json", "")}
+	}
+
+	return nil
+}
+
+// NewFieldError builds a single field error with a localized message.
+func NewFieldError(locale, field, rule, param string) schemas.FieldError {
+	return schemas.FieldError{
+		Field:   field,
+		Rule:    rule,
+		Param:   param,
+		Message: Message(locale, rule, field, param),
+	}
+}
@@ -0,0 +1,124 @@
+package validation
+
+import (
+	"encoding/json"
+	"errors"
+	"mas-diq/go-graphql/schemas"
+	"reflect"
+	"testing"
+)
+
+type postInput struct {
+	Title     string `json:"title" binding:"required,min=3"`
+	Slug      string `json:"slug" binding:"omitempty,slug"`
+	CreatedBy uint   `json:"createdBy" binding:"required"`
+	Status    string `form:"status" binding:"omitempty,oneof=draft published"`
+}
+
+func TestTranslate(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		locale string
+		want   []schemas.FieldError
+	}{
+		{
+			name:   "rules by JSON name",
+			err:    Validate(postInput{Title: "Go", Slug: "Not A Slug"}),
+			locale: "en",
+			want: []schemas.FieldError{
+				{Field: "title", Rule: "min", Param: "3", Message: "title must be at least 3 characters long"},
+				{Field: "slug", Rule: "slug", Message: "slug may only contain lowercase letters, numbers and dashes"},
+				{Field: "createdBy", Rule: "required", Message: "createdBy is required"},
+			},
+		},
+		{
+			name:   "form name",
+			err:    Validate(postInput{Title: "Hello", CreatedBy: 1, Status: "archived"}),
+			locale: "id",
+			want: []schemas.FieldError{
+				{Field: "status", Rule: "oneof", Param: "draft published", Message: "status harus salah satu dari: draft published"},
+			},
+		},
+		{
+			name:   "wrong JSON type",
+			err:    json.Unmarshal([]byte(`{"createdBy": "one"}`), &postInput{}),
+			locale: "en",
+			want:   []schemas.FieldError{{Field: "createdBy", Rule: "type", Param: "uint", Message: "createdBy must be of type uint"}},
+		},
+		{
+			name:   "invalid JSON",
+			err:    json.Unmarshal([]byte(`{"title":`+"\x00"), &postInput{}),
+			locale: "en",
+			want:   []schemas.FieldError{{Rule: "json", Message: "request body is not valid JSON"}},
+		},
+		{
+			name:   "other errors",
+			err:    errors.New("connection reset"),
+			locale: "en",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Translate(tt.err, tt.locale); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("Translate = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateAcceptsValidInput(t *testing.T) {
+	if err := Validate(postInput{Title: "Hello", Slug: "hello-world", CreatedBy: 1, Status: "draft"}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMessage(t *testing.T) {
+	tests := []struct {
+		locale, rule string
+		want         string
+	}{
+		{locale: "en", rule: "max", want: "name must be at most 10 characters long"},
+		{locale: "id", rule: "max", want: "name maksimal 10 karakter"},
+		{locale: "fr", rule: "max", want: "name must be at most 10 characters long"},
+		{locale: "en", rule: "unknown", want: "name is invalid"},
+	}
+	for _, tt := range tests {
+		if got := Message(tt.locale, tt.rule, "name", "10"); got != tt.want {
+			t.Errorf("Message(%q, %q) = %q, want %q", tt.locale, tt.rule, got, tt.want)
+		}
+	}
+}
+
+func TestRegisterMessages(t *testing.T) {
+	RegisterMessages("NL", Messages{"required": "{field} is verplicht"})
+
+	if got := Message("nl", "required", "name", ""); got != "name is verplicht" {
+		t.Errorf("registered message = %q", got)
+	}
+	if got := Message("nl", "email", "email", ""); got != "email must be a valid email address" {
+		t.Errorf("missing rule = %q, want the default locale's", got)
+	}
+	if got := ParseLocale("nl-BE"); got != "nl" {
+		t.Errorf("ParseLocale = %q, want the registered locale", got)
+	}
+}
+
+func TestParseLocale(t *testing.T) {
+	tests := []struct {
+		acceptLanguage string
+		want           string
+	}{
+		{acceptLanguage: "", want: "en"},
+		{acceptLanguage: "id", want: "id"},
+		{acceptLanguage: "id-ID,id;q=0.9,en;q=0.8", want: "id"},
+		{acceptLanguage: "fr-FR, en;q=0.5", want: "en"},
+		{acceptLanguage: "EN-us", want: "en"},
+		{acceptLanguage: "fr, de", want: "en"},
+	}
+	for _, tt := range tests {
+		if got := ParseLocale(tt.acceptLanguage); got != tt.want {
+			t.Errorf("ParseLocale(%q) = %q, want %q", tt.acceptLanguage, got, tt.want)
+		}
+	}
+}
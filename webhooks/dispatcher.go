@@ -0,0 +1,258 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mas-diq/go-graphql/config"
+	"mas-diq/go-graphql/logging"
+	"mas-diq/go-graphql/models"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var logger = logging.For("webhooks")
+
+const (
+	// batchSize is how many events or deliveries are read at a time.
+	batchSize = 100
+	// workers is how many deliveries are sent at once.
+	workers = 8
+	// maxErrorBody is how much of a failed response is kept as the error.
+	maxErrorBody = 256
+)
+
+// Envelope is the JSON body of a delivery.
+type Envelope struct {
+	ID        uint64           `json:"id"`
+	Type      models.EventType `json:"type"`
+	CreatedAt time.Time        `json:"createdAt"`
+	Data      json.RawMessage  `json:"data"`
+}
+
+// Dispatcher hands outbox events to the webhooks that subscribed to them and
+// sends the deliveries, retrying failed ones with exponential backoff until
+// MaxAttempts, after which they are dead until replayed. Deliveries are sent
+// at least once: receivers should ignore the X-Webhook-Delivery values they
+// have already seen.
+type Dispatcher struct {
+	Client      *http.Client
+	MaxAttempts int
+	BackoffBase time.Duration
+	BackoffMax  time.Duration
+	// Lease is how long a claimed delivery is left alone by other
+	// dispatchers; a delivery whose dispatcher died is retried after it.
+	Lease time.Duration
+}
+
+// New returns a dispatcher with the given settings.
+func New(cfg config.WebhooksConfig) *Dispatcher {
+	return &Dispatcher{
+		Client:      &http.Client{Timeout: cfg.Timeout},
+		MaxAttempts: cfg.MaxAttempts,
+		BackoffBase: cfg.BackoffBase,
+		BackoffMax:  cfg.BackoffMax,
+		Lease:       2 * cfg.Timeout,
+	}
+}
+
+// Start runs a dispatcher every cfg.PollInterval until ctx is cancelled.
+// Events and deliveries live in the database, so what was left over when the
+// server stopped is picked up on the first run. The returned channel is
+// closed once it stopped.
+func Start(ctx context.Context, cfg config.WebhooksConfig) <-chan struct{} {
+	done := make(chan struct{})
+	if !cfg.Enabled {
+		close(done)
+		return done
+	}
+	d := New(cfg)
+
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(cfg.PollInterval)
+		defer ticker.Stop()
+
+		for {
+			d.Run(ctx)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return done
+}
+
+// Run dispatches the pending events, then sends the deliveries that are due.
+func (d *Dispatcher) Run(ctx context.Context) {
+	if err := d.dispatchEvents(); err != nil {
+		logger.Error("Failed to dispatch outbox events", "error", err)
+	}
+	if err := d.sendDue(ctx); err != nil {
+		logger.Error("Failed to send webhook deliveries", "error", err)
+	}
+}
+
+// dispatchEvents creates a delivery for every active webhook that wants each
+// pending event.
+func (d *Dispatcher) dispatchEvents() error {
+	var webhooks []models.Webhook
+	if err := models.GetActiveWebhooks(&webhooks); err != nil {
+		return err
+	}
+
+	for {
+		var events []models.OutboxEvent
+		if err := models.GetPendingOutboxEvents(&events, batchSize); err != nil {
+			return err
+		}
+
+		for i := range events {
+			var targets []models.Webhook
+			for _, webhook := range webhooks {
+				if webhook.Wants(events[i].Type) {
+					targets = append(targets, webhook)
+				}
+			}
+			if _, err := models.DispatchOutboxEvent(&events[i], targets, time.Now()); err != nil {
+				return err
+			}
+		}
+
+		if len(events) < batchSize {
+			return nil
+		}
+	}
+}
+
+// sendDue sends the deliveries that are due, a few at a time.
+func (d *Dispatcher) sendDue(ctx context.Context) error {
+	var deliveries []models.WebhookDelivery
+	if err := models.GetDueDeliveries(&deliveries, time.Now(), batchSize); err != nil {
+		return err
+	}
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, workers)
+	for i := range deliveries {
+		claimed, err := models.ClaimDelivery(&deliveries[i], time.Now().Add(d.Lease))
+		if err != nil {
+			return err
+		}
+		if !claimed {
+			continue
+		}
+
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(delivery *models.WebhookDelivery) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			d.attempt(ctx, delivery)
+		}(&deliveries[i])
+	}
+	wg.Wait()
+	return nil
+}
+
+// attempt sends a delivery once and stores the outcome.
+func (d *Dispatcher) attempt(ctx context.Context, delivery *models.WebhookDelivery) {
+	status, err := d.send(ctx, delivery)
+	if ctx.Err() != nil {
+		// Shutting down: the attempt doesn't count, it is retried once the lease is over
+		return
+	}
+
+	now := time.Now()
+	delivery.Attempts++
+	delivery.LastStatus = status
+	delivery.LastError = ""
+	switch {
+	case err == nil:
+		delivery.Status = models.DeliveryDelivered
+		delivery.DeliveredAt = &now
+		delivery.NextAttemptAt = nil
+	case delivery.Attempts >= d.MaxAttempts:
+		delivery.Status = models.DeliveryDead
+		delivery.LastError = truncate(err.Error(), 1024)
+		delivery.NextAttemptAt = nil
+		logger.Warn("Webhook delivery is dead", "delivery_id", delivery.ID, "webhook_id", delivery.WebhookID, "attempts", delivery.Attempts, "error", err)
+	default:
+		next := now.Add(d.backoff(delivery.Attempts))
+		delivery.LastError = truncate(err.Error(), 1024)
+		delivery.NextAttemptAt = &next
+	}
+
+	if err := models.SaveDeliveryAttempt(delivery); err != nil {
+		logger.Error("Failed to save webhook delivery", "delivery_id", delivery.ID, "error", err)
+	}
+}
+
+// send posts the event to the webhook. It returns the response status, 0
+// when there was no response, and an error unless the status is 2xx.
+func (d *Dispatcher) send(ctx context.Context, delivery *models.WebhookDelivery) (int, error) {
+	event := delivery.Event
+	body, err := json.Marshal(Envelope{
+		ID:        event.ID,
+		Type:      event.Type,
+		CreatedAt: event.CreatedAt,
+		Data:      json.RawMessage(event.Payload),
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.Webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "go-graphql-webhooks")
+	req.Header.Set("X-Webhook-Event", string(event.Type))
+	req.Header.Set("X-Webhook-Delivery", strconv.FormatUint(delivery.ID, 10))
+	req.Header.Set("X-Webhook-Timestamp", strconv.FormatInt(timestamp, 10))
+	req.Header.Set("X-Webhook-Signature", Sign(delivery.Webhook.Secret, timestamp, body))
+
+	resp, err := d.Client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		snippet, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+		return resp.StatusCode, fmt.Errorf("%s: %s", resp.Status, bytes.TrimSpace(snippet))
+	}
+	// Drain the body so the connection can be reused
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	return resp.StatusCode, nil
+}
+
+// backoff returns the wait after the given number of failed attempts:
+// BackoffBase doubled for every attempt after the first, at most BackoffMax.
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	wait := d.BackoffBase
+	for i := 1; i < attempts; i++ {
+		wait *= 2
+		if wait >= d.BackoffMax {
+			return d.BackoffMax
+		}
+	}
+	return min(wait, d.BackoffMax)
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return strings.ToValidUTF8(s[:n], "")
+}
@@ -0,0 +1,116 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"mas-diq/go-graphql/models"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSign(t *testing.T) {
+	body := []byte(`{"id":1,"type":"post.created"}`)
+	// printf '%s' '1700000000.{"id":1,"type":"post.created"}' | openssl dgst -sha256 -hmac whsec_test
+	want := "sha256=59fe031c8a5996d082dee9f8f68a50bc05f04eb9b67d6cc7fec5c29840381ecb"
+	if got := Sign("whsec_test", 1700000000, body); got != want {
+		t.Fatalf("Sign = %s, want %s", got, want)
+	}
+
+	if Sign("whsec_other", 1700000000, body) == want {
+		t.Error("signature doesn't depend on the secret")
+	}
+	if Sign("whsec_test", 1700000001, body) == want {
+		t.Error("signature doesn't depend on the timestamp")
+	}
+}
+
+func TestNewSecret(t *testing.T) {
+	a, err := NewSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := NewSecret()
+	if !strings.HasPrefix(a, secretPrefix) || len(a) != len(secretPrefix)+48 {
+		t.Errorf("secret = %q", a)
+	}
+	if a == b {
+		t.Error("secrets repeat")
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	d := &Dispatcher{BackoffBase: 10 * time.Second, BackoffMax: time.Hour}
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{attempts: 1, want: 10 * time.Second},
+		{attempts: 2, want: 20 * time.Second},
+		{attempts: 3, want: 40 * time.Second},
+		{attempts: 9, want: 2560 * time.Second},
+		{attempts: 10, want: time.Hour},
+		{attempts: 1000, want: time.Hour},
+	}
+	for _, tt := range tests {
+		if got := d.backoff(tt.attempts); got != tt.want {
+			t.Errorf("backoff(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+
+	if got := (&Dispatcher{BackoffBase: time.Minute, BackoffMax: time.Second}).backoff(1); got != time.Second {
+		t.Errorf("base above the max: backoff(1) = %v", got)
+	}
+}
+
+func TestSendSignsTheBody(t *testing.T) {
+	var header http.Header
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header
+		body, _ = io.ReadAll(r.Body)
+	}))
+	defer server.Close()
+
+	delivery := &models.WebhookDelivery{
+		ID:      7,
+		Event:   models.OutboxEvent{ID: 3, Type: "post.created", Payload: `{"id":1}`},
+		Webhook: models.Webhook{URL: server.URL, Secret: "whsec_test"},
+	}
+	d := &Dispatcher{Client: server.Client()}
+	if status, err := d.send(context.Background(), delivery); status != http.StatusOK || err != nil {
+		t.Fatalf("send = %d, %v", status, err)
+	}
+
+	timestamp, err := strconv.ParseInt(header.Get("X-Webhook-Timestamp"), 10, 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := header.Get("X-Webhook-Signature"); got != Sign("whsec_test", timestamp, body) {
+		t.Errorf("signature %s doesn't match the body", got)
+	}
+	if header.Get("X-Webhook-Event") != "post.created" || header.Get("X-Webhook-Delivery") != "7" {
+		t.Errorf("headers = %v", header)
+	}
+	var envelope Envelope
+	if err := json.Unmarshal(body, &envelope); err != nil || envelope.ID != 3 || string(envelope.Data) != `{"id":1}` {
+		t.Errorf("body = %s", body)
+	}
+}
+
+func TestSendFailsOnErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "try again later", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	delivery := &models.WebhookDelivery{Event: models.OutboxEvent{Payload: `{}`}, Webhook: models.Webhook{URL: server.URL}}
+	status, err := (&Dispatcher{Client: server.Client()}).send(context.Background(), delivery)
+	if status != http.StatusServiceUnavailable || err == nil || !strings.Contains(err.Error(), "try again later") {
+		t.Fatalf("send = %d, %v", status, err)
+	}
+}